@@ -15,9 +15,15 @@ var (
 
 type MetricMaker interface {
 	Name() string
+	LogName() string
 	MakeMetric(metric telegraf.Metric) telegraf.Metric
 }
 
+// errorReporter is implemented by plugins that keep track of their errors.
+type errorReporter interface {
+	ReportError(err error)
+}
+
 type accumulator struct {
 	maker     MetricMaker
 	metrics   chan<- telegraf.Metric
@@ -111,7 +117,10 @@ func (ac *accumulator) AddError(err error) {
 		return
 	}
 	NErrors.Incr(1)
-	log.Printf("E! [%s]: Error in plugin: %v", ac.maker.Name(), err)
+	if r, ok := ac.maker.(errorReporter); ok {
+		r.ReportError(err)
+	}
+	log.Printf("E! [%s]: Error in plugin: %v", ac.maker.LogName(), err)
 }
 
 func (ac *accumulator) SetPrecision(precision time.Duration) {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,18 +19,64 @@ import (
 // Agent runs a set of plugins.
 type Agent struct {
 	Config *config.Config
+
+	reloadC chan reloadRequest
+	stopped chan struct{}
+
+	startTime time.Time
+	inputC    chan telegraf.Metric
+	outputC   chan telegraf.Metric
+
+	// Set while all plugins are running, read by the health endpoint.
+	ready int32
+
+	// Units for the currently running plugins, these are only modified by
+	// Run and applyConfig.
+	inputsMu   sync.RWMutex
+	inputs     []*inputUnit
+	processing *processingUnit
+
+	outputsMu sync.RWMutex
+	outputs   []*outputUnit
 }
 
 // NewAgent returns an Agent for the given Config.
 func NewAgent(config *config.Config) (*Agent, error) {
 	a := &Agent{
-		Config: config,
+		Config:  config,
+		reloadC: make(chan reloadRequest),
+		stopped: make(chan struct{}),
 	}
 	return a, nil
 }
 
+// inputUnit is an input gathering in the background.
+type inputUnit struct {
+	input  *models.RunningInput
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// processingUnit applies the processors and aggregators to metrics in the
+// background.
+type processingUnit struct {
+	processors  models.RunningProcessors
+	aggregators []*models.RunningAggregator
+	cancel      context.CancelFunc
+	done        chan struct{}
+}
+
+// outputUnit is an output flushing in the background.
+type outputUnit struct {
+	output *models.RunningOutput
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
 // Run starts and runs the Agent until the context is done.
 func (a *Agent) Run(ctx context.Context) error {
+	defer close(a.stopped)
+
 	log.Printf("I! [agent] Config: Interval:%s, Quiet:%#v, Hostname:%#v, "+
 		"Flush Interval:%s",
 		a.Config.Agent.Interval.Duration, a.Config.Agent.Quiet,
@@ -39,100 +86,253 @@ func (a *Agent) Run(ctx context.Context) error {
 		return ctx.Err()
 	}
 
+	stopHealthServer, err := a.startHealthServer()
+	if err != nil {
+		return err
+	}
+	defer stopHealthServer()
+
 	log.Printf("D! [agent] Connecting outputs")
-	err := a.connectOutputs(ctx)
+	err = a.connectOutputs(ctx)
 	if err != nil {
 		return err
 	}
 
-	inputC := make(chan telegraf.Metric, 100)
-	procC := make(chan telegraf.Metric, 100)
-	outputC := make(chan telegraf.Metric, 100)
+	a.inputC = make(chan telegraf.Metric, 100)
+	a.outputC = make(chan telegraf.Metric, 100)
 
-	startTime := time.Now()
+	a.startTime = time.Now()
 
 	log.Printf("D! [agent] Starting service inputs")
-	err = a.startServiceInputs(ctx, inputC)
+	err = a.startServiceInputs(ctx, a.inputC)
 	if err != nil {
 		return err
 	}
 
-	var wg sync.WaitGroup
-
-	src := inputC
-	dst := inputC
+	a.outputsMu.Lock()
+	for _, output := range a.Config.Outputs {
+		a.outputs = append(a.outputs, a.startOutput(output))
+	}
+	a.outputsMu.Unlock()
 
-	wg.Add(1)
-	go func(dst chan telegraf.Metric) {
-		defer wg.Done()
+	routeDone := make(chan struct{})
+	go func() {
+		defer close(routeDone)
 
-		err := a.runInputs(ctx, startTime, dst)
+		err := a.runOutputs(a.outputC)
 		if err != nil {
-			log.Printf("E! [agent] Error running inputs: %v", err)
+			log.Printf("E! [agent] Error running outputs: %v", err)
+		}
+	}()
+
+	a.processing = a.startProcessing(a.Config.Processors, a.Config.Aggregators)
+
+	a.inputsMu.Lock()
+	for _, input := range a.Config.Inputs {
+		a.inputs = append(a.inputs, a.startInput(ctx, input))
+	}
+	a.inputsMu.Unlock()
+
+	a.setReady(true)
+	a.handleReloads(ctx)
+	a.setReady(false)
+
+	log.Printf("D! [agent] Stopping inputs")
+	for _, unit := range a.inputs {
+		stopInput(unit)
+	}
+	close(a.inputC)
+	log.Printf("D! [agent] Input channel closed")
+
+	<-a.processing.done
+	close(a.outputC)
+	log.Printf("D! [agent] Output channel closed")
+	<-routeDone
+
+	log.Println("I! [agent] Hang on, flushing any cached metrics before shutdown")
+	a.outputsMu.Lock()
+	outputs := a.outputs
+	a.outputs = nil
+	a.outputsMu.Unlock()
+	for _, unit := range outputs {
+		stopOutput(unit)
+	}
+
+	log.Printf("D! [agent] Closing outputs")
+	a.closeOutputs()
+
+	log.Printf("D! [agent] Stopped Successfully")
+	return nil
+}
+
+// handleReloads applies configuration reloads until the context is done.
+func (a *Agent) handleReloads(ctx context.Context) {
+	for {
+		select {
+		case req := <-a.reloadC:
+			req.err <- a.applyConfig(ctx, req.config)
+		case <-ctx.Done():
+			return
 		}
+	}
+}
 
-		log.Printf("D! [agent] Stopping service inputs")
-		a.stopServiceInputs()
+// startInput starts gathering from an input until the context is done or
+// the input is stopped.  Service inputs must already be started.
+func (a *Agent) startInput(
+	ctx context.Context,
+	input *models.RunningInput,
+) *inputUnit {
+	interval := a.Config.Agent.Interval.Duration
+	jitter := a.Config.Agent.CollectionJitter.Duration
 
-		close(dst)
-		log.Printf("D! [agent] Input channel closed")
-	}(dst)
+	// Overwrite agent interval if this plugin has its own.
+	if input.Config.Interval != 0 {
+		interval = input.Config.Interval
+	}
 
-	src = dst
+	acc := NewAccumulator(input, a.inputC)
+	acc.SetPrecision(a.Precision())
 
-	if len(a.Config.Processors) > 0 {
-		dst = procC
+	ctx, cancel := context.WithCancel(ctx)
+	unit := &inputUnit{
+		input:  input,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
 
-		wg.Add(1)
-		go func(src, dst chan telegraf.Metric) {
-			defer wg.Done()
+	go func() {
+		defer close(unit.done)
 
-			err := a.runProcessors(src, dst)
+		if a.Config.Agent.RoundInterval {
+			err := internal.SleepContext(
+				ctx, internal.AlignDuration(a.startTime, interval))
 			if err != nil {
-				log.Printf("E! [agent] Error running processors: %v", err)
+				return
 			}
-			close(dst)
-			log.Printf("D! [agent] Processor channel closed")
-		}(src, dst)
+		}
+
+		a.gatherOnInterval(ctx, acc, input, interval, jitter)
+	}()
 
-		src = dst
+	return unit
+}
+
+// stopInput stops the periodic gather of an input, waits for an ongoing
+// Gather call to complete and stops the service if it is a service input.
+func stopInput(unit *inputUnit) {
+	unit.cancel()
+	<-unit.done
+
+	if si, ok := unit.input.Input.(telegraf.ServiceInput); ok {
+		si.Stop()
+	}
+}
+
+// startProcessing starts applying the processors and aggregators to the
+// metrics from the input channel until the channel is closed or the
+// processing is stopped.
+func (a *Agent) startProcessing(
+	processors models.RunningProcessors,
+	aggregators []*models.RunningAggregator,
+) *processingUnit {
+	ctx, cancel := context.WithCancel(context.Background())
+	unit := &processingUnit{
+		processors:  processors,
+		aggregators: aggregators,
+		cancel:      cancel,
+		done:        make(chan struct{}),
 	}
 
-	if len(a.Config.Aggregators) > 0 {
-		dst = outputC
+	go func() {
+		defer close(unit.done)
+
+		if len(aggregators) == 0 {
+			a.runProcessing(ctx, processors, a.outputC)
+			return
+		}
 
-		wg.Add(1)
-		go func(src, dst chan telegraf.Metric) {
-			defer wg.Done()
+		// The streaming processors run until the aggregators have been
+		// pushed one final time, so that the aggregated metrics go through
+		// all of the processors.
+		procC := make(chan telegraf.Metric, 100)
+		streamC := make(chan telegraf.Metric, 100)
+		running, streams := a.startStreams(processors, streamC)
 
-			err := a.runAggregators(startTime, src, dst)
+		inputDone := make(chan struct{})
+		go func() {
+			err := a.runProcessors(ctx, running, a.inputC, procC)
 			if err != nil {
-				log.Printf("E! [agent] Error running aggregators: %v", err)
+				log.Printf("E! [agent] Error running processors: %v", err)
 			}
-			close(dst)
-			log.Printf("D! [agent] Output channel closed")
-		}(src, dst)
+			close(inputDone)
+		}()
 
-		src = dst
+		forwarded := make(chan struct{})
+		go func() {
+			defer close(forwarded)
+			forwardStreams(streamC, procC, a.outputC, inputDone)
+			log.Printf("D! [agent] Processor channel closed")
+		}()
+
+		err := a.runAggregators(a.startTime, running, aggregators, procC, a.outputC)
+		if err != nil {
+			log.Printf("E! [agent] Error running aggregators: %v", err)
+		}
+
+		stopStreams(streams)
+		close(streamC)
+		<-forwarded
+	}()
+
+	return unit
+}
+
+// stopProcessing stops reading from the input channel, the aggregators are
+// pushed one final time.  Unread metrics are left in the input channel.
+func stopProcessing(unit *processingUnit) {
+	unit.cancel()
+	<-unit.done
+}
+
+// startOutput starts the periodic flush of an output until it is stopped.
+func (a *Agent) startOutput(output *models.RunningOutput) *outputUnit {
+	interval := a.Config.Agent.FlushInterval.Duration
+	jitter := a.Config.Agent.FlushJitter.Duration
+
+	// Overwrite agent flush_interval if this plugin has its own.
+	if output.Config.FlushInterval != 0 {
+		interval = output.Config.FlushInterval
 	}
 
-	wg.Add(1)
-	go func(src chan telegraf.Metric) {
-		defer wg.Done()
+	ctx, cancel := context.WithCancel(context.Background())
+	unit := &outputUnit{
+		output: output,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
 
-		err := a.runOutputs(startTime, src)
-		if err != nil {
-			log.Printf("E! [agent] Error running outputs: %v", err)
+	go func() {
+		defer close(unit.done)
+
+		// If the output is stopped while waiting, flush still writes the
+		// buffered metrics one final time.
+		if a.Config.Agent.RoundInterval {
+			internal.SleepContext(
+				ctx, internal.AlignDuration(a.startTime, interval))
 		}
-	}(src)
 
-	wg.Wait()
+		a.flush(ctx, output, interval, jitter)
+	}()
 
-	log.Printf("D! [agent] Closing outputs")
-	a.closeOutputs()
+	return unit
+}
 
-	log.Printf("D! [agent] Stopped Successfully")
-	return nil
+// stopOutput stops the periodic flush of an output after writing the
+// metrics remaining in its buffer.
+func stopOutput(unit *outputUnit) {
+	unit.cancel()
+	<-unit.done
 }
 
 // Test runs the inputs once and prints the output to stdout in line protocol.
@@ -168,6 +368,74 @@ func (a *Agent) Test(ctx context.Context) error {
 		}
 	}()
 
+	return a.testGather(ctx, metricC, nulC)
+}
+
+// TestRoutes runs the inputs and processors once and prints each metric with
+// the outputs it would be written to.  The outputs are not connected.
+func (a *Agent) TestRoutes(ctx context.Context) error {
+	var wg sync.WaitGroup
+	metricC := make(chan telegraf.Metric)
+	nulC := make(chan telegraf.Metric)
+	defer func() {
+		close(metricC)
+		close(nulC)
+		wg.Wait()
+	}()
+
+	processors := withoutStreaming(a.Config.Processors)
+	if len(processors) != len(a.Config.Processors) {
+		log.Printf("W! [agent] Streaming processors are skipped when testing routes")
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		s := influx.NewSerializer()
+		s.SetFieldSortOrder(influx.SortFields)
+		for metric := range metricC {
+			for _, metric := range applyProcessors(processors, metric) {
+				route := models.TakeRoute(metric)
+
+				var names []string
+				for _, output := range a.Config.Outputs {
+					if output.Routed(route) && output.Config.Filter.Select(metric) {
+						names = append(names, output.LogName())
+					}
+				}
+				if len(names) == 0 {
+					names = append(names, "(dropped)")
+				}
+
+				octets, err := s.Serialize(metric)
+				if err == nil {
+					fmt.Printf("> %s -> %s\n",
+						strings.TrimSuffix(string(octets), "\n"),
+						strings.Join(names, ", "))
+				}
+				metric.Drop()
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range nulC {
+		}
+	}()
+
+	return a.testGather(ctx, metricC, nulC)
+}
+
+// testGather runs each input once, metrics are sent to metricC.  Metrics of
+// inputs that need a warm up gather are sent to nulC first.
+func (a *Agent) testGather(
+	ctx context.Context,
+	metricC chan<- telegraf.Metric,
+	nulC chan<- telegraf.Metric,
+) error {
 	for _, input := range a.Config.Inputs {
 		select {
 		case <-ctx.Done():
@@ -175,7 +443,7 @@ func (a *Agent) Test(ctx context.Context) error {
 		default:
 			if _, ok := input.Input.(telegraf.ServiceInput); ok {
 				log.Printf("W!: [agent] skipping plugin [[%s]]: service inputs not supported in --test mode",
-					input.Name())
+					input.LogName())
 				continue
 			}
 
@@ -190,16 +458,16 @@ func (a *Agent) Test(ctx context.Context) error {
 				nulAcc := NewAccumulator(input, nulC)
 				nulAcc.SetPrecision(a.Precision())
 				if err := input.Input.Gather(nulAcc); err != nil {
-					return err
+					return fmt.Errorf("%s: %v", input.LogName(), err)
 				}
 
 				time.Sleep(500 * time.Millisecond)
 				if err := input.Input.Gather(acc); err != nil {
-					return err
+					return fmt.Errorf("%s: %v", input.LogName(), err)
 				}
 			default:
 				if err := input.Input.Gather(acc); err != nil {
-					return err
+					return fmt.Errorf("%s: %v", input.LogName(), err)
 				}
 			}
 		}
@@ -208,48 +476,6 @@ func (a *Agent) Test(ctx context.Context) error {
 	return nil
 }
 
-// runInputs starts and triggers the periodic gather for Inputs.
-//
-// When the context is done the timers are stopped and this function returns
-// after all ongoing Gather calls complete.
-func (a *Agent) runInputs(
-	ctx context.Context,
-	startTime time.Time,
-	dst chan<- telegraf.Metric,
-) error {
-	var wg sync.WaitGroup
-	for _, input := range a.Config.Inputs {
-		interval := a.Config.Agent.Interval.Duration
-		jitter := a.Config.Agent.CollectionJitter.Duration
-
-		// Overwrite agent interval if this plugin has its own.
-		if input.Config.Interval != 0 {
-			interval = input.Config.Interval
-		}
-
-		acc := NewAccumulator(input, dst)
-		acc.SetPrecision(a.Precision())
-
-		wg.Add(1)
-		go func(input *models.RunningInput) {
-			defer wg.Done()
-
-			if a.Config.Agent.RoundInterval {
-				err := internal.SleepContext(
-					ctx, internal.AlignDuration(startTime, interval))
-				if err != nil {
-					return
-				}
-			}
-
-			a.gatherOnInterval(ctx, acc, input, interval, jitter)
-		}(input)
-	}
-	wg.Wait()
-
-	return nil
-}
-
 // gather runs an input's gather function periodically until the context is
 // done.
 func (a *Agent) gatherOnInterval(
@@ -305,31 +531,63 @@ func (a *Agent) gatherOnce(
 			return err
 		case <-ticker.C:
 			log.Printf("W! [agent] input %q did not complete within its interval",
-				input.Name())
+				input.LogName())
 		}
 	}
 }
 
+// runProcessing starts the streaming processors, applies the processors to
+// the metrics from the input channel until it is closed or the context is
+// done, and then stops the streaming processors.
+func (a *Agent) runProcessing(
+	ctx context.Context,
+	processors models.RunningProcessors,
+	dst chan<- telegraf.Metric,
+) {
+	processors, streams := a.startStreams(processors, dst)
+
+	err := a.runProcessors(ctx, processors, a.inputC, dst)
+	if err != nil {
+		log.Printf("E! [agent] Error running processors: %v", err)
+	}
+
+	stopStreams(streams)
+}
+
 // runProcessors applies processors to metrics.
+//
+// Runs until src is closed or the context is done.
 func (a *Agent) runProcessors(
+	ctx context.Context,
+	processors models.RunningProcessors,
 	src <-chan telegraf.Metric,
 	agg chan<- telegraf.Metric,
 ) error {
-	for metric := range src {
-		metrics := a.applyProcessors(metric)
+	for {
+		select {
+		case metric, ok := <-src:
+			if !ok {
+				return nil
+			}
 
-		for _, metric := range metrics {
-			agg <- metric
+			metrics := applyProcessors(processors, metric)
+
+			for _, metric := range metrics {
+				agg <- metric
+			}
+		case <-ctx.Done():
+			return nil
 		}
 	}
-
-	return nil
 }
 
 // applyProcessors applies all processors to a metric.
-func (a *Agent) applyProcessors(m telegraf.Metric) []telegraf.Metric {
+func applyProcessors(
+	processors models.RunningProcessors,
+	m telegraf.Metric,
+) []telegraf.Metric {
 	metrics := []telegraf.Metric{m}
-	for _, processor := range a.Config.Processors {
+	for _, processor := range processors {
 		metrics = processor.Apply(metrics...)
 	}
 
@@ -359,6 +617,8 @@ func updateWindow(start time.Time, roundInterval bool, period time.Duration) (ti
 // push one final time before returning.
 func (a *Agent) runAggregators(
 	startTime time.Time,
+	processors models.RunningProcessors,
+	aggregators []*models.RunningAggregator,
 	src <-chan telegraf.Metric,
 	dst chan<- telegraf.Metric,
 ) error {
@@ -366,7 +626,7 @@ func (a *Agent) runAggregators(
 
 	// Before calling Add, initialize the aggregation window.  This ensures
 	// that any metric created after start time will be aggregated.
-	for _, agg := range a.Config.Aggregators {
+	for _, agg := range aggregators {
 		since, until := updateWindow(startTime, a.Config.Agent.RoundInterval, agg.Period())
 		agg.UpdateWindow(since, until)
 	}
@@ -377,7 +637,7 @@ func (a *Agent) runAggregators(
 		defer wg.Done()
 		for metric := range src {
 			var dropOriginal bool
-			for _, agg := range a.Config.Aggregators {
+			for _, agg := range aggregators {
 				if ok := agg.Add(metric); ok {
 					dropOriginal = true
 				}
@@ -398,7 +658,7 @@ func (a *Agent) runAggregators(
 		defer wg.Done()
 
 		var aggWg sync.WaitGroup
-		for _, agg := range a.Config.Aggregators {
+		for _, agg := range aggregators {
 			aggWg.Add(1)
 			go func(agg *models.RunningAggregator) {
 				defer aggWg.Done()
@@ -414,7 +674,7 @@ func (a *Agent) runAggregators(
 	}()
 
 	for metric := range aggregations {
-		metrics := a.applyProcessors(metric)
+		metrics := applyProcessors(processors, metric)
 		for _, metric := range metrics {
 			dst <- metric
 		}
@@ -448,58 +708,36 @@ func (a *Agent) push(
 	}
 }
 
-// runOutputs triggers the periodic write for Outputs.
+// runOutputs passes metrics to the running outputs.
 //
-
-// Runs until src is closed and all metrics have been processed.  Will call
-// Write one final time before returning.
+// Runs until src is closed and all metrics have been processed.
 func (a *Agent) runOutputs(
-	startTime time.Time,
 	src <-chan telegraf.Metric,
 ) error {
-	interval := a.Config.Agent.FlushInterval.Duration
-	jitter := a.Config.Agent.FlushJitter.Duration
-
-	ctx, cancel := context.WithCancel(context.Background())
-
-	var wg sync.WaitGroup
-	for _, output := range a.Config.Outputs {
-		interval := interval
-		// Overwrite agent flush_interval if this plugin has its own.
-		if output.Config.FlushInterval != 0 {
-			interval = output.Config.FlushInterval
-		}
-
-		wg.Add(1)
-		go func(output *models.RunningOutput) {
-			defer wg.Done()
-
-			if a.Config.Agent.RoundInterval {
-				err := internal.SleepContext(
-					ctx, internal.AlignDuration(startTime, interval))
-				if err != nil {
-					return
-				}
-			}
-
-			a.flush(ctx, output, interval, jitter)
-		}(output)
-	}
-
 	for metric := range src {
-		for i, output := range a.Config.Outputs {
-			if i == len(a.Config.Outputs)-1 {
-				output.AddMetric(metric)
-			} else {
-				output.AddMetric(metric.Copy())
+		route := models.TakeRoute(metric)
+
+		// Each output gets a copy of the metric, except for the last output
+		// it is routed to.
+		var last *models.RunningOutput
+		a.outputsMu.RLock()
+		for _, unit := range a.outputs {
+			if !unit.output.Routed(route) {
+				continue
 			}
+			if last != nil {
+				last.AddMetric(metric.Copy())
+			}
+			last = unit.output
+		}
+		if last != nil {
+			last.AddMetric(metric)
+		} else {
+			metric.Drop()
 		}
+		a.outputsMu.RUnlock()
 	}
 
-	log.Println("I! [agent] Hang on, flushing any cached metrics before shutdown")
-	cancel()
-	wg.Wait()
-
 	return nil
 }
 
@@ -518,7 +756,7 @@ func (a *Agent) flush(
 
 	logError := func(err error) {
 		if err != nil {
-			log.Printf("E! [agent] Error writing to output [%s]: %v", output.Name, err)
+			log.Printf("E! [agent] Error writing to output [%s]: %v", output.LogName(), err)
 		}
 	}
 
@@ -526,7 +764,7 @@ func (a *Agent) flush(
 		// Favor shutdown over other methods.
 		select {
 		case <-ctx.Done():
-			logError(a.flushOnce(output, interval, output.Write))
+			logError(a.flushOnce(output, interval, output.WriteFinal))
 			return
 		default:
 		}
@@ -543,7 +781,7 @@ func (a *Agent) flush(
 				logError(a.flushOnce(output, interval, output.WriteBatch))
 			}
 		case <-ctx.Done():
-			logError(a.flushOnce(output, interval, output.Write))
+			logError(a.flushOnce(output, interval, output.WriteFinal))
 			return
 		}
 	}
@@ -571,7 +809,7 @@ func (a *Agent) flushOnce(
 			return err
 		case <-ticker.C:
 			log.Printf("W! [agent] output %q did not complete within its flush interval",
-				output.Name)
+				output.LogName())
 			output.LogBufferStatus()
 		}
 	}
@@ -581,24 +819,39 @@ func (a *Agent) flushOnce(
 // connectOutputs connects to all outputs.
 func (a *Agent) connectOutputs(ctx context.Context) error {
 	for _, output := range a.Config.Outputs {
-		log.Printf("D! [agent] Attempting connection to output: %s\n", output.Name)
-		err := output.Output.Connect()
+		err := connectOutput(ctx, output)
 		if err != nil {
-			log.Printf("E! [agent] Failed to connect to output %s, retrying in 15s, "+
-				"error was '%s' \n", output.Name, err)
+			return err
+		}
+	}
+	return nil
+}
 
-			err := internal.SleepContext(ctx, 15*time.Second)
-			if err != nil {
-				return err
-			}
+// connectOutput opens the buffer of an output and connects to it, retrying
+// the connection once after a delay.
+func connectOutput(ctx context.Context, output *models.RunningOutput) error {
+	err := output.Init()
+	if err != nil {
+		return fmt.Errorf("initializing output %s: %v", output.LogName(), err)
+	}
 
-			err = output.Output.Connect()
-			if err != nil {
-				return err
-			}
+	log.Printf("D! [agent] Attempting connection to output: %s\n", output.LogName())
+	err = output.Connect()
+	if err != nil {
+		log.Printf("E! [agent] Failed to connect to output %s, retrying in 15s, "+
+			"error was '%s' \n", output.LogName(), err)
+
+		err := internal.SleepContext(ctx, 15*time.Second)
+		if err != nil {
+			return err
+		}
+
+		err = output.Connect()
+		if err != nil {
+			return err
 		}
-		log.Printf("D! [agent] Successfully connected to output: %s\n", output.Name)
 	}
+	log.Printf("D! [agent] Successfully connected to output: %s\n", output.LogName())
 	return nil
 }
 
@@ -618,18 +871,8 @@ func (a *Agent) startServiceInputs(
 
 	for _, input := range a.Config.Inputs {
 		if si, ok := input.Input.(telegraf.ServiceInput); ok {
-			// Service input plugins are not subject to timestamp rounding.
-			// This only applies to the accumulator passed to Start(), the
-			// Gather() accumulator does apply rounding according to the
-			// precision agent setting.
-			acc := NewAccumulator(input, dst)
-			acc.SetPrecision(time.Nanosecond)
-
-			err := si.Start(acc)
+			err := startServiceInput(input, dst)
 			if err != nil {
-				log.Printf("E! [agent] Service for input %s failed to start: %v",
-					input.Name(), err)
-
 				for _, si := range started {
 					si.Stop()
 				}
@@ -644,13 +887,32 @@ func (a *Agent) startServiceInputs(
 	return nil
 }
 
-// stopServiceInputs stops all service inputs.
-func (a *Agent) stopServiceInputs() {
-	for _, input := range a.Config.Inputs {
-		if si, ok := input.Input.(telegraf.ServiceInput); ok {
-			si.Stop()
-		}
+// startServiceInput starts the service of an input, it does nothing if the
+// input is not a service input.
+func startServiceInput(
+	input *models.RunningInput,
+	dst chan<- telegraf.Metric,
+) error {
+	si, ok := input.Input.(telegraf.ServiceInput)
+	if !ok {
+		return nil
 	}
+
+	// Service input plugins are not subject to timestamp rounding.
+	// This only applies to the accumulator passed to Start(), the
+	// Gather() accumulator does apply rounding according to the
+	// precision agent setting.
+	acc := NewAccumulator(input, dst)
+	acc.SetPrecision(time.Nanosecond)
+
+	err := si.Start(acc)
+	if err != nil {
+		log.Printf("E! [agent] Service for input %s failed to start: %v",
+			input.LogName(), err)
+		return err
+	}
+
+	return nil
 }
 
 // Returns the rounding precision for metrics.
@@ -680,7 +942,7 @@ func panicRecover(input *models.RunningInput) {
 		trace := make([]byte, 2048)
 		runtime.Stack(trace, true)
 		log.Printf("E! FATAL: Input [%s] panicked: %s, Stack:\n%s\n",
-			input.Name(), err, trace)
+			input.LogName(), err, trace)
 		log.Println("E! PLEASE REPORT THIS PANIC ON GITHUB with " +
 			"stack trace, configuration, and OS information: " +
 			"https://github.com/influxdata/telegraf/issues/new/choose")
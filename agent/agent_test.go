@@ -4,9 +4,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
 	_ "github.com/influxdata/telegraf/plugins/inputs/all"
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
+	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -166,3 +169,70 @@ func TestWindow(t *testing.T) {
 		})
 	}
 }
+
+type recordingOutput struct {
+	metrics []telegraf.Metric
+}
+
+func (o *recordingOutput) Connect() error       { return nil }
+func (o *recordingOutput) Close() error         { return nil }
+func (o *recordingOutput) SampleConfig() string { return "" }
+func (o *recordingOutput) Description() string  { return "" }
+
+func (o *recordingOutput) Write(metrics []telegraf.Metric) error {
+	o.metrics = append(o.metrics, metrics...)
+	return nil
+}
+
+func TestAgent_RunOutputsRoutes(t *testing.T) {
+	a, err := NewAgent(config.NewConfig())
+	require.NoError(t, err)
+
+	outputs := map[string]*recordingOutput{}
+	for _, tt := range []struct {
+		name   string
+		routes []string
+	}{
+		{name: "all"},
+		{name: "a", routes: []string{"a"}},
+		{name: "ab", routes: []string{"a", "b"}},
+	} {
+		output := &recordingOutput{}
+		outputs[tt.name] = output
+		ro := models.NewRunningOutput(tt.name, output,
+			&models.OutputConfig{Name: tt.name, Routes: tt.routes}, 10, 100)
+		a.outputs = append(a.outputs, &outputUnit{output: ro})
+	}
+
+	src := make(chan telegraf.Metric, 3)
+	src <- testutil.MustMetric("none", map[string]string{},
+		map[string]interface{}{"value": 1}, time.Unix(0, 0))
+	src <- testutil.MustMetric("a", map[string]string{models.RouteTag: "a"},
+		map[string]interface{}{"value": 1}, time.Unix(0, 0))
+	src <- testutil.MustMetric("b", map[string]string{models.RouteTag: "b"},
+		map[string]interface{}{"value": 1}, time.Unix(0, 0))
+	close(src)
+
+	require.NoError(t, a.runOutputs(src))
+
+	names := func(name string) []string {
+		unit := a.outputs[0]
+		for _, u := range a.outputs {
+			if u.output.Name == name {
+				unit = u
+			}
+		}
+		require.NoError(t, unit.output.Write())
+
+		var result []string
+		for _, m := range outputs[name].metrics {
+			require.False(t, m.HasTag(models.RouteTag))
+			result = append(result, m.Name())
+		}
+		return result
+	}
+
+	require.ElementsMatch(t, []string{"none", "a", "b"}, names("all"))
+	require.ElementsMatch(t, []string{"a"}, names("a"))
+	require.ElementsMatch(t, []string{"a", "b"}, names("ab"))
+}
@@ -0,0 +1,233 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+const (
+	healthPass = "pass"
+	healthFail = "fail"
+)
+
+// healthResponse is the body of the /health endpoint.
+type healthResponse struct {
+	Status  string         `json:"status"`
+	Ready   bool           `json:"ready"`
+	Checks  []string       `json:"checks,omitempty"`
+	Inputs  []inputHealth  `json:"inputs"`
+	Outputs []outputHealth `json:"outputs"`
+}
+
+type inputHealth struct {
+	Name                string     `json:"name"`
+	Healthy             bool       `json:"healthy"`
+	LastGather          *time.Time `json:"last_gather,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorTime       *time.Time `json:"last_error_time,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+}
+
+type outputHealth struct {
+	Name                string     `json:"name"`
+	Healthy             bool       `json:"healthy"`
+	BufferSize          int        `json:"buffer_size"`
+	BufferFill          float64    `json:"buffer_fill_percent"`
+	LastWrite           *time.Time `json:"last_write,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	LastErrorTime       *time.Time `json:"last_error_time,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	CircuitState        string     `json:"circuit_state"`
+}
+
+// startHealthServer starts the HTTP health endpoint if an address is
+// configured.  The returned function stops the server.
+func (a *Agent) startHealthServer() (func(), error) {
+	address := a.Config.Agent.HealthAddress
+	if address == "" {
+		return func() {}, nil
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("error starting health endpoint: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health/live", a.handleLive)
+	mux.HandleFunc("/health/ready", a.handleReady)
+	mux.HandleFunc("/health", a.handleHealth)
+
+	server := &http.Server{
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("E! [agent] Error serving health endpoint: %v", err)
+		}
+	}()
+
+	log.Printf("I! [agent] Health endpoint listening on %s", listener.Addr())
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}, nil
+}
+
+// handleLive reports that the agent process is running.
+func (a *Agent) handleLive(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK\n"))
+}
+
+// handleReady reports whether the agent has started all plugins and is
+// not shutting down.
+func (a *Agent) handleReady(w http.ResponseWriter, r *http.Request) {
+	if !a.isReady() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK\n"))
+}
+
+// handleHealth reports the state of the plugins, the check fails if any
+// plugin exceeds a configured threshold or the agent is not ready.
+func (a *Agent) handleHealth(w http.ResponseWriter, r *http.Request) {
+	resp := a.health()
+
+	code := http.StatusOK
+	if resp.Status != healthPass {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (a *Agent) isReady() bool {
+	return atomic.LoadInt32(&a.ready) == 1
+}
+
+func (a *Agent) setReady(ready bool) {
+	var v int32
+	if ready {
+		v = 1
+	}
+	atomic.StoreInt32(&a.ready, v)
+}
+
+// health checks the status of the running plugins against the configured
+// thresholds.
+func (a *Agent) health() *healthResponse {
+	resp := &healthResponse{
+		Status:  healthPass,
+		Ready:   a.isReady(),
+		Inputs:  []inputHealth{},
+		Outputs: []outputHealth{},
+	}
+
+	if !resp.Ready {
+		resp.Checks = append(resp.Checks, "agent is not ready")
+	}
+
+	maxGatherFailures := a.Config.Agent.HealthMaxGatherFailures
+	for _, input := range a.runningInputs() {
+		status := input.Status()
+		ih := inputHealth{
+			Name:                input.LogName(),
+			Healthy:             true,
+			LastGather:          timePtr(status.LastGather),
+			LastError:           status.LastError,
+			LastErrorTime:       timePtr(status.LastErrorTime),
+			ConsecutiveFailures: status.ConsecutiveFailures,
+		}
+
+		if maxGatherFailures > 0 && status.ConsecutiveFailures >= maxGatherFailures {
+			ih.Healthy = false
+			resp.Checks = append(resp.Checks, fmt.Sprintf(
+				"%s failed %d gathers in a row", ih.Name, status.ConsecutiveFailures))
+		}
+		resp.Inputs = append(resp.Inputs, ih)
+	}
+
+	maxBufferFill := a.Config.Agent.HealthMaxBufferFill
+	maxWriteFailures := a.Config.Agent.HealthMaxWriteFailures
+	for _, output := range a.runningOutputs() {
+		status := output.Status()
+		oh := outputHealth{
+			Name:                output.LogName(),
+			Healthy:             true,
+			BufferSize:          status.BufferSize,
+			BufferFill:          status.BufferFill * 100,
+			LastWrite:           timePtr(status.LastWrite),
+			LastError:           status.LastError,
+			LastErrorTime:       timePtr(status.LastErrorTime),
+			ConsecutiveFailures: status.ConsecutiveFailures,
+			CircuitState:        status.CircuitState.String(),
+		}
+
+		if maxBufferFill > 0 && oh.BufferFill >= maxBufferFill {
+			oh.Healthy = false
+			resp.Checks = append(resp.Checks, fmt.Sprintf(
+				"%s buffer is %.1f%% full", oh.Name, oh.BufferFill))
+		}
+		if maxWriteFailures > 0 && status.ConsecutiveFailures >= maxWriteFailures {
+			oh.Healthy = false
+			resp.Checks = append(resp.Checks, fmt.Sprintf(
+				"%s failed %d writes in a row", oh.Name, status.ConsecutiveFailures))
+		}
+		resp.Outputs = append(resp.Outputs, oh)
+	}
+
+	if len(resp.Checks) > 0 {
+		resp.Status = healthFail
+	}
+	return resp
+}
+
+// runningInputs returns the inputs that are currently running.
+func (a *Agent) runningInputs() []*models.RunningInput {
+	a.inputsMu.RLock()
+	defer a.inputsMu.RUnlock()
+
+	inputs := make([]*models.RunningInput, 0, len(a.inputs))
+	for _, unit := range a.inputs {
+		inputs = append(inputs, unit.input)
+	}
+	return inputs
+}
+
+// runningOutputs returns the outputs that are currently running.
+func (a *Agent) runningOutputs() []*models.RunningOutput {
+	a.outputsMu.RLock()
+	defer a.outputsMu.RUnlock()
+
+	outputs := make([]*models.RunningOutput, 0, len(a.outputs))
+	for _, unit := range a.outputs {
+		outputs = append(outputs, unit.output)
+	}
+	return outputs
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
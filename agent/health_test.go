@@ -0,0 +1,156 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type failingInput struct {
+	err error
+}
+
+func (i *failingInput) SampleConfig() string              { return "" }
+func (i *failingInput) Description() string               { return "" }
+func (i *failingInput) Gather(telegraf.Accumulator) error { return i.err }
+
+type failingOutput struct {
+	err error
+}
+
+func (o *failingOutput) Connect() error                { return nil }
+func (o *failingOutput) Close() error                  { return nil }
+func (o *failingOutput) SampleConfig() string          { return "" }
+func (o *failingOutput) Description() string           { return "" }
+func (o *failingOutput) Write([]telegraf.Metric) error { return o.err }
+
+func newHealthTestAgent(t *testing.T) (*Agent, *models.RunningInput, *models.RunningOutput) {
+	a, err := NewAgent(config.NewConfig())
+	require.NoError(t, err)
+
+	input := models.NewRunningInput(&failingInput{}, &models.InputConfig{Name: "test"})
+	output := models.NewRunningOutput("test", &failingOutput{},
+		&models.OutputConfig{Name: "test"}, 1, 10)
+
+	a.inputs = []*inputUnit{{input: input}}
+	a.outputs = []*outputUnit{{output: output}}
+	return a, input, output
+}
+
+func getHealth(t *testing.T, a *Agent) (int, *healthResponse) {
+	rec := httptest.NewRecorder()
+	a.handleHealth(rec, httptest.NewRequest("GET", "/health", nil))
+
+	var resp healthResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec.Code, &resp
+}
+
+func TestHealth_Ready(t *testing.T) {
+	a, _, _ := newHealthTestAgent(t)
+
+	rec := httptest.NewRecorder()
+	a.handleReady(rec, httptest.NewRequest("GET", "/health/ready", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	code, resp := getHealth(t, a)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, healthFail, resp.Status)
+
+	a.setReady(true)
+
+	rec = httptest.NewRecorder()
+	a.handleReady(rec, httptest.NewRequest("GET", "/health/ready", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	code, resp = getHealth(t, a)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, healthPass, resp.Status)
+	require.Len(t, resp.Inputs, 1)
+	require.Equal(t, "inputs.test", resp.Inputs[0].Name)
+	require.Len(t, resp.Outputs, 1)
+	require.Equal(t, "outputs.test", resp.Outputs[0].Name)
+}
+
+func TestHealth_Live(t *testing.T) {
+	a, _, _ := newHealthTestAgent(t)
+
+	rec := httptest.NewRecorder()
+	a.handleLive(rec, httptest.NewRequest("GET", "/health/live", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestHealth_GatherFailures(t *testing.T) {
+	a, input, _ := newHealthTestAgent(t)
+	a.Config.Agent.HealthMaxGatherFailures = 2
+	a.setReady(true)
+
+	input.Input.(*failingInput).err = errors.New("gather failed")
+	input.Gather(&testutil.Accumulator{})
+
+	code, _ := getHealth(t, a)
+	require.Equal(t, http.StatusOK, code)
+
+	input.Gather(&testutil.Accumulator{})
+
+	code, resp := getHealth(t, a)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.False(t, resp.Inputs[0].Healthy)
+	require.Equal(t, 2, resp.Inputs[0].ConsecutiveFailures)
+	require.Equal(t, "gather failed", resp.Inputs[0].LastError)
+}
+
+func TestHealth_WriteFailures(t *testing.T) {
+	a, _, output := newHealthTestAgent(t)
+	a.Config.Agent.HealthMaxWriteFailures = 1
+	a.setReady(true)
+
+	output.Output.(*failingOutput).err = errors.New("write failed")
+	output.AddMetric(testutil.TestMetric(1.0))
+	require.Error(t, output.Write())
+
+	code, resp := getHealth(t, a)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.False(t, resp.Outputs[0].Healthy)
+	require.Equal(t, "write failed", resp.Outputs[0].LastError)
+}
+
+func TestHealth_BufferFill(t *testing.T) {
+	a, _, output := newHealthTestAgent(t)
+	a.Config.Agent.HealthMaxBufferFill = 50
+	a.setReady(true)
+
+	for i := 0; i < 4; i++ {
+		output.AddMetric(testutil.TestMetric(1.0))
+	}
+	code, _ := getHealth(t, a)
+	require.Equal(t, http.StatusOK, code)
+
+	output.AddMetric(testutil.TestMetric(1.0))
+	code, resp := getHealth(t, a)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, 50.0, resp.Outputs[0].BufferFill)
+	require.Equal(t, 5, resp.Outputs[0].BufferSize)
+}
+
+func TestHealth_Server(t *testing.T) {
+	a, _, _ := newHealthTestAgent(t)
+	a.Config.Agent.HealthAddress = "localhost:0"
+
+	stop, err := a.startHealthServer()
+	require.NoError(t, err)
+	stop()
+
+	a.Config.Agent.HealthAddress = ""
+	stop, err = a.startHealthServer()
+	require.NoError(t, err)
+	stop()
+}
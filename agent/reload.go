@@ -0,0 +1,278 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"reflect"
+
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// ErrRestartRequired is returned by Reload when the new configuration can
+// only be applied by restarting the agent.
+var ErrRestartRequired = errors.New("agent settings changed, restart required")
+
+type reloadRequest struct {
+	config *config.Config
+	err    chan error
+}
+
+// Reload replaces the running configuration with c.  Plugins with unchanged
+// settings keep running, unchanged outputs keep their buffered metrics, and
+// all other plugins are stopped or started as needed.
+//
+// Changes to the agent settings or global tags cannot be applied while
+// running, in this case ErrRestartRequired is returned and the running
+// configuration is not modified.
+func (a *Agent) Reload(c *config.Config) error {
+	req := reloadRequest{
+		config: c,
+		err:    make(chan error, 1),
+	}
+
+	select {
+	case a.reloadC <- req:
+	case <-a.stopped:
+		return errors.New("agent is not running")
+	}
+	return <-req.err
+}
+
+// applyConfig starts and stops plugins so that the running plugins match
+// the configuration c.
+func (a *Agent) applyConfig(ctx context.Context, c *config.Config) error {
+	if !reflect.DeepEqual(a.Config.Agent, c.Agent) ||
+		!reflect.DeepEqual(a.Config.Tags, c.Tags) {
+		return ErrRestartRequired
+	}
+
+	var failed int
+
+	// Start the new outputs first so that no metrics are lost while the
+	// rest of the pipeline changes.
+	oldOutputs := make([]string, 0, len(a.outputs))
+	for _, unit := range a.outputs {
+		oldOutputs = append(oldOutputs, unit.output.Config.Hash)
+	}
+	newOutputs := make([]string, 0, len(c.Outputs))
+	for _, output := range c.Outputs {
+		newOutputs = append(newOutputs, output.Config.Hash)
+	}
+	outputMatches, removedOutputs := matchHashes(oldOutputs, newOutputs)
+
+	// A buffer directory can only be opened by one output at a time, the
+	// removed outputs whose directory is used by a new output are stopped
+	// before the new outputs are started.
+	reused := make(map[string]bool)
+	for i, output := range c.Outputs {
+		if outputMatches[i] < 0 && output.Config.BufferDirectory != "" {
+			reused[filepath.Clean(output.Config.BufferDirectory)] = true
+		}
+	}
+	running := a.outputs
+	removed := make([]*outputUnit, 0, len(removedOutputs))
+	stopFirst := make(map[*outputUnit]bool)
+	for _, i := range removedOutputs {
+		unit := running[i]
+		dir := unit.output.Config.BufferDirectory
+		if dir != "" && reused[filepath.Clean(dir)] {
+			stopFirst[unit] = true
+			continue
+		}
+		removed = append(removed, unit)
+	}
+	if len(stopFirst) > 0 {
+		remaining := make([]*outputUnit, 0, len(running))
+		for _, unit := range running {
+			if !stopFirst[unit] {
+				remaining = append(remaining, unit)
+			}
+		}
+		a.outputsMu.Lock()
+		a.outputs = remaining
+		a.outputsMu.Unlock()
+
+		for unit := range stopFirst {
+			log.Printf("I! [agent] Stopping output %s", unit.output.LogName())
+			stopOutput(unit)
+			unit.output.Close()
+		}
+	}
+
+	outputs := make([]*outputUnit, 0, len(c.Outputs))
+	runningOutputs := make([]*models.RunningOutput, 0, len(c.Outputs))
+	for i, output := range c.Outputs {
+		if j := outputMatches[i]; j >= 0 {
+			// The new output is not used, the running output with the same
+			// settings keeps running.
+			output.Close()
+			outputs = append(outputs, running[j])
+			runningOutputs = append(runningOutputs, running[j].output)
+			continue
+		}
+
+		log.Printf("I! [agent] Starting output %s", output.LogName())
+		err := connectOutput(ctx, output)
+		if err != nil {
+			log.Printf("E! [agent] Failed to connect to output %s: %v",
+				output.LogName(), err)
+			output.Close()
+			failed++
+			continue
+		}
+
+		outputs = append(outputs, a.startOutput(output))
+		runningOutputs = append(runningOutputs, output)
+	}
+
+	a.outputsMu.Lock()
+	a.outputs = outputs
+	a.outputsMu.Unlock()
+
+	// Processors and aggregators are replaced together if any of them
+	// changed, as the order of the processors is significant.
+	processors := a.processing.processors
+	aggregators := a.processing.aggregators
+	if !equalHashes(processorHashes(processors), processorHashes(c.Processors)) ||
+		!equalHashes(aggregatorHashes(aggregators), aggregatorHashes(c.Aggregators)) {
+		log.Printf("I! [agent] Restarting processors and aggregators")
+		stopProcessing(a.processing)
+
+		processors = c.Processors
+		aggregators = c.Aggregators
+		a.processing = a.startProcessing(processors, aggregators)
+	}
+
+	oldInputs := make([]string, 0, len(a.inputs))
+	for _, unit := range a.inputs {
+		oldInputs = append(oldInputs, unit.input.Config.Hash)
+	}
+	newInputs := make([]string, 0, len(c.Inputs))
+	for _, input := range c.Inputs {
+		newInputs = append(newInputs, input.Config.Hash)
+	}
+	inputMatches, removedInputs := matchHashes(oldInputs, newInputs)
+
+	for _, i := range removedInputs {
+		log.Printf("I! [agent] Stopping input %s", a.inputs[i].input.LogName())
+		stopInput(a.inputs[i])
+	}
+
+	inputs := make([]*inputUnit, 0, len(c.Inputs))
+	runningInputs := make([]*models.RunningInput, 0, len(c.Inputs))
+	for i, input := range c.Inputs {
+		if j := inputMatches[i]; j >= 0 {
+			inputs = append(inputs, a.inputs[j])
+			runningInputs = append(runningInputs, a.inputs[j].input)
+			continue
+		}
+
+		log.Printf("I! [agent] Starting input %s", input.LogName())
+		err := startServiceInput(input, a.inputC)
+		if err != nil {
+			failed++
+			continue
+		}
+
+		inputs = append(inputs, a.startInput(ctx, input))
+		runningInputs = append(runningInputs, input)
+	}
+	a.inputsMu.Lock()
+	a.inputs = inputs
+	a.inputsMu.Unlock()
+
+	for _, unit := range removed {
+		log.Printf("I! [agent] Stopping output %s", unit.output.LogName())
+		stopOutput(unit)
+		unit.output.Close()
+	}
+
+	a.Config.Inputs = runningInputs
+	a.Config.Outputs = runningOutputs
+	a.Config.Processors = processors
+	a.Config.Aggregators = aggregators
+
+	log.Printf("I! [agent] Reloaded config: inputs started:%d stopped:%d, "+
+		"outputs started:%d stopped:%d",
+		countUnmatched(inputMatches), len(removedInputs),
+		countUnmatched(outputMatches), len(removedOutputs))
+
+	if failed > 0 {
+		return fmt.Errorf("%d plugins failed to start", failed)
+	}
+	return nil
+}
+
+// matchHashes pairs each new plugin with a running plugin with the same
+// settings.  The returned matches hold the index of the running plugin for
+// each new plugin, or -1 if there is none; removed holds the indexes of the
+// running plugins without a match.
+func matchHashes(old, new []string) (matches []int, removed []int) {
+	available := make(map[string][]int)
+	for i, hash := range old {
+		available[hash] = append(available[hash], i)
+	}
+
+	matches = make([]int, len(new))
+	for i, hash := range new {
+		indexes := available[hash]
+		if len(indexes) == 0 {
+			matches[i] = -1
+			continue
+		}
+
+		matches[i] = indexes[0]
+		available[hash] = indexes[1:]
+	}
+
+	for i, hash := range old {
+		for _, j := range available[hash] {
+			if i == j {
+				removed = append(removed, i)
+			}
+		}
+	}
+	return matches, removed
+}
+
+func countUnmatched(matches []int) int {
+	var count int
+	for _, j := range matches {
+		if j < 0 {
+			count++
+		}
+	}
+	return count
+}
+
+func equalHashes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func processorHashes(processors models.RunningProcessors) []string {
+	hashes := make([]string, 0, len(processors))
+	for _, processor := range processors {
+		hashes = append(hashes, processor.Config.Hash)
+	}
+	return hashes
+}
+
+func aggregatorHashes(aggregators []*models.RunningAggregator) []string {
+	hashes := make([]string, 0, len(aggregators))
+	for _, aggregator := range aggregators {
+		hashes = append(hashes, aggregator.Config.Hash)
+	}
+	return hashes
+}
@@ -0,0 +1,215 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchHashes(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     []string
+		new     []string
+		matches []int
+		removed []int
+	}{
+		{
+			name:    "unchanged",
+			old:     []string{"a", "b"},
+			new:     []string{"a", "b"},
+			matches: []int{0, 1},
+		},
+		{
+			name:    "reordered",
+			old:     []string{"a", "b"},
+			new:     []string{"b", "a"},
+			matches: []int{1, 0},
+		},
+		{
+			name:    "changed",
+			old:     []string{"a", "b"},
+			new:     []string{"a", "c"},
+			matches: []int{0, -1},
+			removed: []int{1},
+		},
+		{
+			name:    "duplicate settings",
+			old:     []string{"a", "a", "b"},
+			new:     []string{"a", "b"},
+			matches: []int{0, 2},
+			removed: []int{1},
+		},
+		{
+			name:    "added",
+			old:     []string{},
+			new:     []string{"a", "a"},
+			matches: []int{-1, -1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, removed := matchHashes(tt.old, tt.new)
+			require.Equal(t, tt.matches, matches)
+			require.Equal(t, tt.removed, removed)
+		})
+	}
+}
+
+type countingInput struct {
+	mu      sync.Mutex
+	gathers int
+}
+
+func (i *countingInput) SampleConfig() string { return "" }
+func (i *countingInput) Description() string  { return "" }
+func (i *countingInput) Gather(acc telegraf.Accumulator) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.gathers++
+	acc.AddFields("test", map[string]interface{}{"value": 42}, nil)
+	return nil
+}
+
+func (i *countingInput) count() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.gathers
+}
+
+type nullOutput struct {
+	closed bool
+}
+
+func (o *nullOutput) SampleConfig() string                  { return "" }
+func (o *nullOutput) Description() string                   { return "" }
+func (o *nullOutput) Connect() error                        { return nil }
+func (o *nullOutput) Close() error                          { o.closed = true; return nil }
+func (o *nullOutput) Write(metrics []telegraf.Metric) error { return nil }
+
+func newReloadConfig(inputs []string, outputs []string) *config.Config {
+	c := config.NewConfig()
+	c.Agent.Interval.Duration = 10 * time.Millisecond
+	c.Agent.FlushInterval.Duration = time.Hour
+	c.Agent.RoundInterval = false
+	for _, hash := range inputs {
+		input := models.NewRunningInput(&countingInput{},
+			&models.InputConfig{Name: "counting", Hash: hash})
+		c.Inputs = append(c.Inputs, input)
+	}
+	for _, hash := range outputs {
+		output := models.NewRunningOutput("null", &nullOutput{},
+			&models.OutputConfig{Name: "null", Hash: hash}, 0, 0)
+		c.Outputs = append(c.Outputs, output)
+	}
+	return c
+}
+
+func TestAgent_Reload(t *testing.T) {
+	c := newReloadConfig([]string{"a", "b"}, []string{"x", "y"})
+	inputA := c.Inputs[0]
+	inputB := c.Inputs[1]
+	outputX := c.Outputs[0]
+	outputY := c.Outputs[1]
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- a.Run(ctx)
+	}()
+
+	reload := newReloadConfig([]string{"a", "c"}, []string{"x", "z"})
+	inputC := reload.Inputs[1]
+	err = a.Reload(reload)
+	require.NoError(t, err)
+
+	require.Equal(t, []*models.RunningInput{inputA, inputC}, a.Config.Inputs)
+	require.Len(t, a.Config.Outputs, 2)
+	require.Equal(t, outputX, a.Config.Outputs[0])
+	require.True(t, outputY.Output.(*nullOutput).closed)
+	require.False(t, outputX.Output.(*nullOutput).closed)
+
+	stopped := inputB.Input.(*countingInput).count()
+	for inputC.Input.(*countingInput).count() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	require.Equal(t, stopped, inputB.Input.(*countingInput).count())
+
+	cancel()
+	require.NoError(t, <-done)
+	require.True(t, outputX.Output.(*nullOutput).closed)
+}
+
+// afterOutput fails to connect unless the output it replaces is closed.
+type afterOutput struct {
+	nullOutput
+	replaced *nullOutput
+}
+
+func (o *afterOutput) Connect() error {
+	if !o.replaced.closed {
+		return errors.New("replaced output is still running")
+	}
+	return nil
+}
+
+func TestAgent_ReloadBufferDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := newReloadConfig([]string{"a"}, nil)
+	old := &nullOutput{}
+	c.Outputs = append(c.Outputs, models.NewRunningOutput("null", old,
+		&models.OutputConfig{Name: "null", Hash: "x", BufferDirectory: dir}, 0, 0))
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- a.Run(ctx)
+	}()
+
+	reload := newReloadConfig([]string{"a"}, nil)
+	output := models.NewRunningOutput("null", &afterOutput{replaced: old},
+		&models.OutputConfig{Name: "null", Hash: "y", BufferDirectory: dir + "/"}, 0, 0)
+	reload.Outputs = append(reload.Outputs, output)
+	require.NoError(t, a.Reload(reload))
+	require.Equal(t, []*models.RunningOutput{output}, a.Config.Outputs)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestAgent_ReloadAgentSettings(t *testing.T) {
+	c := newReloadConfig([]string{"a"}, []string{"x"})
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- a.Run(ctx)
+	}()
+
+	reload := newReloadConfig([]string{"a"}, []string{"x"})
+	reload.Agent.Interval.Duration = time.Second
+	require.Equal(t, ErrRestartRequired, a.Reload(reload))
+
+	cancel()
+	require.NoError(t, <-done)
+}
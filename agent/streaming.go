@@ -0,0 +1,145 @@
+package agent
+
+import (
+	"log"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// stream forwards the metrics emitted by a streaming processor through the
+// processors that follow it.
+type stream struct {
+	processor *models.RunningProcessor
+	metrics   chan telegraf.Metric
+	done      chan struct{}
+}
+
+// processorMetricMaker is the MetricMaker for the accumulator of a
+// streaming processor, emitted metrics are passed on with the route of the
+// processor.
+type processorMetricMaker struct {
+	name    string
+	logName string
+	route   string
+}
+
+func (m processorMetricMaker) Name() string {
+	return m.name
+}
+
+func (m processorMetricMaker) LogName() string {
+	return m.logName
+}
+
+func (m processorMetricMaker) MakeMetric(metric telegraf.Metric) telegraf.Metric {
+	if m.route != "" {
+		metric.AddTag(models.RouteTag, m.route)
+	}
+	return metric
+}
+
+// startStreams starts the streaming processors.  The metrics emitted by a
+// streaming processor are applied to the processors that follow it and sent
+// to dst.
+//
+// Processors that fail to start are removed, the returned processors should
+// be applied to the metrics.
+func (a *Agent) startStreams(
+	processors models.RunningProcessors,
+	dst chan<- telegraf.Metric,
+) (models.RunningProcessors, []*stream) {
+	// The processors are started in reverse, so that each stream only
+	// forwards to processors that are already running.
+	running := make(models.RunningProcessors, 0, len(processors))
+	var streams []*stream
+	for i := len(processors) - 1; i >= 0; i-- {
+		processor := processors[i]
+		sp, ok := processor.Processor.(telegraf.StreamingProcessor)
+		if !ok {
+			running = append(models.RunningProcessors{processor}, running...)
+			continue
+		}
+
+		s := &stream{
+			processor: processor,
+			metrics:   make(chan telegraf.Metric, 100),
+			done:      make(chan struct{}),
+		}
+
+		acc := NewAccumulator(processorMetricMaker{
+			name:    processor.Name,
+			logName: processor.LogName(),
+			route:   processor.Config.Route,
+		}, s.metrics)
+		acc.SetPrecision(a.Precision())
+
+		err := sp.Start(acc)
+		if err != nil {
+			log.Printf("E! [agent] Failed to start processor %s: %v",
+				processor.LogName(), err)
+			continue
+		}
+
+		go func(following models.RunningProcessors) {
+			defer close(s.done)
+			for metric := range s.metrics {
+				for _, metric := range applyProcessors(following, metric) {
+					dst <- metric
+				}
+			}
+		}(running)
+
+		running = append(models.RunningProcessors{processor}, running...)
+		streams = append([]*stream{s}, streams...)
+	}
+
+	return running, streams
+}
+
+// stopStreams stops the streaming processors in order, forwarding the metrics
+// emitted while stopping before the following processors are stopped.
+func stopStreams(streams []*stream) {
+	for _, s := range streams {
+		s.processor.Processor.(telegraf.StreamingProcessor).Stop()
+		close(s.metrics)
+		<-s.done
+	}
+}
+
+// forwardStreams sends the metrics emitted by the streaming processors to
+// the aggregators until inputDone is closed.  The aggregator channel is then
+// closed and the metrics emitted after that, while the aggregators are
+// pushed one final time and the processors are stopped, are sent to the
+// outputs until src is closed.
+func forwardStreams(
+	src <-chan telegraf.Metric,
+	agg chan<- telegraf.Metric,
+	dst chan<- telegraf.Metric,
+	inputDone <-chan struct{},
+) {
+	for {
+		select {
+		case metric := <-src:
+			agg <- metric
+		case <-inputDone:
+			close(agg)
+			for metric := range src {
+				dst <- metric
+			}
+			return
+		}
+	}
+}
+
+// withoutStreaming returns the processors that are not streaming
+// processors.
+func withoutStreaming(processors models.RunningProcessors) models.RunningProcessors {
+	result := make(models.RunningProcessors, 0, len(processors))
+	for _, processor := range processors {
+		if _, ok := processor.Processor.(telegraf.StreamingProcessor); !ok {
+			result = append(result, processor)
+		}
+	}
+	return result
+}
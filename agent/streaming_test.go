@@ -0,0 +1,196 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// echoProcessor emits each applied metric through its accumulator.
+type echoProcessor struct {
+	acc      telegraf.Accumulator
+	startErr error
+	stopped  bool
+}
+
+func (p *echoProcessor) SampleConfig() string { return "" }
+func (p *echoProcessor) Description() string  { return "" }
+
+func (p *echoProcessor) Start(acc telegraf.Accumulator) error {
+	p.acc = acc
+	return p.startErr
+}
+
+func (p *echoProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		p.acc.AddMetric(m)
+	}
+	return nil
+}
+
+func (p *echoProcessor) Stop() {
+	p.stopped = true
+}
+
+// tagProcessor adds a tag to each metric.
+type tagProcessor struct{}
+
+func (p *tagProcessor) SampleConfig() string { return "" }
+func (p *tagProcessor) Description() string  { return "" }
+
+func (p *tagProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		m.AddTag("processed", "true")
+	}
+	return in
+}
+
+// countAggregator counts the metrics added in each period.
+type countAggregator struct {
+	count int64
+}
+
+func (c *countAggregator) SampleConfig() string { return "" }
+func (c *countAggregator) Description() string  { return "" }
+
+func (c *countAggregator) Add(in telegraf.Metric) {
+	c.count++
+}
+
+func (c *countAggregator) Push(acc telegraf.Accumulator) {
+	acc.AddFields("count", map[string]interface{}{"value": c.count}, nil, time.Unix(0, 0))
+}
+
+func (c *countAggregator) Reset() {
+	c.count = 0
+}
+
+func newTestProcessor(name string, p telegraf.Processor) *models.RunningProcessor {
+	return &models.RunningProcessor{
+		Name:      name,
+		Processor: p,
+		Config:    &models.ProcessorConfig{Name: name},
+	}
+}
+
+func TestStreams(t *testing.T) {
+	a, _ := NewAgent(config.NewConfig())
+
+	echo := &echoProcessor{}
+	processors := models.RunningProcessors{
+		newTestProcessor("echo", echo),
+		newTestProcessor("tag", &tagProcessor{}),
+	}
+
+	dst := make(chan telegraf.Metric, 10)
+	running, streams := a.startStreams(processors, dst)
+	require.Len(t, running, 2)
+	require.Len(t, streams, 1)
+
+	m := testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{"value": 42.0},
+		time.Unix(0, 0),
+	)
+	require.Empty(t, applyProcessors(running, m))
+
+	stopStreams(streams)
+	require.True(t, echo.stopped)
+	close(dst)
+
+	var actual []telegraf.Metric
+	for m := range dst {
+		actual = append(actual, m)
+	}
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"processed": "true"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestStreams_Aggregated(t *testing.T) {
+	a, _ := NewAgent(config.NewConfig())
+	a.inputC = make(chan telegraf.Metric, 10)
+	a.outputC = make(chan telegraf.Metric, 10)
+	now := time.Now().Truncate(time.Second)
+	a.startTime = now
+
+	echo := &echoProcessor{}
+	processors := models.RunningProcessors{
+		newTestProcessor("echo", echo),
+		newTestProcessor("tag", &tagProcessor{}),
+	}
+	aggregators := []*models.RunningAggregator{
+		models.NewRunningAggregator(&countAggregator{}, &models.AggregatorConfig{
+			Name:   "count",
+			Period: time.Hour,
+		}),
+	}
+
+	unit := a.startProcessing(processors, aggregators)
+
+	a.inputC <- testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{"value": 42.0},
+		now,
+	)
+	actual := []telegraf.Metric{<-a.outputC}
+
+	// The final push of the aggregator goes through the streaming processor.
+	stopProcessing(unit)
+	require.True(t, echo.stopped)
+	close(a.outputC)
+	for m := range a.outputC {
+		actual = append(actual, m)
+	}
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"processed": "true"},
+			map[string]interface{}{"value": 42.0},
+			now,
+		),
+		testutil.MustMetric("count",
+			map[string]string{"processed": "true"},
+			map[string]interface{}{"value": int64(1)},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestStreams_StartError(t *testing.T) {
+	a, _ := NewAgent(config.NewConfig())
+
+	processors := models.RunningProcessors{
+		newTestProcessor("echo", &echoProcessor{startErr: errors.New("start failed")}),
+		newTestProcessor("tag", &tagProcessor{}),
+	}
+
+	running, streams := a.startStreams(processors, make(chan telegraf.Metric))
+	require.Len(t, running, 1)
+	require.Equal(t, "tag", running[0].Name)
+	require.Empty(t, streams)
+}
+
+func TestWithoutStreaming(t *testing.T) {
+	processors := models.RunningProcessors{
+		newTestProcessor("echo", &echoProcessor{}),
+		newTestProcessor("tag", &tagProcessor{}),
+	}
+
+	result := withoutStreaming(processors)
+	require.Len(t, result, 1)
+	require.Equal(t, "tag", result[0].Name)
+}
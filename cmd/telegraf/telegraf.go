@@ -13,6 +13,7 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/influxdata/telegraf/agent"
 	"github.com/influxdata/telegraf/internal"
@@ -24,7 +25,9 @@ import (
 	"github.com/influxdata/telegraf/plugins/outputs"
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
 	_ "github.com/influxdata/telegraf/plugins/processors/all"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/all"
 	"github.com/kardianos/service"
+	"gopkg.in/fsnotify.v1"
 )
 
 var fDebug = flag.Bool("debug", false,
@@ -34,6 +37,8 @@ var pprofAddr = flag.String("pprof-addr", "",
 var fQuiet = flag.Bool("quiet", false,
 	"run in quiet mode")
 var fTest = flag.Bool("test", false, "gather metrics, print them out, and exit")
+var fTestRoutes = flag.Bool("test-routes", false,
+	"gather metrics, apply processors, print each metric with the outputs it is routed to, and exit")
 var fConfig = flag.String("config", "", "configuration file to load")
 var fConfigDirectory = flag.String("config-directory", "",
 	"directory containing additional *.conf files")
@@ -62,6 +67,11 @@ var fService = flag.String("service", "",
 var fServiceName = flag.String("service-name", "telegraf", "service name (windows only)")
 var fServiceDisplayName = flag.String("service-display-name", "Telegraf Data Collector Service", "service display name (windows only)")
 var fRunAsConsole = flag.Bool("console", false, "run as console application (windows only)")
+var fWatchConfig = flag.Bool("watch-config", false,
+	"reload the configuration when files in --config-directory change")
+
+// Time to wait for further changes before reloading a watched config.
+const watchSettleTime = time.Second
 
 var (
 	version string
@@ -85,74 +95,112 @@ func reloadLoop(
 
 		ctx, cancel := context.WithCancel(context.Background())
 
+		// Requests to reload the config of the running agent.
+		configChanged := make(chan struct{}, 1)
+
 		signals := make(chan os.Signal)
 		signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
 			syscall.SIGTERM, syscall.SIGINT)
 		go func() {
-			select {
-			case sig := <-signals:
-				if sig == syscall.SIGHUP {
-					log.Printf("I! Reloading Telegraf config")
-					<-reload
-					reload <- true
+			for {
+				select {
+				case sig := <-signals:
+					if sig == syscall.SIGHUP {
+						log.Printf("I! Reloading Telegraf config")
+						select {
+						case configChanged <- struct{}{}:
+						default:
+						}
+						continue
+					}
+					cancel()
+				case <-stop:
+					cancel()
+				case <-ctx.Done():
 				}
-				cancel()
-			case <-stop:
-				cancel()
+				return
 			}
 		}()
 
-		err := runAgent(ctx, inputFilters, outputFilters)
+		if *fWatchConfig && *fConfigDirectory != "" {
+			go watchConfigDirectory(ctx, *fConfigDirectory, configChanged)
+		}
+
+		restart, err := runAgent(ctx, configChanged, inputFilters, outputFilters)
 		if err != nil {
 			log.Fatalf("E! [telegraf] Error running agent: %v", err)
 		}
+		signal.Stop(signals)
+		cancel()
+
+		if restart {
+			<-reload
+			reload <- true
+		}
 	}
 }
 
-func runAgent(ctx context.Context,
+// loadConfig loads the configuration from the config file and directory.
+func loadConfig(
 	inputFilters []string,
 	outputFilters []string,
-) error {
-	// Setup default logging. This may need to change after reading the config
-	// file, but we can configure it to use our logger implementation now.
-	logger.SetupLogging(logger.LogConfig{})
-	log.Printf("I! Starting Telegraf %s", version)
-
-	// If no other options are specified, load the config file and run.
+) (*config.Config, error) {
 	c := config.NewConfig()
 	c.OutputFilters = outputFilters
 	c.InputFilters = inputFilters
 	err := c.LoadConfig(*fConfig)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if *fConfigDirectory != "" {
 		err = c.LoadDirectory(*fConfigDirectory)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 	if !*fTest && len(c.Outputs) == 0 {
-		return errors.New("Error: no outputs found, did you provide a valid config file?")
+		return nil, errors.New("Error: no outputs found, did you provide a valid config file?")
 	}
 	if len(c.Inputs) == 0 {
-		return errors.New("Error: no inputs found, did you provide a valid config file?")
+		return nil, errors.New("Error: no inputs found, did you provide a valid config file?")
 	}
 
 	if int64(c.Agent.Interval.Duration) <= 0 {
-		return fmt.Errorf("Agent interval must be positive, found %s",
+		return nil, fmt.Errorf("Agent interval must be positive, found %s",
 			c.Agent.Interval.Duration)
 	}
 
 	if int64(c.Agent.FlushInterval.Duration) <= 0 {
-		return fmt.Errorf("Agent flush_interval must be positive; found %s",
+		return nil, fmt.Errorf("Agent flush_interval must be positive; found %s",
 			c.Agent.Interval.Duration)
 	}
 
+	return c, nil
+}
+
+// runAgent runs the agent until the context is done, applying the config
+// each time configChanged receives.  It returns true if the agent must be
+// restarted to apply the config.
+func runAgent(ctx context.Context,
+	configChanged <-chan struct{},
+	inputFilters []string,
+	outputFilters []string,
+) (bool, error) {
+	// Setup default logging. This may need to change after reading the config
+	// file, but we can configure it to use our logger implementation now.
+	logger.SetupLogging(logger.LogConfig{})
+	log.Printf("I! Starting Telegraf %s", version)
+
+	// If no other options are specified, load the config file and run.
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err != nil {
+		return false, err
+	}
+
 	ag, err := agent.NewAgent(c)
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	// Setup logging as configured.
@@ -163,12 +211,20 @@ func runAgent(ctx context.Context,
 		RotationInterval:    ag.Config.Agent.LogfileRotationInterval,
 		RotationMaxSize:     ag.Config.Agent.LogfileRotationMaxSize,
 		RotationMaxArchives: ag.Config.Agent.LogfileRotationMaxArchives,
+		Format:              ag.Config.Agent.LogFormat,
+		Target:              ag.Config.Agent.LogTarget,
+		SyslogAddress:       ag.Config.Agent.LogSyslogAddress,
 	}
 
 	logger.SetupLogging(logConfig)
+	logger.SetPluginLevels(c.PluginLogLevels())
 
 	if *fTest {
-		return ag.Test(ctx)
+		return false, ag.Test(ctx)
+	}
+
+	if *fTestRoutes {
+		return false, ag.TestRoutes(ctx)
 	}
 
 	log.Printf("I! Loaded inputs: %s", strings.Join(c.InputNames(), " "))
@@ -195,7 +251,106 @@ func runAgent(ctx context.Context,
 		}
 	}
 
-	return ag.Run(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Closed when the agent must be restarted to apply the config.
+	restart := make(chan struct{})
+	go func() {
+		// The config is reloaded periodically to refresh the secrets, only
+		// the plugins with changed secrets are restarted.
+		refreshInterval := c.SecretRefreshInterval
+		for {
+			var refresh <-chan time.Time
+			if refreshInterval > 0 {
+				refresh = time.After(refreshInterval)
+			}
+
+			select {
+			case <-configChanged:
+			case <-refresh:
+				log.Printf("D! [telegraf] Reloading config to refresh secrets")
+			case <-ctx.Done():
+				return
+			}
+
+			c, err := loadConfig(inputFilters, outputFilters)
+			if err != nil {
+				log.Printf("E! [telegraf] Error reloading config, keeping the running config: %v", err)
+				continue
+			}
+			refreshInterval = c.SecretRefreshInterval
+
+			err = ag.Reload(c)
+			if err == agent.ErrRestartRequired {
+				log.Printf("I! [telegraf] Restarting agent to apply config")
+				close(restart)
+				cancel()
+				return
+			}
+			if err != nil {
+				log.Printf("E! [telegraf] Error reloading config: %v", err)
+			}
+
+			// The config is applied even if some of the plugins failed to
+			// start, the levels must match the running plugins.
+			logger.SetPluginLevels(c.PluginLogLevels())
+		}
+	}()
+
+	err = ag.Run(ctx)
+	select {
+	case <-restart:
+		return true, err
+	default:
+		return false, err
+	}
+}
+
+// watchConfigDirectory requests a config reload when a *.conf file in the
+// directory is changed.  Events are collected for a short time so that a
+// burst of changes results in a single reload.
+func watchConfigDirectory(
+	ctx context.Context,
+	directory string,
+	configChanged chan<- struct{},
+) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("E! [telegraf] Unable to watch config directory: %v", err)
+		return
+	}
+	defer watcher.Close()
+
+	err = watcher.Add(directory)
+	if err != nil {
+		log.Printf("E! [telegraf] Unable to watch config directory: %v", err)
+		return
+	}
+
+	var pending <-chan time.Time
+	for {
+		select {
+		case event := <-watcher.Events:
+			if !strings.HasSuffix(event.Name, ".conf") {
+				continue
+			}
+			if pending == nil {
+				pending = time.After(watchSettleTime)
+			}
+		case err := <-watcher.Errors:
+			log.Printf("E! [telegraf] Error watching config directory: %v", err)
+		case <-pending:
+			pending = nil
+			log.Printf("I! [telegraf] Config directory changed, reloading Telegraf config")
+			select {
+			case configChanged <- struct{}{}:
+			default:
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
 }
 
 func usageExit(rc int) {
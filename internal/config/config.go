@@ -2,8 +2,11 @@ package config
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"math"
@@ -18,9 +21,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/alecthomas/units"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/logger"
 	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
@@ -66,6 +71,13 @@ type Config struct {
 	Aggregators []*models.RunningAggregator
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors models.RunningProcessors
+
+	// SecretStores are the stores referenced by plugin settings, by id.
+	SecretStores map[string]telegraf.SecretStore
+
+	// SecretRefreshInterval is the shortest refresh_interval of the secret
+	// stores, 0 if the secrets are never refreshed.
+	SecretRefreshInterval time.Duration
 }
 
 func NewConfig() *Config {
@@ -84,6 +96,7 @@ func NewConfig() *Config {
 		Processors:    make([]*models.RunningProcessor, 0),
 		InputFilters:  make([]string, 0),
 		OutputFilters: make([]string, 0),
+		SecretStores:  make(map[string]telegraf.SecretStore),
 	}
 	return c
 }
@@ -161,8 +174,64 @@ type AgentConfig struct {
 	// If set to -1, no archives are removed.
 	LogfileRotationMaxArchives int `toml:"logfile_rotation_max_archives"`
 
+	// LogFormat is the format of the log messages, "text" or "json".
+	LogFormat string `toml:"log_format"`
+
+	// LogTarget is the destination of the log messages, "file", "stderr" or
+	// "syslog".
+	LogTarget string `toml:"log_target"`
+
+	// LogSyslogAddress is the address of the syslog server when logging to
+	// syslog, the local syslog socket is used if empty.
+	LogSyslogAddress string `toml:"log_syslog_address"`
+
 	Hostname     string
 	OmitHostname bool
+
+	// HealthAddress is the address the HTTP health endpoint listens on, the
+	// endpoint is disabled when empty.
+	HealthAddress string `toml:"health_address"`
+
+	// HealthMaxBufferFill is the percentage of an output buffer that can be
+	// in use before the agent is reported as unhealthy, 0 disables the check.
+	HealthMaxBufferFill float64 `toml:"health_max_buffer_fill"`
+
+	// HealthMaxWriteFailures is the number of consecutive failed writes of
+	// an output before the agent is reported as unhealthy, 0 disables the
+	// check.
+	HealthMaxWriteFailures int `toml:"health_max_write_failures"`
+
+	// HealthMaxGatherFailures is the number of consecutive failed gathers of
+	// an input before the agent is reported as unhealthy, 0 disables the
+	// check.
+	HealthMaxGatherFailures int `toml:"health_max_gather_failures"`
+}
+
+// PluginLogLevels returns the log levels of the plugins that override the
+// agent log level, by the name the plugin uses in log messages.
+func (c *Config) PluginLogLevels() map[string]string {
+	levels := make(map[string]string)
+	for _, input := range c.Inputs {
+		if input.Config.LogLevel != "" {
+			levels[input.LogName()] = input.Config.LogLevel
+		}
+	}
+	for _, processor := range c.Processors {
+		if processor.Config.LogLevel != "" {
+			levels[processor.LogName()] = processor.Config.LogLevel
+		}
+	}
+	for _, aggregator := range c.Aggregators {
+		if aggregator.Config.LogLevel != "" {
+			levels[aggregator.LogName()] = aggregator.Config.LogLevel
+		}
+	}
+	for _, output := range c.Outputs {
+		if output.Config.LogLevel != "" {
+			levels[output.LogName()] = output.Config.LogLevel
+		}
+	}
+	return levels
 }
 
 // Inputs returns a list of strings of the configured inputs.
@@ -300,11 +369,33 @@ var agentConfig = `
   ## If set to -1, no archives are removed.
   # logfile_rotation_max_archives = 5
 
+  ## Format of the log messages, "text" or "json".
+  # log_format = "text"
+
+  ## Destination of the log messages: "file" writes to the logfile, or stderr
+  ## if no logfile is set, "stderr", or "syslog".
+  # log_target = "file"
+
+  ## Address of the syslog server when logging to syslog, such as
+  ## "udp://localhost:514" or "unixgram:///dev/log".  The local syslog socket
+  ## is used if empty.
+  # log_syslog_address = ""
+
   ## Override default hostname, if empty use os.Hostname()
   hostname = ""
   ## If set to true, do no set the "host" tag in the telegraf agent.
   omit_hostname = false
 
+  ## Address of the HTTP health endpoint, disabled when empty.
+  # health_address = "localhost:8080"
+
+  ## The health check fails when the buffer of an output is fuller than this
+  ## percentage, when an output has failed this many writes in a row, or when
+  ## an input has failed this many gathers in a row.  Set to 0 to disable.
+  # health_max_buffer_fill = 0.0
+  # health_max_write_failures = 0
+  # health_max_gather_failures = 0
+
 `
 
 var outputHeader = `
@@ -711,6 +802,27 @@ func (c *Config) LoadConfig(path string) error {
 		c.Tags["host"] = c.Agent.Hostname
 	}
 
+	// Parse secret stores before the plugins that reference them:
+	if val, ok := tbl.Fields["secretstores"]; ok {
+		subTable, ok := val.(*ast.Table)
+		if !ok {
+			return fmt.Errorf("%s: invalid configuration", path)
+		}
+		for storeName, storeVal := range subTable.Fields {
+			switch storeSubTable := storeVal.(type) {
+			case []*ast.Table:
+				for _, t := range storeSubTable {
+					if err = c.addSecretStore(storeName, t); err != nil {
+						return fmt.Errorf("Error parsing %s, %s", path, err)
+					}
+				}
+			default:
+				return fmt.Errorf("Unsupported config format: %s, file %s",
+					storeName, path)
+			}
+		}
+	}
+
 	// Parse all the rest of the plugins:
 	for name, val := range tbl.Fields {
 		subTable, ok := val.(*ast.Table)
@@ -719,7 +831,15 @@ func (c *Config) LoadConfig(path string) error {
 		}
 
 		switch name {
-		case "agent", "global_tags", "tags":
+		case "agent", "global_tags", "tags", "secretstores":
+			continue
+		}
+
+		if err = c.resolveSecrets(subTable); err != nil {
+			return fmt.Errorf("Error parsing %s, %s", path, err)
+		}
+
+		switch name {
 		case "outputs":
 			for pluginName, pluginVal := range subTable.Fields {
 				switch pluginSubTable := pluginVal.(type) {
@@ -883,17 +1003,73 @@ func parseConfig(contents []byte) (*ast.Table, error) {
 	return toml.Parse(contents)
 }
 
+// tableHash returns a digest of the settings in a plugin table.  It must be
+// computed before any of the table fields are consumed.
+func tableHash(name string, tbl *ast.Table) string {
+	h := sha256.New()
+	io.WriteString(h, name)
+	writeTable(h, tbl)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeTable writes the fields of a table in sorted order.
+func writeTable(w io.Writer, tbl *ast.Table) {
+	keys := make([]string, 0, len(tbl.Fields))
+	for key := range tbl.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fmt.Fprintf(w, "\x00%s=", key)
+		switch v := tbl.Fields[key].(type) {
+		case *ast.KeyValue:
+			writeValue(w, v.Value)
+		case *ast.Table:
+			io.WriteString(w, "{")
+			writeTable(w, v)
+			io.WriteString(w, "}")
+		case []*ast.Table:
+			for _, t := range v {
+				io.WriteString(w, "[{")
+				writeTable(w, t)
+				io.WriteString(w, "}]")
+			}
+		}
+	}
+}
+
+// writeValue writes a value, strings are written decoded so that changes to
+// resolved secrets are detected.
+func writeValue(w io.Writer, value ast.Value) {
+	switch v := value.(type) {
+	case *ast.String:
+		io.WriteString(w, strconv.Quote(v.Value))
+	case *ast.Array:
+		io.WriteString(w, "[")
+		for _, elem := range v.Value {
+			writeValue(w, elem)
+			io.WriteString(w, ",")
+		}
+		io.WriteString(w, "]")
+	default:
+		io.WriteString(w, value.Source())
+	}
+}
+
 func (c *Config) addAggregator(name string, table *ast.Table) error {
 	creator, ok := aggregators.Aggregators[name]
 	if !ok {
 		return fmt.Errorf("Undefined but requested aggregator: %s", name)
 	}
 	aggregator := creator()
+	hash := tableHash(name, table)
 
 	conf, err := buildAggregator(name, table)
 	if err != nil {
 		return err
 	}
+	conf.Hash = hash
 
 	if err := toml.UnmarshalTable(table, aggregator); err != nil {
 		return err
@@ -909,20 +1085,24 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested processor: %s", name)
 	}
 	processor := creator()
+	hash := tableHash(name, table)
 
 	processorConfig, err := buildProcessor(name, table)
 	if err != nil {
 		return err
 	}
+	processorConfig.Hash = hash
 
 	if err := toml.UnmarshalTable(table, processor); err != nil {
 		return err
 	}
 
-	rf := &models.RunningProcessor{
-		Name:      name,
-		Processor: processor,
-		Config:    processorConfig,
+	rf := models.NewRunningProcessor(name, processor, processorConfig)
+
+	if p, ok := processor.(telegraf.Initializer); ok {
+		if err := p.Init(); err != nil {
+			return fmt.Errorf("Error initializing processor %s: %v", rf.LogName(), err)
+		}
 	}
 
 	c.Processors = append(c.Processors, rf)
@@ -938,6 +1118,7 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested output: %s", name)
 	}
 	output := creator()
+	hash := tableHash(name, table)
 
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
@@ -954,6 +1135,7 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 	if err != nil {
 		return err
 	}
+	outputConfig.Hash = hash
 
 	if err := toml.UnmarshalTable(table, output); err != nil {
 		return err
@@ -979,6 +1161,7 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 		return fmt.Errorf("Undefined but requested input: %s", name)
 	}
 	input := creator()
+	hash := tableHash(name, table)
 
 	// If the input has a SetParser function, then this means it can accept
 	// arbitrary types of input, so build the parser and set it.
@@ -1006,6 +1189,7 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	if err != nil {
 		return err
 	}
+	pluginConfig.Hash = hash
 
 	if err := toml.UnmarshalTable(table, input); err != nil {
 		return err
@@ -1098,6 +1282,27 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 		}
 	}
 
+	if node, ok := tbl.Fields["log_level"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				if _, err := logger.ParseLevel(str.Value); err != nil {
+					return nil, err
+				}
+				conf.LogLevel = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				conf.Alias = str.Value
+			}
+		}
+	}
+
+	delete(tbl.Fields, "alias")
+	delete(tbl.Fields, "log_level")
 	delete(tbl.Fields, "period")
 	delete(tbl.Fields, "delay")
 	delete(tbl.Fields, "drop_original")
@@ -1131,7 +1336,37 @@ func buildProcessor(name string, tbl *ast.Table) (*models.ProcessorConfig, error
 		}
 	}
 
+	if node, ok := tbl.Fields["route"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				conf.Route = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["log_level"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				if _, err := logger.ParseLevel(str.Value); err != nil {
+					return nil, err
+				}
+				conf.LogLevel = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				conf.Alias = str.Value
+			}
+		}
+	}
+
+	delete(tbl.Fields, "alias")
+	delete(tbl.Fields, "log_level")
 	delete(tbl.Fields, "order")
+	delete(tbl.Fields, "route")
 	var err error
 	conf.Filter, err = buildFilter(tbl)
 	if err != nil {
@@ -1319,6 +1554,33 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["route"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				cp.Route = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["log_level"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				if _, err := logger.ParseLevel(str.Value); err != nil {
+					return nil, err
+				}
+				cp.LogLevel = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				cp.Alias = str.Value
+			}
+		}
+	}
+
 	cp.Tags = make(map[string]string)
 	if node, ok := tbl.Fields["tags"]; ok {
 		if subtbl, ok := node.(*ast.Table); ok {
@@ -1331,7 +1593,10 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 	delete(tbl.Fields, "name_prefix")
 	delete(tbl.Fields, "name_suffix")
 	delete(tbl.Fields, "name_override")
+	delete(tbl.Fields, "alias")
+	delete(tbl.Fields, "log_level")
 	delete(tbl.Fields, "interval")
+	delete(tbl.Fields, "route")
 	delete(tbl.Fields, "tags")
 	var err error
 	cp.Filter, err = buildFilter(tbl)
@@ -1726,6 +1991,136 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 		}
 	}
 
+	//for prometheus parser
+	if node, ok := tbl.Fields["prometheus_openmetrics"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				var err error
+				c.PrometheusOpenMetrics, err = b.Boolean()
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["prometheus_exemplars"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				var err error
+				c.PrometheusExemplars, err = b.Boolean()
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	//for xml parser
+	if node, ok := tbl.Fields["xml_metric_selection"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.XMLMetricSelection = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["xml_metric_name"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.XMLMetricName = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["xml_timestamp"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.XMLTimestamp = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["xml_timestamp_format"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.XMLTimestampFormat = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["xml_timezone"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.XMLTimezone = str.Value
+			}
+		}
+	}
+
+	c.XMLTags = getStringTable(tbl, "xml_tags")
+	c.XMLFields = getStringTable(tbl, "xml_fields")
+	c.XMLFieldTypes = getStringTable(tbl, "xml_field_types")
+
+	//for protobuf parser
+	if node, ok := tbl.Fields["protobuf_descriptor_set"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.ProtobufDescriptorSet = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_message_type"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.ProtobufMessageType = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_metric_selection"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.ProtobufMetricSelection = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_metric_name"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.ProtobufMetricName = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_timestamp"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.ProtobufTimestamp = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_timestamp_format"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.ProtobufTimestampFormat = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["protobuf_timezone"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.ProtobufTimezone = str.Value
+			}
+		}
+	}
+
+	c.ProtobufTags = getStringTable(tbl, "protobuf_tags")
+	c.ProtobufFields = getStringTable(tbl, "protobuf_fields")
+
 	c.MetricName = name
 
 	delete(tbl.Fields, "data_format")
@@ -1767,10 +2162,51 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 	delete(tbl.Fields, "csv_timestamp_column")
 	delete(tbl.Fields, "csv_timestamp_format")
 	delete(tbl.Fields, "csv_trim_space")
+	delete(tbl.Fields, "prometheus_openmetrics")
+	delete(tbl.Fields, "prometheus_exemplars")
+	delete(tbl.Fields, "xml_metric_selection")
+	delete(tbl.Fields, "xml_metric_name")
+	delete(tbl.Fields, "xml_timestamp")
+	delete(tbl.Fields, "xml_timestamp_format")
+	delete(tbl.Fields, "xml_timezone")
+	delete(tbl.Fields, "xml_tags")
+	delete(tbl.Fields, "xml_fields")
+	delete(tbl.Fields, "xml_field_types")
+	delete(tbl.Fields, "protobuf_descriptor_set")
+	delete(tbl.Fields, "protobuf_message_type")
+	delete(tbl.Fields, "protobuf_metric_selection")
+	delete(tbl.Fields, "protobuf_metric_name")
+	delete(tbl.Fields, "protobuf_timestamp")
+	delete(tbl.Fields, "protobuf_timestamp_format")
+	delete(tbl.Fields, "protobuf_timezone")
+	delete(tbl.Fields, "protobuf_tags")
+	delete(tbl.Fields, "protobuf_fields")
 
 	return c, nil
 }
 
+// getStringTable returns the string values of a sub-table by key.
+func getStringTable(tbl *ast.Table, name string) map[string]string {
+	node, ok := tbl.Fields[name]
+	if !ok {
+		return nil
+	}
+	subtbl, ok := node.(*ast.Table)
+	if !ok {
+		return nil
+	}
+
+	values := make(map[string]string, len(subtbl.Fields))
+	for key, val := range subtbl.Fields {
+		if kv, ok := val.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				values[key] = str.Value
+			}
+		}
+	}
+	return values
+}
+
 // buildSerializer grabs the necessary entries from the ast.Table for creating
 // a serializers.Serializer object, and creates it, which can then be added onto
 // an Output object.
@@ -1905,6 +2341,30 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 		}
 	}
 
+	if node, ok := tbl.Fields["prometheus_export_timestamp"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				var err error
+				c.PrometheusExportTimestamp, err = b.Boolean()
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["prometheus_string_as_label"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				var err error
+				c.PrometheusStringAsLabel, err = b.Boolean()
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
 	delete(tbl.Fields, "influx_max_line_bytes")
 	delete(tbl.Fields, "influx_sort_fields")
 	delete(tbl.Fields, "influx_uint_support")
@@ -1916,6 +2376,8 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 	delete(tbl.Fields, "splunkmetric_hec_routing")
 	delete(tbl.Fields, "wavefront_source_override")
 	delete(tbl.Fields, "wavefront_use_strict")
+	delete(tbl.Fields, "prometheus_export_timestamp")
+	delete(tbl.Fields, "prometheus_string_as_label")
 	return serializers.NewSerializer(c)
 }
 
@@ -1942,6 +2404,37 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 		oc.Filter.NamePass = oc.Filter.FieldPass
 	}
 
+	if node, ok := tbl.Fields["log_level"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				if _, err := logger.ParseLevel(str.Value); err != nil {
+					return nil, err
+				}
+				oc.LogLevel = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["alias"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.Alias = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["routes"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						oc.Routes = append(oc.Routes, str.Value)
+					}
+				}
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["flush_interval"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1979,9 +2472,149 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["buffer_directory"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.BufferDirectory = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_disk_limit"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			switch v := kv.Value.(type) {
+			case *ast.Integer:
+				size, err := v.Int()
+				if err != nil {
+					return nil, err
+				}
+				oc.BufferDiskLimit = size
+			case *ast.String:
+				size, err := units.ParseStrictBytes(v.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.BufferDiskLimit = size
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["buffer_fsync"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				switch str.Value {
+				case models.BufferFsyncAlways, models.BufferFsyncBatch, models.BufferFsyncNever:
+					oc.BufferFsync = str.Value
+				default:
+					return nil, fmt.Errorf("invalid buffer_fsync value %q", str.Value)
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_initial_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				oc.Retry.InitialInterval = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_max_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				oc.Retry.MaxInterval = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_jitter"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			var v float64
+			switch value := kv.Value.(type) {
+			case *ast.Float:
+				f, err := value.Float()
+				if err != nil {
+					return nil, err
+				}
+				v = f
+			case *ast.Integer:
+				i, err := value.Int()
+				if err != nil {
+					return nil, err
+				}
+				v = float64(i)
+			}
+			if v < 0 || v > 1 {
+				return nil, fmt.Errorf("retry_jitter must be between 0 and 1, got %v", v)
+			}
+			oc.Retry.Jitter = v
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_max_age"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				oc.Retry.MaxAge = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["circuit_breaker_failures"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				v, err := integer.Int()
+				if err != nil {
+					return nil, err
+				}
+				oc.Retry.CircuitBreakerFailures = int(v)
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["circuit_breaker_timeout"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+
+				oc.Retry.CircuitBreakerTimeout = dur
+			}
+		}
+	}
+
+	delete(tbl.Fields, "alias")
+	delete(tbl.Fields, "log_level")
+	delete(tbl.Fields, "routes")
 	delete(tbl.Fields, "flush_interval")
 	delete(tbl.Fields, "metric_buffer_limit")
 	delete(tbl.Fields, "metric_batch_size")
+	delete(tbl.Fields, "buffer_directory")
+	delete(tbl.Fields, "buffer_disk_limit")
+	delete(tbl.Fields, "buffer_fsync")
+	delete(tbl.Fields, "retry_initial_interval")
+	delete(tbl.Fields, "retry_max_interval")
+	delete(tbl.Fields, "retry_jitter")
+	delete(tbl.Fields, "retry_max_age")
+	delete(tbl.Fields, "circuit_breaker_failures")
+	delete(tbl.Fields, "circuit_breaker_timeout")
 
 	return oc, nil
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -14,6 +15,8 @@ import (
 	"github.com/influxdata/telegraf/plugins/inputs/procstat"
 	httpOut "github.com/influxdata/telegraf/plugins/outputs/http"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/file"
+	"github.com/influxdata/toml"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -251,3 +254,181 @@ func TestConfig_BadOrdering(t *testing.T) {
 	require.Error(t, err, "bad ordering")
 	assert.Equal(t, "Error parsing ./testdata/non_slice_slice.toml, line 4: cannot unmarshal TOML array into string (need slice)", err.Error())
 }
+
+func TestConfig_SecretStore(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/secret_store.toml")
+	require.NoError(t, err)
+
+	require.Contains(t, c.SecretStores, "files")
+	assert.Equal(t, time.Minute, c.SecretRefreshInterval)
+
+	input, ok := c.Inputs[0].Input.(*memcached.Memcached)
+	require.True(t, ok)
+	assert.Equal(t, []string{"memcached.example.org:11211", "localhost"}, input.Servers)
+
+	output, ok := c.Outputs[0].Output.(*httpOut.HTTP)
+	require.True(t, ok)
+	assert.Equal(t, "telegraf", output.Username)
+	assert.Equal(t, "hunter2", output.Password)
+}
+
+func TestConfig_SecretStoreUnknown(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/secret_store_unknown.toml")
+	require.Error(t, err)
+}
+
+type mockSecretStore map[string]string
+
+func (m mockSecretStore) SampleConfig() string { return "" }
+func (m mockSecretStore) Description() string  { return "" }
+
+func (m mockSecretStore) Get(key string) (string, error) {
+	if v, ok := m[key]; ok {
+		return v, nil
+	}
+	return "", errors.New("not found")
+}
+
+func TestConfig_SecretChangesHash(t *testing.T) {
+	hash := func(password string) string {
+		c := NewConfig()
+		c.SecretStores["mock"] = mockSecretStore{"password": password}
+
+		tbl, err := toml.Parse([]byte(`password = "@{mock:password}"`))
+		require.NoError(t, err)
+		require.NoError(t, c.resolveSecrets(tbl))
+		return tableHash("http", tbl)
+	}
+
+	assert.Equal(t, hash("hunter2"), hash("hunter2"))
+	assert.NotEqual(t, hash("hunter2"), hash("hunter3"))
+}
+
+func TestConfig_ResolveSecretErrors(t *testing.T) {
+	c := NewConfig()
+	c.SecretStores["mock"] = mockSecretStore{"password": "hunter2"}
+
+	resolved, err := c.resolveString("user:@{mock:password}@localhost")
+	require.NoError(t, err)
+	assert.Equal(t, "user:hunter2@localhost", resolved)
+
+	_, err = c.resolveString("@{mock:missing}")
+	require.Error(t, err)
+
+	_, err = c.resolveString("@{other:password}")
+	require.Error(t, err)
+}
+
+func TestConfig_LogLevel(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/log_level.toml"))
+	require.Equal(t, map[string]string{
+		"inputs.memcached::cache": "debug",
+		"outputs.http":            "error",
+	}, c.PluginLogLevels())
+
+	c = NewConfig()
+	require.Error(t, c.LoadConfig("./testdata/log_level_invalid.toml"))
+}
+
+func TestConfig_OutputRetry(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/retry.toml"))
+	require.Len(t, c.Outputs, 1)
+	require.Equal(t, models.RetryConfig{
+		InitialInterval:        time.Second,
+		MaxInterval:            5 * time.Minute,
+		Jitter:                 0.2,
+		MaxAge:                 time.Hour,
+		CircuitBreakerFailures: 10,
+		CircuitBreakerTimeout:  2 * time.Minute,
+	}, c.Outputs[0].Config.Retry)
+}
+
+func TestConfig_OutputRetryIntegerJitter(t *testing.T) {
+	tbl, err := parseConfig([]byte(`
+retry_jitter = 1
+`))
+	require.NoError(t, err)
+
+	oc, err := buildOutput("http", tbl)
+	require.NoError(t, err)
+	require.Equal(t, 1.0, oc.Retry.Jitter)
+
+	tbl, err = parseConfig([]byte(`
+retry_jitter = 2
+`))
+	require.NoError(t, err)
+
+	_, err = buildOutput("http", tbl)
+	require.Error(t, err)
+}
+
+func TestConfig_XMLParser(t *testing.T) {
+	tbl, err := parseConfig([]byte(`
+data_format = "xml"
+xml_metric_selection = "//sensor"
+xml_metric_name = "@type"
+xml_timestamp = "@time"
+xml_timestamp_format = "unix"
+xml_timezone = "Local"
+[xml_tags]
+  id = "@id"
+[xml_fields]
+  value = "value"
+  ok = "ok"
+[xml_field_types]
+  value = "float"
+`))
+	require.NoError(t, err)
+
+	c, err := getParserConfig("exec", tbl)
+	require.NoError(t, err)
+	require.Equal(t, "xml", c.DataFormat)
+	require.Equal(t, "//sensor", c.XMLMetricSelection)
+	require.Equal(t, "@type", c.XMLMetricName)
+	require.Equal(t, "@time", c.XMLTimestamp)
+	require.Equal(t, "unix", c.XMLTimestampFormat)
+	require.Equal(t, "Local", c.XMLTimezone)
+	require.Equal(t, map[string]string{"id": "@id"}, c.XMLTags)
+	require.Equal(t, map[string]string{"value": "value", "ok": "ok"}, c.XMLFields)
+	require.Equal(t, map[string]string{"value": "float"}, c.XMLFieldTypes)
+	require.Empty(t, tbl.Fields)
+
+	_, err = parsers.NewParser(c)
+	require.NoError(t, err)
+}
+
+func TestConfig_ProtobufParser(t *testing.T) {
+	tbl, err := parseConfig([]byte(`
+data_format = "protobuf"
+protobuf_descriptor_set = "/etc/telegraf/device.pb"
+protobuf_message_type = "device.Batch"
+protobuf_metric_selection = "readings"
+protobuf_metric_name = "sensor"
+protobuf_timestamp = "/time"
+protobuf_timestamp_format = "unix_ms"
+protobuf_timezone = "Local"
+[protobuf_tags]
+  device = "/device"
+[protobuf_fields]
+  value = "value"
+`))
+	require.NoError(t, err)
+
+	c, err := getParserConfig("mqtt_consumer", tbl)
+	require.NoError(t, err)
+	require.Equal(t, "protobuf", c.DataFormat)
+	require.Equal(t, "/etc/telegraf/device.pb", c.ProtobufDescriptorSet)
+	require.Equal(t, "device.Batch", c.ProtobufMessageType)
+	require.Equal(t, "readings", c.ProtobufMetricSelection)
+	require.Equal(t, "sensor", c.ProtobufMetricName)
+	require.Equal(t, "/time", c.ProtobufTimestamp)
+	require.Equal(t, "unix_ms", c.ProtobufTimestampFormat)
+	require.Equal(t, "Local", c.ProtobufTimezone)
+	require.Equal(t, map[string]string{"device": "/device"}, c.ProtobufTags)
+	require.Equal(t, map[string]string{"value": "value"}, c.ProtobufFields)
+	require.Empty(t, tbl.Fields)
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/influxdata/telegraf/plugins/secretstores"
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
+)
+
+var (
+	// secretRe matches references to secrets in string values, the
+	// reference "@{id:key}" is replaced by the secret with the key from the
+	// store with the id.
+	secretRe = regexp.MustCompile(`@\{(\w+):([^}]+)\}`)
+
+	// secretStoreIDRe matches the valid ids of a secret store.
+	secretStoreIDRe = regexp.MustCompile(`^\w+$`)
+)
+
+func (c *Config) addSecretStore(name string, table *ast.Table) error {
+	creator, ok := secretstores.SecretStores[name]
+	if !ok {
+		return fmt.Errorf("Undefined but requested secretstore: %s", name)
+	}
+	store := creator()
+
+	id, refreshInterval, err := buildSecretStore(name, table)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := c.SecretStores[id]; ok {
+		return fmt.Errorf("Duplicate secretstore id: %s", id)
+	}
+
+	if err := toml.UnmarshalTable(table, store); err != nil {
+		return err
+	}
+
+	if refreshInterval > 0 &&
+		(c.SecretRefreshInterval == 0 || refreshInterval < c.SecretRefreshInterval) {
+		c.SecretRefreshInterval = refreshInterval
+	}
+
+	c.SecretStores[id] = store
+	return nil
+}
+
+// buildSecretStore parses the settings common to all secret stores and
+// removes them from the table.
+func buildSecretStore(name string, tbl *ast.Table) (string, time.Duration, error) {
+	var id string
+	if node, ok := tbl.Fields["id"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				id = str.Value
+			}
+		}
+	}
+	if !secretStoreIDRe.MatchString(id) {
+		return "", 0, fmt.Errorf("secretstore %s: id must be set to letters, digits or underscores", name)
+	}
+
+	var refreshInterval time.Duration
+	if node, ok := tbl.Fields["refresh_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return "", 0, err
+				}
+
+				refreshInterval = dur
+			}
+		}
+	}
+
+	delete(tbl.Fields, "id")
+	delete(tbl.Fields, "refresh_interval")
+	return id, refreshInterval, nil
+}
+
+// resolveSecrets replaces the secret references in the string values of the
+// table and its subtables.
+func (c *Config) resolveSecrets(tbl *ast.Table) error {
+	for _, field := range tbl.Fields {
+		switch v := field.(type) {
+		case *ast.KeyValue:
+			if err := c.resolveValue(v.Value); err != nil {
+				return fmt.Errorf("line %d: %v", v.Line, err)
+			}
+		case *ast.Table:
+			if err := c.resolveSecrets(v); err != nil {
+				return err
+			}
+		case []*ast.Table:
+			for _, t := range v {
+				if err := c.resolveSecrets(t); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Config) resolveValue(value ast.Value) error {
+	switch v := value.(type) {
+	case *ast.String:
+		resolved, err := c.resolveString(v.Value)
+		if err != nil {
+			return err
+		}
+		v.Value = resolved
+	case *ast.Array:
+		for _, elem := range v.Value {
+			if err := c.resolveValue(elem); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveString replaces each secret reference in s with the secret.
+func (c *Config) resolveString(s string) (string, error) {
+	var err error
+	resolved := secretRe.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+
+		match := secretRe.FindStringSubmatch(ref)
+		id, key := match[1], match[2]
+
+		store, ok := c.SecretStores[id]
+		if !ok {
+			err = fmt.Errorf("unknown secretstore %q", id)
+			return ref
+		}
+
+		var secret string
+		secret, err = store.Get(key)
+		if err != nil {
+			err = fmt.Errorf("could not get secret %q from secretstore %q: %v", key, id, err)
+			return ref
+		}
+		return secret
+	})
+	if err != nil {
+		return "", err
+	}
+	return resolved, nil
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"sync"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/selfstat"
@@ -12,6 +13,41 @@ var (
 	AgentMetricsDropped = selfstat.Register("agent", "metrics_dropped", map[string]string{})
 )
 
+// MetricBuffer holds the metrics of an output until they have been written.
+type MetricBuffer interface {
+	// Len returns the number of metrics currently in the buffer.
+	Len() int
+
+	// Fill returns the fraction of the buffer capacity in use, between 0
+	// and 1.  An unlimited buffer is never full.
+	Fill() float64
+
+	// Add adds metrics to the buffer.
+	Add(metrics ...telegraf.Metric)
+
+	// Batch returns up to batchSize metrics from the buffer.  Only a single
+	// batch may be outstanding at any time.
+	Batch(batchSize int) []telegraf.Metric
+
+	// Accept marks the batch, acquired from Batch(), as successfully written.
+	Accept(batch []telegraf.Metric)
+
+	// Reject returns the batch, acquired from Batch(), to the buffer.
+	Reject(batch []telegraf.Metric)
+
+	// Drop removes the batch, acquired from Batch(), from the buffer without
+	// writing it.
+	Drop(batch []telegraf.Metric)
+
+	// Expire removes the metrics with a time before the cutoff and returns
+	// the number of metrics removed.  It must not be called while a batch is
+	// outstanding.
+	Expire(cutoff time.Time) int
+
+	// Close releases any resources held by the buffer.
+	Close() error
+}
+
 // Buffer stores metrics in a circular buffer.
 type Buffer struct {
 	sync.Mutex
@@ -32,7 +68,8 @@ type Buffer struct {
 }
 
 // NewBuffer returns a new empty Buffer with the given capacity.
-func NewBuffer(name string, capacity int) *Buffer {
+func NewBuffer(name string, alias string, capacity int) *Buffer {
+	tags := statTags("output", name, alias)
 	b := &Buffer{
 		buf:   make([]telegraf.Metric, capacity),
 		first: 0,
@@ -43,34 +80,40 @@ func NewBuffer(name string, capacity int) *Buffer {
 		MetricsAdded: selfstat.Register(
 			"write",
 			"metrics_added",
-			map[string]string{"output": name},
+			tags,
 		),
 		MetricsWritten: selfstat.Register(
 			"write",
 			"metrics_written",
-			map[string]string{"output": name},
+			tags,
 		),
 		MetricsDropped: selfstat.Register(
 			"write",
 			"metrics_dropped",
-			map[string]string{"output": name},
+			tags,
 		),
 		BufferSize: selfstat.Register(
 			"write",
 			"buffer_size",
-			map[string]string{"output": name},
+			tags,
 		),
 		BufferLimit: selfstat.Register(
 			"write",
 			"buffer_limit",
-			map[string]string{"output": name},
+			tags,
 		),
 	}
-	b.BufferSize.Set(int64(0))
-	b.BufferLimit.Set(int64(capacity))
 	return b
 }
 
+// setStats sets the size and limit stats of the buffer.  They are shared by
+// the buffers of the outputs with the same name and alias, so they are only
+// set when the output is started.
+func (b *Buffer) setStats() {
+	b.BufferSize.Set(int64(b.Len()))
+	b.BufferLimit.Set(int64(b.cap))
+}
+
 // Len returns the number of metrics currently in the buffer.
 func (b *Buffer) Len() int {
 	b.Lock()
@@ -79,6 +122,13 @@ func (b *Buffer) Len() int {
 	return b.length()
 }
 
+func (b *Buffer) Fill() float64 {
+	b.Lock()
+	defer b.Unlock()
+
+	return float64(b.length()) / float64(b.cap)
+}
+
 func (b *Buffer) length() int {
 	return min(b.size+b.batchSize, b.cap)
 }
@@ -176,6 +226,51 @@ func (b *Buffer) Accept(batch []telegraf.Metric) {
 	b.BufferSize.Set(int64(b.length()))
 }
 
+// Drop removes the batch, acquired from Batch(), from the buffer without
+// writing it.
+func (b *Buffer) Drop(batch []telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, m := range batch {
+		b.metricDropped(m)
+	}
+
+	b.resetBatch()
+	b.BufferSize.Set(int64(b.length()))
+}
+
+// Expire removes the metrics older than the cutoff, the remaining metrics
+// keep their order.
+func (b *Buffer) Expire(cutoff time.Time) int {
+	b.Lock()
+	defer b.Unlock()
+
+	var expired int
+	r, w := b.first, b.first
+	for i := 0; i < b.size; i++ {
+		m := b.buf[r]
+		if m.Time().Before(cutoff) {
+			b.metricDropped(m)
+			expired++
+		} else {
+			b.buf[w] = m
+			w = b.next(w)
+		}
+		r = b.next(r)
+	}
+
+	b.last = w
+	for i := 0; i < expired; i++ {
+		b.buf[w] = nil
+		w = b.next(w)
+	}
+	b.size -= expired
+
+	b.BufferSize.Set(int64(b.length()))
+	return expired
+}
+
 // Reject returns the batch, acquired from Batch(), to the buffer and marks it
 // as unsent.
 func (b *Buffer) Reject(batch []telegraf.Metric) {
@@ -225,6 +320,11 @@ func (b *Buffer) Reject(batch []telegraf.Metric) {
 	b.BufferSize.Set(int64(b.length()))
 }
 
+// Close is a no-op for the in-memory buffer; any remaining metrics are lost.
+func (b *Buffer) Close() error {
+	return nil
+}
+
 // dist returns the distance between two indexes.  Because this data structure
 // uses a half open range the arguments must both either left side or right
 // side pairs.
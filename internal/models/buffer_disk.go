@@ -0,0 +1,813 @@
+package models
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	serializer "github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const (
+	// Fsync policies for the disk buffer.
+	BufferFsyncAlways = "always"
+	BufferFsyncBatch  = "batch"
+	BufferFsyncNever  = "never"
+
+	// Default maximum size of a single segment file.
+	defaultSegmentSize = 8 * 1024 * 1024
+	minSegmentSize     = 64 * 1024
+
+	segmentExt     = ".wal"
+	checkpointFile = "checkpoint"
+
+	// record header: payload length and crc32 checksum of the payload
+	recordHeaderSize = 8
+	maxRecordSize    = 64 * 1024 * 1024
+)
+
+var errCorruptRecord = errors.New("corrupt record")
+
+// segment is a single append-only file of the write-ahead log.  Records in a
+// segment are numbered consecutively starting at start.
+type segment struct {
+	path  string
+	start uint64 // sequence number of the first record
+	count uint64 // number of records in the segment
+	size  int64  // size of the file in bytes
+}
+
+func (s *segment) end() uint64 {
+	return s.start + s.count
+}
+
+// position points to a record in the log.
+type position struct {
+	seq    uint64 // sequence number of the record
+	index  int    // index of the segment containing the record
+	offset int64  // byte offset of the record in the segment
+}
+
+// DiskBuffer stores metrics in a write-ahead log on disk so that they survive
+// restarts of the agent.  Metrics are kept on disk until they have been
+// accepted by the output, or until the total size of the log exceeds the
+// configured limit, in which case the oldest segments are discarded.
+//
+// Once a metric has been added to the log it is considered delivered from the
+// point of view of metric tracking.
+type DiskBuffer struct {
+	sync.Mutex
+	path        string
+	limit       int64  // maximum size of the log in bytes, 0 is unlimited
+	fsync       string // one of the BufferFsync* policies
+	segmentSize int64
+
+	segments []*segment // oldest first, the last segment is the active one
+	writer   *os.File   // file handle of the active segment
+	size     int64      // total size of all segments in bytes
+
+	first   position // oldest unacknowledged record
+	next    uint64   // sequence number of the next record to be written
+	pending int      // number of unacknowledged records
+
+	batchEnd  position // position after the last record of the batch
+	batchSize int      // number of records in the batch
+
+	serializer *serializer.Serializer
+	parser     *influx.Parser
+
+	MetricsAdded   selfstat.Stat
+	MetricsWritten selfstat.Stat
+	MetricsDropped selfstat.Stat
+	BufferSize     selfstat.Stat
+	BufferLimit    selfstat.Stat
+	DiskSize       selfstat.Stat
+}
+
+// NewDiskBuffer opens, or creates if missing, the write-ahead log in the
+// directory path.  Any metrics remaining from a previous run are made
+// available to Batch.
+func NewDiskBuffer(name string, alias string, path string, limit int64, fsync string) (*DiskBuffer, error) {
+	switch fsync {
+	case "":
+		fsync = BufferFsyncBatch
+	case BufferFsyncAlways, BufferFsyncBatch, BufferFsyncNever:
+	default:
+		return nil, fmt.Errorf("invalid buffer fsync policy: %q", fsync)
+	}
+
+	segmentSize := int64(defaultSegmentSize)
+	if limit > 0 && limit/8 < segmentSize {
+		segmentSize = limit / 8
+		if segmentSize < minSegmentSize {
+			segmentSize = minSegmentSize
+		}
+	}
+
+	s := serializer.NewSerializer()
+	s.SetFieldTypeSupport(serializer.UintSupport)
+
+	tags := statTags("output", name, alias)
+	b := &DiskBuffer{
+		path:        path,
+		limit:       limit,
+		fsync:       fsync,
+		segmentSize: segmentSize,
+		serializer:  s,
+		parser:      influx.NewParser(influx.NewMetricHandler()),
+
+		MetricsAdded: selfstat.Register(
+			"write",
+			"metrics_added",
+			tags,
+		),
+		MetricsWritten: selfstat.Register(
+			"write",
+			"metrics_written",
+			tags,
+		),
+		MetricsDropped: selfstat.Register(
+			"write",
+			"metrics_dropped",
+			tags,
+		),
+		BufferSize: selfstat.Register(
+			"write",
+			"buffer_size",
+			tags,
+		),
+		BufferLimit: selfstat.Register(
+			"write",
+			"buffer_limit",
+			tags,
+		),
+		DiskSize: selfstat.Register(
+			"write",
+			"buffer_disk_size",
+			tags,
+		),
+	}
+
+	if err := b.open(); err != nil {
+		b.closeWriter()
+		return nil, err
+	}
+
+	b.BufferSize.Set(int64(b.pending))
+	b.BufferLimit.Set(int64(0))
+	b.DiskSize.Set(b.size)
+	return b, nil
+}
+
+// open loads the existing segments from disk, discarding those that have
+// already been acknowledged and truncating any partially written records.
+func (b *DiskBuffer) open() error {
+	err := os.MkdirAll(b.path, 0755)
+	if err != nil {
+		return err
+	}
+
+	checkpoint, err := b.readCheckpoint()
+	if err != nil {
+		return err
+	}
+
+	files, err := ioutil.ReadDir(b.path)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), segmentExt) {
+			continue
+		}
+
+		start, err := strconv.ParseUint(strings.TrimSuffix(file.Name(), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		b.segments = append(b.segments, &segment{
+			path:  filepath.Join(b.path, file.Name()),
+			start: start,
+		})
+	}
+	sort.Slice(b.segments, func(i, j int) bool {
+		return b.segments[i].start < b.segments[j].start
+	})
+
+	segments := b.segments[:0]
+	for _, seg := range b.segments {
+		firstOffset, err := b.loadSegment(seg, checkpoint)
+		if err != nil {
+			return err
+		}
+
+		if seg.end() <= checkpoint {
+			if err := os.Remove(seg.path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if len(segments) == 0 {
+			b.first = position{
+				seq:    maxUint64(seg.start, checkpoint),
+				offset: firstOffset,
+			}
+		}
+
+		segments = append(segments, seg)
+		b.pending += int(seg.end() - maxUint64(seg.start, checkpoint))
+		b.size += seg.size
+		b.next = seg.end()
+	}
+	b.segments = segments
+
+	if len(b.segments) == 0 {
+		b.next = checkpoint
+		b.first = position{seq: checkpoint}
+		return b.createSegment()
+	}
+
+	active := b.segments[len(b.segments)-1]
+	b.writer, err = os.OpenFile(active.path, os.O_WRONLY|os.O_APPEND, 0644)
+	return err
+}
+
+// loadSegment counts the valid records of a segment and returns the offset
+// of the record with the sequence number seq.  The file is truncated after
+// the last valid record.
+func (b *DiskBuffer) loadSegment(seg *segment, seq uint64) (int64, error) {
+	file, err := os.Open(seg.path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var seqOffset int64
+	var offset int64
+	reader := bufio.NewReader(file)
+	for {
+		if seg.start+seg.count == seq {
+			seqOffset = offset
+		}
+
+		_, n, err := readRecord(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Printf("W! Truncating buffer segment %q at offset %d: %v",
+				seg.path, offset, err)
+			if err := os.Truncate(seg.path, offset); err != nil {
+				return 0, err
+			}
+			break
+		}
+
+		offset += n
+		seg.count++
+	}
+
+	seg.size = offset
+	return seqOffset, nil
+}
+
+func (b *DiskBuffer) readCheckpoint() (uint64, error) {
+	data, err := ioutil.ReadFile(filepath.Join(b.path, checkpointFile))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	checkpoint, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid buffer checkpoint: %v", err)
+	}
+	return checkpoint, nil
+}
+
+// writeCheckpoint records the sequence number of the oldest unacknowledged
+// record.
+func (b *DiskBuffer) writeCheckpoint() error {
+	filename := filepath.Join(b.path, checkpointFile)
+	tmpname := filename + ".tmp"
+
+	file, err := os.Create(tmpname)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.WriteString(strconv.FormatUint(b.first.seq, 10))
+	if err == nil && b.fsync != BufferFsyncNever {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmpname, filename)
+}
+
+func (b *DiskBuffer) createSegment() error {
+	seg := &segment{
+		path:  filepath.Join(b.path, fmt.Sprintf("%020d%s", b.next, segmentExt)),
+		start: b.next,
+	}
+
+	file, err := os.OpenFile(seg.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	b.closeWriter()
+	b.writer = file
+	b.segments = append(b.segments, seg)
+	return nil
+}
+
+func (b *DiskBuffer) closeWriter() {
+	if b.writer == nil {
+		return
+	}
+
+	err := b.writer.Close()
+	if err != nil {
+		log.Printf("E! Error closing buffer segment: %v", err)
+	}
+	b.writer = nil
+}
+
+func (b *DiskBuffer) active() *segment {
+	return b.segments[len(b.segments)-1]
+}
+
+func (b *DiskBuffer) sync() {
+	err := b.writer.Sync()
+	if err != nil {
+		log.Printf("E! Error syncing buffer segment: %v", err)
+	}
+}
+
+// Len returns the number of metrics currently in the buffer.
+func (b *DiskBuffer) Len() int {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.pending
+}
+
+// Fill returns the size of the log relative to the limit, it is always 0 if
+// the log is unlimited.
+func (b *DiskBuffer) Fill() float64 {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.limit <= 0 {
+		return 0
+	}
+	return float64(b.size) / float64(b.limit)
+}
+
+func (b *DiskBuffer) metricAdded() {
+	b.MetricsAdded.Incr(1)
+}
+
+func (b *DiskBuffer) metricWritten(metric telegraf.Metric) {
+	AgentMetricsWritten.Incr(1)
+	b.MetricsWritten.Incr(1)
+	metric.Accept()
+}
+
+func (b *DiskBuffer) metricsDropped(count int) {
+	AgentMetricsDropped.Incr(int64(count))
+	b.MetricsDropped.Incr(int64(count))
+}
+
+func (b *DiskBuffer) add(m telegraf.Metric) error {
+	line, err := b.serializer.Serialize(m)
+	if err != nil {
+		log.Printf("E! Dropping metric %q from buffer: %v", m.Name(), err)
+		b.metricsDropped(1)
+		m.Reject()
+		return nil
+	}
+
+	payload := make([]byte, recordHeaderSize+1+len(line))
+	payload[recordHeaderSize] = byte(m.Type())
+	copy(payload[recordHeaderSize+1:], line)
+	binary.BigEndian.PutUint32(payload[0:4], uint32(len(payload)-recordHeaderSize))
+	binary.BigEndian.PutUint32(payload[4:8], crc32.ChecksumIEEE(payload[recordHeaderSize:]))
+
+	if b.active().count > 0 && b.active().size+int64(len(payload)) > b.segmentSize {
+		if b.fsync != BufferFsyncNever {
+			b.sync()
+		}
+		if err := b.createSegment(); err != nil {
+			return err
+		}
+	}
+
+	seg := b.active()
+	n, err := b.writer.Write(payload)
+	if err != nil {
+		b.discardPartial(seg, n)
+		return err
+	}
+	seg.size += int64(n)
+	b.size += int64(n)
+
+	b.active().count++
+	b.next++
+	b.pending++
+
+	b.metricAdded()
+	m.Accept()
+	return nil
+}
+
+// discardPartial removes the n bytes of a failed write from the end of the
+// segment, records appended after a partial record could not be read back.
+// If the segment cannot be truncated, writing continues in a new segment.
+func (b *DiskBuffer) discardPartial(seg *segment, n int) {
+	if n == 0 {
+		return
+	}
+
+	err := os.Truncate(seg.path, seg.size)
+	if err == nil {
+		return
+	}
+	log.Printf("E! Error truncating buffer segment %q: %v", seg.path, err)
+
+	seg.size += int64(n)
+	b.size += int64(n)
+	if err := b.createSegment(); err != nil {
+		log.Printf("E! Error creating buffer segment: %v", err)
+	}
+}
+
+// Add adds metrics to the buffer
+func (b *DiskBuffer) Add(metrics ...telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	for i, m := range metrics {
+		err := b.add(m)
+		if err != nil {
+			log.Printf("E! Error writing to buffer %q: %v", b.path, err)
+			for _, m := range metrics[i:] {
+				m.Reject()
+			}
+			b.metricsDropped(len(metrics) - i)
+			break
+		}
+	}
+
+	if b.fsync == BufferFsyncAlways {
+		b.sync()
+	}
+
+	b.enforceLimit()
+	b.BufferSize.Set(int64(b.pending))
+	b.DiskSize.Set(b.size)
+}
+
+// Batch returns a slice containing up to batchSize of the oldest metrics in
+// the buffer.  The batch must not be modified by the client.
+func (b *DiskBuffer) Batch(batchSize int) []telegraf.Metric {
+	b.Lock()
+	defer b.Unlock()
+
+	outLen := min(b.pending, batchSize)
+	out := make([]telegraf.Metric, 0, outLen)
+	if outLen == 0 {
+		return out
+	}
+
+	if b.fsync == BufferFsyncBatch {
+		b.sync()
+	}
+
+	pos := b.first
+	read := 0
+	for read < outLen && pos.index < len(b.segments) {
+		seg := b.segments[pos.index]
+		if pos.seq >= seg.end() {
+			pos = b.nextSegment(pos)
+			continue
+		}
+
+		metrics, next, err := b.readSegment(seg, pos, outLen-read)
+		if err != nil {
+			// Skip the unreadable remainder of the segment, the records
+			// are counted as dropped once the batch is accepted.
+			log.Printf("E! Error reading buffer segment %q: %v", seg.path, err)
+			next = position{seq: seg.end(), index: pos.index}
+		}
+
+		read += int(next.seq - pos.seq)
+		out = append(out, metrics...)
+		pos = next
+	}
+
+	b.batchEnd = pos
+	b.batchSize = read
+	return out
+}
+
+// nextSegment returns the position of the first record of the segment
+// following the one pos points into.
+func (b *DiskBuffer) nextSegment(pos position) position {
+	index := pos.index + 1
+	if index < len(b.segments) {
+		return position{seq: maxUint64(pos.seq, b.segments[index].start), index: index}
+	}
+	return position{seq: pos.seq, index: index}
+}
+
+// readSegment reads up to count records of a segment starting at pos.
+func (b *DiskBuffer) readSegment(seg *segment, pos position, count int) ([]telegraf.Metric, position, error) {
+	file, err := os.Open(seg.path)
+	if err != nil {
+		return nil, pos, err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(pos.offset, io.SeekStart); err != nil {
+		return nil, pos, err
+	}
+
+	var metrics []telegraf.Metric
+	reader := bufio.NewReader(file)
+	for i := 0; i < count && pos.seq < seg.end(); i++ {
+		payload, n, err := readRecord(reader)
+		if err != nil {
+			return metrics, pos, err
+		}
+
+		pos.seq++
+		pos.offset += n
+
+		m, err := b.decode(payload)
+		if err != nil {
+			log.Printf("E! Dropping unreadable metric from buffer: %v", err)
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, pos, nil
+}
+
+func (b *DiskBuffer) decode(payload []byte) (telegraf.Metric, error) {
+	if len(payload) < 1 {
+		return nil, errCorruptRecord
+	}
+
+	metrics, err := b.parser.Parse(payload[1:])
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) != 1 {
+		return nil, errCorruptRecord
+	}
+
+	m := metrics[0]
+	tp := telegraf.ValueType(payload[0])
+	if tp == m.Type() {
+		return m, nil
+	}
+	return metric.New(m.Name(), m.Tags(), m.Fields(), m.Time(), tp)
+}
+
+// Accept marks the batch, acquired from Batch(), as successfully written.
+func (b *DiskBuffer) Accept(batch []telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, m := range batch {
+		b.metricWritten(m)
+	}
+
+	// Records that could not be read are part of the batch but were never
+	// handed to the output.
+	if dropped := b.batchSize - len(batch); dropped > 0 {
+		b.metricsDropped(dropped)
+	}
+
+	b.removeBatch()
+}
+
+// Drop removes the batch, acquired from Batch(), from the buffer without
+// writing it.
+func (b *DiskBuffer) Drop(batch []telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, m := range batch {
+		m.Reject()
+	}
+	b.metricsDropped(b.batchSize)
+
+	b.removeBatch()
+}
+
+// Expire removes the metrics at the start of the log that are older than the
+// cutoff.  The metrics are read one at a time, the first metric that is not
+// older than the cutoff ends the search.
+func (b *DiskBuffer) Expire(cutoff time.Time) int {
+	var expired int
+	for n := b.Len(); n > 0; {
+		batch := b.Batch(1)
+		if len(batch) > 0 && !batch[0].Time().Before(cutoff) {
+			b.Reject(batch)
+			break
+		}
+		b.Drop(batch)
+		expired += len(batch)
+
+		// Unreadable records are dropped as well, stop if nothing was
+		// removed.
+		remaining := b.Len()
+		if remaining == n {
+			break
+		}
+		n = remaining
+	}
+	return expired
+}
+
+// removeBatch acknowledges the outstanding batch, removing the segments that
+// no longer hold any pending metrics.
+func (b *DiskBuffer) removeBatch() {
+	b.pending -= b.batchSize
+	b.first = b.batchEnd
+	b.resetBatch()
+
+	// Remove segments that have been fully acknowledged
+	for len(b.segments) > 1 && b.segments[0].end() <= b.first.seq {
+		b.removeSegment()
+		if b.first.index > 0 {
+			b.first.index--
+		} else {
+			b.first = position{seq: maxUint64(b.first.seq, b.segments[0].start)}
+		}
+	}
+
+	if err := b.writeCheckpoint(); err != nil {
+		log.Printf("E! Error writing buffer checkpoint: %v", err)
+	}
+
+	// Start a fresh segment once everything has been acknowledged
+	if b.pending == 0 && b.active().count > 0 {
+		if err := b.createSegment(); err != nil {
+			log.Printf("E! Error creating buffer segment: %v", err)
+		} else {
+			b.removeSegment()
+			b.first = position{seq: b.next}
+		}
+	}
+
+	b.enforceLimit()
+	b.BufferSize.Set(int64(b.pending))
+	b.DiskSize.Set(b.size)
+}
+
+// Reject returns the batch, acquired from Batch(), to the buffer and marks it
+// as unsent.
+func (b *DiskBuffer) Reject(batch []telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.resetBatch()
+	b.enforceLimit()
+	b.BufferSize.Set(int64(b.pending))
+	b.DiskSize.Set(b.size)
+}
+
+// Close closes the active segment; all unacknowledged metrics remain on disk.
+func (b *DiskBuffer) Close() error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.writer == nil {
+		return nil
+	}
+
+	var err error
+	if b.fsync != BufferFsyncNever {
+		err = b.writer.Sync()
+	}
+	if cerr := b.writer.Close(); err == nil {
+		err = cerr
+	}
+	b.writer = nil
+	return err
+}
+
+// enforceLimit drops the oldest segments while the log is larger than the
+// limit.  Segments are never dropped while a batch is outstanding.
+func (b *DiskBuffer) enforceLimit() {
+	if b.limit <= 0 || b.batchSize > 0 {
+		return
+	}
+
+	for b.size > b.limit {
+		if len(b.segments) == 1 {
+			if b.active().count == 0 {
+				return
+			}
+
+			// The active segment alone exceeds the limit, start a new
+			// segment so that it can be dropped.
+			if err := b.createSegment(); err != nil {
+				log.Printf("E! Error creating buffer segment: %v", err)
+				return
+			}
+		}
+
+		seg := b.segments[0]
+		dropped := int(seg.end() - maxUint64(seg.start, b.first.seq))
+		b.metricsDropped(dropped)
+		b.pending -= dropped
+
+		b.removeSegment()
+		b.first = position{seq: b.segments[0].start}
+	}
+}
+
+// removeSegment deletes the oldest segment.
+func (b *DiskBuffer) removeSegment() {
+	seg := b.segments[0]
+	err := os.Remove(seg.path)
+	if err != nil {
+		log.Printf("E! Error removing buffer segment: %v", err)
+	}
+
+	b.size -= seg.size
+	b.segments[0] = nil
+	b.segments = b.segments[1:]
+}
+
+func (b *DiskBuffer) resetBatch() {
+	b.batchEnd = position{}
+	b.batchSize = 0
+}
+
+// readRecord reads a single record, returning its payload and the number of
+// bytes consumed.
+func readRecord(r *bufio.Reader) ([]byte, int64, error) {
+	var header [recordHeaderSize]byte
+	_, err := io.ReadFull(r, header[:])
+	if err == io.EOF {
+		return nil, 0, io.EOF
+	}
+	if err != nil {
+		return nil, 0, errCorruptRecord
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length > maxRecordSize {
+		return nil, 0, errCorruptRecord
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, 0, errCorruptRecord
+	}
+
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, 0, errCorruptRecord
+	}
+
+	return payload, int64(recordHeaderSize + len(payload)), nil
+}
+
+func maxUint64(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,292 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDiskBuffer(t *testing.T, dir string, limit int64) *DiskBuffer {
+	b, err := NewDiskBuffer("test", "", dir, limit, BufferFsyncNever)
+	require.NoError(t, err)
+	b.MetricsAdded.Set(0)
+	b.MetricsWritten.Set(0)
+	b.MetricsDropped.Set(0)
+	return b
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	return dir
+}
+
+func TestDiskBuffer_LenEmpty(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir, 0)
+	defer b.Close()
+
+	require.Equal(t, 0, b.Len())
+}
+
+func TestDiskBuffer_BatchAccept(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir, 0)
+	defer b.Close()
+
+	b.Add(MetricTime(1), MetricTime(2), MetricTime(3))
+	require.Equal(t, 3, b.Len())
+
+	batch := b.Batch(2)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(1),
+			MetricTime(2),
+		}, batch)
+
+	b.Accept(batch)
+	require.Equal(t, 1, b.Len())
+	require.Equal(t, int64(2), b.MetricsWritten.Get())
+
+	batch = b.Batch(2)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(3),
+		}, batch)
+
+	b.Accept(batch)
+	require.Equal(t, 0, b.Len())
+}
+
+func TestDiskBuffer_RejectReturnsBatch(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir, 0)
+	defer b.Close()
+
+	b.Add(MetricTime(1), MetricTime(2))
+	batch := b.Batch(2)
+	b.Reject(batch)
+	require.Equal(t, 2, b.Len())
+
+	batch = b.Batch(2)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(1),
+			MetricTime(2),
+		}, batch)
+	require.Equal(t, int64(0), b.MetricsDropped.Get())
+}
+
+func TestDiskBuffer_DropRemovesBatch(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir, 0)
+	defer b.Close()
+
+	b.Add(MetricTime(1), MetricTime(2), MetricTime(3))
+	batch := b.Batch(2)
+	b.Drop(batch)
+	require.Equal(t, 1, b.Len())
+	require.Equal(t, int64(2), b.MetricsDropped.Get())
+	require.Equal(t, int64(0), b.MetricsWritten.Get())
+
+	batch = b.Batch(2)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(3),
+		}, batch)
+}
+
+func TestDiskBuffer_Expire(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir, 0)
+	defer b.Close()
+
+	b.Add(MetricTime(1), MetricTime(2), MetricTime(3), MetricTime(1))
+	require.Equal(t, 2, b.Expire(time.Unix(3, 0)))
+	require.Equal(t, 2, b.Len())
+	require.Equal(t, int64(2), b.MetricsDropped.Get())
+
+	batch := b.Batch(2)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(3),
+			MetricTime(1),
+		}, batch)
+}
+
+func TestDiskBuffer_AddAcceptsMetric(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir, 0)
+	defer b.Close()
+
+	var accept int
+	mm := &MockMetric{
+		Metric: Metric(),
+		AcceptF: func() {
+			accept++
+		},
+	}
+	b.Add(mm)
+	require.Equal(t, 1, accept)
+}
+
+func TestDiskBuffer_PersistsAcrossRestart(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir, 0)
+	b.Add(MetricTime(1), MetricTime(2), MetricTime(3))
+	b.Accept(b.Batch(1))
+	b.Reject(b.Batch(1))
+	require.NoError(t, b.Close())
+
+	b = newTestDiskBuffer(t, dir, 0)
+	defer b.Close()
+
+	require.Equal(t, 2, b.Len())
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(2),
+			MetricTime(3),
+		}, b.Batch(10))
+}
+
+func TestDiskBuffer_PreservesTypes(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir, 0)
+	defer b.Close()
+
+	m, err := metric.New(
+		"cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{
+			"uint":   uint64(42),
+			"int":    int64(42),
+			"float":  42.0,
+			"string": "howdy",
+			"bool":   true,
+		},
+		time.Unix(0, 42),
+		telegraf.Counter,
+	)
+	require.NoError(t, err)
+
+	b.Add(m)
+	batch := b.Batch(1)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{m}, batch)
+	require.Equal(t, telegraf.Counter, batch[0].Type())
+}
+
+func TestDiskBuffer_LimitDropsOldest(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir, 2*minSegmentSize)
+	defer b.Close()
+
+	for i := 0; i < 10000; i++ {
+		b.Add(MetricTime(int64(i)))
+	}
+
+	require.True(t, b.size <= b.limit)
+	require.True(t, b.Len() < 10000)
+	require.Equal(t, int64(10000-b.Len()), b.MetricsDropped.Get())
+
+	batch := b.Batch(1)
+	require.Equal(t, int64(10000-b.Len()), batch[0].Time().Unix())
+}
+
+func TestDiskBuffer_LimitDropsActiveSegment(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir, 1024)
+	b.segmentSize = defaultSegmentSize
+	defer b.Close()
+
+	for i := 0; i < 100; i++ {
+		b.Add(MetricTime(int64(i)))
+	}
+
+	require.True(t, b.size <= b.limit)
+	require.Equal(t, int64(100-b.Len()), b.MetricsDropped.Get())
+
+	b.Add(MetricTime(100))
+	batch := b.Batch(100)
+	require.NotEmpty(t, batch)
+	require.Equal(t, int64(100), batch[len(batch)-1].Time().Unix())
+}
+
+func TestDiskBuffer_TruncatesPartialRecord(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir, 0)
+	b.Add(MetricTime(1), MetricTime(2))
+	require.NoError(t, b.Close())
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	info, err := os.Stat(files[0])
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(files[0], info.Size()-3))
+
+	b = newTestDiskBuffer(t, dir, 0)
+	defer b.Close()
+
+	require.Equal(t, 1, b.Len())
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(1),
+		}, b.Batch(10))
+}
+
+func TestDiskBuffer_RemovesAcceptedSegments(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	b := newTestDiskBuffer(t, dir, 0)
+	b.segmentSize = 128
+	defer b.Close()
+
+	for i := 0; i < 100; i++ {
+		b.Add(MetricTime(int64(i)))
+	}
+	require.True(t, len(b.segments) > 1)
+
+	b.Accept(b.Batch(100))
+	require.Equal(t, 0, b.Len())
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	b.Add(MetricTime(100))
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{
+			MetricTime(100),
+		}, b.Batch(10))
+}
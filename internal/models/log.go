@@ -0,0 +1,46 @@
+package models
+
+// logName returns the name used for a plugin in log messages, the alias is
+// appended so that plugins of the same type can be told apart.
+func logName(pluginType, name, alias string) string {
+	if alias == "" {
+		return pluginType + "." + name
+	}
+	return pluginType + "." + name + "::" + alias
+}
+
+// statTags returns the tags of the internal metrics of a plugin.
+func statTags(pluginType, name, alias string) map[string]string {
+	tags := map[string]string{pluginType: name}
+	if alias != "" {
+		tags["alias"] = alias
+	}
+	return tags
+}
+
+// logNamer is implemented by plugins that include their name in their own
+// log messages.
+type logNamer interface {
+	SetLogName(name string)
+}
+
+// setLogName passes the name used in log messages to the plugin, so that
+// the messages of the plugin include its alias.
+func setLogName(plugin interface{}, name string) {
+	if p, ok := plugin.(logNamer); ok {
+		p.SetLogName(name)
+	}
+}
+
+// aliasSetter is implemented by plugins that tag their own internal metrics
+// with their alias.
+type aliasSetter interface {
+	SetAlias(alias string)
+}
+
+// setAlias passes the alias to the plugin.
+func setAlias(plugin interface{}, alias string) {
+	if p, ok := plugin.(aliasSetter); ok {
+		p.SetAlias(alias)
+	}
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// CircuitState is the state of the circuit breaker of an output.
+type CircuitState int
+
+const (
+	// CircuitClosed allows writes to the output.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen blocks writes to the output until the circuit breaker
+	// timeout has elapsed.
+	CircuitOpen
+	// CircuitHalfOpen allows a single trial write, the circuit is closed if
+	// it succeeds and opened again if it fails.
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// RetryConfig is the policy for retrying failed writes of an output.  The
+// zero value retries the failed batch at every flush.
+type RetryConfig struct {
+	// InitialInterval is the delay before retrying after the first failed
+	// write, the delay doubles after each further failure.
+	InitialInterval time.Duration
+
+	// MaxInterval is the upper bound of the delay, unbounded if zero.
+	MaxInterval time.Duration
+
+	// Jitter is the fraction of the delay that is randomized, between 0
+	// and 1.
+	Jitter float64
+
+	// MaxAge is how long metrics are retried before they are dropped, by
+	// the time of the metrics.  Metrics are retried until written if zero.
+	MaxAge time.Duration
+
+	// CircuitBreakerFailures is the number of consecutive failed writes
+	// that open the circuit breaker, disabled if zero.
+	CircuitBreakerFailures int
+
+	// CircuitBreakerTimeout is how long the circuit breaker stays open
+	// before a trial write is allowed.
+	CircuitBreakerTimeout time.Duration
+}
+
+// retryPolicy tracks the failed writes of an output and decides when the next
+// write may be attempted.
+type retryPolicy struct {
+	config RetryConfig
+
+	failures    int
+	nextAttempt time.Time
+
+	state    CircuitState
+	openedAt time.Time
+}
+
+// allow returns true if a write may be attempted at the time.  An open
+// circuit becomes half-open once the circuit breaker timeout has elapsed.
+func (p *retryPolicy) allow(now time.Time) bool {
+	switch p.state {
+	case CircuitOpen:
+		if now.Sub(p.openedAt) < p.config.CircuitBreakerTimeout {
+			return false
+		}
+		p.state = CircuitHalfOpen
+		return true
+	case CircuitHalfOpen:
+		return true
+	}
+	return !now.Before(p.nextAttempt)
+}
+
+// success records a successful write, closing the circuit.
+func (p *retryPolicy) success() {
+	p.failures = 0
+	p.nextAttempt = time.Time{}
+	p.state = CircuitClosed
+}
+
+// failure records a failed write at the time.
+func (p *retryPolicy) failure(now time.Time) {
+	p.failures++
+	p.nextAttempt = now.Add(p.backoff())
+
+	breaker := p.config.CircuitBreakerFailures
+	if p.state == CircuitHalfOpen || (breaker > 0 && p.failures >= breaker) {
+		p.state = CircuitOpen
+		p.openedAt = now
+	}
+}
+
+// backoff returns the delay before the next attempt.
+func (p *retryPolicy) backoff() time.Duration {
+	if p.config.InitialInterval <= 0 {
+		return 0
+	}
+
+	delay := p.config.InitialInterval
+	for i := 1; i < p.failures; i++ {
+		if p.config.MaxInterval > 0 && delay >= p.config.MaxInterval {
+			break
+		}
+		if delay > math.MaxInt64/2 {
+			break
+		}
+		delay *= 2
+	}
+	if p.config.MaxInterval > 0 && delay > p.config.MaxInterval {
+		delay = p.config.MaxInterval
+	}
+
+	if p.config.Jitter > 0 {
+		delay -= time.Duration(rand.Float64() * p.config.Jitter * float64(delay))
+	}
+	return delay
+}
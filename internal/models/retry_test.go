@@ -0,0 +1,93 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := &retryPolicy{config: RetryConfig{
+		InitialInterval: time.Second,
+		MaxInterval:     5 * time.Second,
+	}}
+
+	now := time.Unix(0, 0)
+	require.True(t, p.allow(now))
+
+	var delays []time.Duration
+	for i := 0; i < 5; i++ {
+		p.failure(now)
+		delays = append(delays, p.nextAttempt.Sub(now))
+	}
+	require.Equal(t, []time.Duration{
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		5 * time.Second,
+		5 * time.Second,
+	}, delays)
+
+	require.False(t, p.allow(now.Add(4*time.Second)))
+	require.True(t, p.allow(now.Add(5*time.Second)))
+
+	p.success()
+	require.True(t, p.allow(now))
+}
+
+func TestRetryPolicy_Jitter(t *testing.T) {
+	p := &retryPolicy{config: RetryConfig{
+		InitialInterval: time.Second,
+		Jitter:          0.5,
+	}}
+
+	now := time.Unix(0, 0)
+	for i := 0; i < 100; i++ {
+		p.failure(now)
+		delay := p.nextAttempt.Sub(now)
+		require.True(t, delay > 500*time.Millisecond, delay)
+		require.True(t, delay <= time.Second, delay)
+		p.success()
+	}
+}
+
+func TestRetryPolicy_NoBackoff(t *testing.T) {
+	p := &retryPolicy{}
+
+	now := time.Unix(0, 0)
+	p.failure(now)
+	p.failure(now)
+	require.True(t, p.allow(now))
+	require.Equal(t, CircuitClosed, p.state)
+}
+
+func TestRetryPolicy_CircuitBreaker(t *testing.T) {
+	p := &retryPolicy{config: RetryConfig{
+		CircuitBreakerFailures: 2,
+		CircuitBreakerTimeout:  time.Minute,
+	}}
+
+	now := time.Unix(0, 0)
+	p.failure(now)
+	require.Equal(t, CircuitClosed, p.state)
+	p.failure(now)
+	require.Equal(t, CircuitOpen, p.state)
+	require.False(t, p.allow(now.Add(30*time.Second)))
+
+	// A failed trial write opens the circuit again.
+	now = now.Add(time.Minute)
+	require.True(t, p.allow(now))
+	require.Equal(t, CircuitHalfOpen, p.state)
+	p.failure(now)
+	require.Equal(t, CircuitOpen, p.state)
+	require.False(t, p.allow(now.Add(30*time.Second)))
+
+	// A successful trial write closes the circuit.
+	now = now.Add(time.Minute)
+	require.True(t, p.allow(now))
+	p.success()
+	require.Equal(t, CircuitClosed, p.state)
+	p.failure(now)
+	require.Equal(t, CircuitClosed, p.state)
+}
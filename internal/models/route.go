@@ -0,0 +1,24 @@
+package models
+
+import "github.com/influxdata/telegraf"
+
+// RouteTag is the tag holding the route of a metric.  Outputs that set
+// routes only receive the metrics with one of their routes, the tag is
+// removed before the metric is added to an output.
+const RouteTag = "_route"
+
+// setRoute sets the route of the metric, if route is not empty.
+func setRoute(metric telegraf.Metric, route string) {
+	if route != "" {
+		metric.AddTag(RouteTag, route)
+	}
+}
+
+// TakeRoute removes the route tag from the metric and returns the route.
+func TakeRoute(metric telegraf.Metric) string {
+	route, ok := metric.GetTag(RouteTag)
+	if ok {
+		metric.RemoveTag(RouteTag)
+	}
+	return route
+}
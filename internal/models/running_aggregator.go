@@ -27,35 +27,41 @@ func NewRunningAggregator(
 	aggregator telegraf.Aggregator,
 	config *AggregatorConfig,
 ) *RunningAggregator {
-	return &RunningAggregator{
+	tags := statTags("aggregator", config.Name, config.Alias)
+	ra := &RunningAggregator{
 		Aggregator: aggregator,
 		Config:     config,
 		MetricsPushed: selfstat.Register(
 			"aggregate",
 			"metrics_pushed",
-			map[string]string{"aggregator": config.Name},
+			tags,
 		),
 		MetricsFiltered: selfstat.Register(
 			"aggregate",
 			"metrics_filtered",
-			map[string]string{"aggregator": config.Name},
+			tags,
 		),
 		MetricsDropped: selfstat.Register(
 			"aggregate",
 			"metrics_dropped",
-			map[string]string{"aggregator": config.Name},
+			tags,
 		),
 		PushTime: selfstat.Register(
 			"aggregate",
 			"push_time_ns",
-			map[string]string{"aggregator": config.Name},
+			tags,
 		),
 	}
+	setLogName(aggregator, ra.LogName())
+	setAlias(aggregator, config.Alias)
+	return ra
 }
 
 // AggregatorConfig is the common config for all aggregators.
 type AggregatorConfig struct {
 	Name         string
+	Alias        string
+	LogLevel     string
 	DropOriginal bool
 	Period       time.Duration
 	Delay        time.Duration
@@ -65,12 +71,21 @@ type AggregatorConfig struct {
 	MeasurementSuffix string
 	Tags              map[string]string
 	Filter            Filter
+
+	// Hash identifies the plugin settings, it is used to detect changes when
+	// the configuration is reloaded.
+	Hash string
 }
 
 func (r *RunningAggregator) Name() string {
 	return "aggregators." + r.Config.Name
 }
 
+// LogName returns the name of the aggregator including its alias.
+func (r *RunningAggregator) LogName() string {
+	return logName("aggregators", r.Config.Name, r.Config.Alias)
+}
+
 func (r *RunningAggregator) Period() time.Duration {
 	return r.Config.Period
 }
@@ -82,7 +97,7 @@ func (r *RunningAggregator) EndPeriod() time.Time {
 func (r *RunningAggregator) UpdateWindow(start, until time.Time) {
 	r.periodStart = start
 	r.periodEnd = until
-	log.Printf("D! [%s] Updated aggregation range [%s, %s]", r.Name(), start, until)
+	log.Printf("D! [%s] Updated aggregation range [%s, %s]", r.LogName(), start, until)
 }
 
 func (r *RunningAggregator) MakeMetric(metric telegraf.Metric) telegraf.Metric {
@@ -127,7 +142,7 @@ func (r *RunningAggregator) Add(m telegraf.Metric) bool {
 
 	if m.Time().Before(r.periodStart) || m.Time().After(r.periodEnd.Add(r.Config.Delay)) {
 		log.Printf("D! [%s] metric is outside aggregation window; discarding. %s: m: %s e: %s",
-			r.Name(), m.Time(), r.periodStart, r.periodEnd)
+			r.LogName(), m.Time(), r.periodStart, r.periodEnd)
 		r.MetricsDropped.Incr(1)
 		return r.Config.DropOriginal
 	}
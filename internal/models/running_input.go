@@ -1,6 +1,7 @@
 package models
 
 import (
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -17,28 +18,53 @@ type RunningInput struct {
 
 	MetricsGathered selfstat.Stat
 	GatherTime      selfstat.Stat
+
+	statusMu     sync.Mutex
+	status       InputStatus
+	gathering    bool
+	gatherErrors int
+}
+
+// InputStatus is the outcome of the recent gathers of an input.
+type InputStatus struct {
+	// LastGather is the start time of the last completed gather.
+	LastGather time.Time
+
+	// LastError is the last error reported by the input.
+	LastError     string
+	LastErrorTime time.Time
+
+	// ConsecutiveFailures is the number of gathers in a row that returned
+	// or reported an error.
+	ConsecutiveFailures int
 }
 
 func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
-	return &RunningInput{
+	tags := statTags("input", config.Name, config.Alias)
+	ri := &RunningInput{
 		Input:  input,
 		Config: config,
 		MetricsGathered: selfstat.Register(
 			"gather",
 			"metrics_gathered",
-			map[string]string{"input": config.Name},
+			tags,
 		),
 		GatherTime: selfstat.RegisterTiming(
 			"gather",
 			"gather_time_ns",
-			map[string]string{"input": config.Name},
+			tags,
 		),
 	}
+	setLogName(input, ri.LogName())
+	setAlias(input, config.Alias)
+	return ri
 }
 
 // InputConfig is the common config for all inputs.
 type InputConfig struct {
 	Name     string
+	Alias    string
+	LogLevel string
 	Interval time.Duration
 
 	NameOverride      string
@@ -46,12 +72,24 @@ type InputConfig struct {
 	MeasurementSuffix string
 	Tags              map[string]string
 	Filter            Filter
+
+	// Route is set on each metric of the input, see RouteTag.
+	Route string
+
+	// Hash identifies the plugin settings, it is used to detect changes when
+	// the configuration is reloaded.
+	Hash string
 }
 
 func (r *RunningInput) Name() string {
 	return "inputs." + r.Config.Name
 }
 
+// LogName returns the name of the input including its alias.
+func (r *RunningInput) LogName() string {
+	return logName("inputs", r.Config.Name, r.Config.Alias)
+}
+
 func (r *RunningInput) metricFiltered(metric telegraf.Metric) {
 	metric.Drop()
 }
@@ -70,6 +108,8 @@ func (r *RunningInput) MakeMetric(metric telegraf.Metric) telegraf.Metric {
 		r.Config.Tags,
 		r.defaultTags)
 
+	setRoute(m, r.Config.Route)
+
 	r.Config.Filter.Modify(metric)
 	if len(metric.FieldList()) == 0 {
 		r.metricFiltered(metric)
@@ -82,13 +122,54 @@ func (r *RunningInput) MakeMetric(metric telegraf.Metric) telegraf.Metric {
 }
 
 func (r *RunningInput) Gather(acc telegraf.Accumulator) error {
+	r.statusMu.Lock()
+	r.gathering = true
+	r.gatherErrors = 0
+	r.statusMu.Unlock()
+
 	start := time.Now()
 	err := r.Input.Gather(acc)
 	elapsed := time.Since(start)
 	r.GatherTime.Incr(elapsed.Nanoseconds())
+
+	r.statusMu.Lock()
+	r.gathering = false
+	r.status.LastGather = start
+	if err != nil {
+		r.status.LastError = err.Error()
+		r.status.LastErrorTime = time.Now()
+	}
+	if err != nil || r.gatherErrors > 0 {
+		r.status.ConsecutiveFailures++
+	} else {
+		r.status.ConsecutiveFailures = 0
+	}
+	r.statusMu.Unlock()
+
 	return err
 }
 
+// ReportError records an error reported by the input.  The running gather,
+// if any, is counted as failed.
+func (r *RunningInput) ReportError(err error) {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+
+	if r.gathering {
+		r.gatherErrors++
+	}
+	r.status.LastError = err.Error()
+	r.status.LastErrorTime = time.Now()
+}
+
+// Status returns the outcome of the recent gathers.
+func (r *RunningInput) Status() InputStatus {
+	r.statusMu.Lock()
+	defer r.statusMu.Unlock()
+
+	return r.status
+}
+
 func (r *RunningInput) SetDefaultTags(tags map[string]string) {
 	r.defaultTags = tags
 }
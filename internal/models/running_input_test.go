@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -44,6 +45,63 @@ func TestMakeMetricFilterAfterApplyingGlobalTags(t *testing.T) {
 	testutil.RequireMetricEqual(t, expected, actual)
 }
 
+func TestMakeMetricRoute(t *testing.T) {
+	now := time.Now()
+	ri := NewRunningInput(&testInput{}, &InputConfig{
+		Name:  "TestRunningInput",
+		Route: "metrics",
+	})
+
+	m, err := metric.New("cpu",
+		map[string]string{},
+		map[string]interface{}{
+			"value": 42,
+		},
+		now)
+	require.NoError(t, err)
+
+	actual := ri.MakeMetric(m)
+
+	expected, err := metric.New("cpu",
+		map[string]string{
+			RouteTag: "metrics",
+		},
+		map[string]interface{}{
+			"value": 42,
+		},
+		now)
+	require.NoError(t, err)
+
+	testutil.RequireMetricEqual(t, expected, actual)
+}
+
+func TestRunningInputAlias(t *testing.T) {
+	ri := NewRunningInput(&testInput{}, &InputConfig{
+		Name: "http",
+	})
+	require.Equal(t, "inputs.http", ri.LogName())
+	require.Equal(t, map[string]string{"input": "http"}, ri.MetricsGathered.Tags())
+
+	ri = NewRunningInput(&testInput{}, &InputConfig{
+		Name:  "http",
+		Alias: "api",
+	})
+	require.Equal(t, "inputs.http", ri.Name())
+	require.Equal(t, "inputs.http::api", ri.LogName())
+	require.Equal(t, map[string]string{"input": "http", "alias": "api"}, ri.MetricsGathered.Tags())
+	require.Equal(t, map[string]string{"input": "http", "alias": "api"}, ri.GatherTime.Tags())
+}
+
+func TestRunningInputSetLogName(t *testing.T) {
+	input := &namedInput{}
+	NewRunningInput(input, &InputConfig{
+		Name:  "http",
+		Alias: "api",
+	})
+	require.Equal(t, "inputs.http::api", input.logName)
+	require.Equal(t, "api", input.alias)
+}
+
 func TestMakeMetricNoFields(t *testing.T) {
 	now := time.Now()
 	ri := NewRunningInput(&testInput{}, &InputConfig{
@@ -256,8 +314,72 @@ func TestMakeMetricNameSuffix(t *testing.T) {
 	require.Equal(t, expected, m)
 }
 
+func TestRunningInputStatus(t *testing.T) {
+	input := &errorInput{}
+	ri := NewRunningInput(input, &InputConfig{Name: "test"})
+
+	require.NoError(t, ri.Gather(nil))
+	status := ri.Status()
+	require.False(t, status.LastGather.IsZero())
+	require.Equal(t, 0, status.ConsecutiveFailures)
+
+	input.err = errors.New("gather failed")
+	require.Error(t, ri.Gather(nil))
+	require.Error(t, ri.Gather(nil))
+	status = ri.Status()
+	require.Equal(t, 2, status.ConsecutiveFailures)
+	require.Equal(t, "gather failed", status.LastError)
+
+	input.err = nil
+	require.NoError(t, ri.Gather(nil))
+	require.Equal(t, 0, ri.Status().ConsecutiveFailures)
+}
+
+func TestRunningInputStatusReportedError(t *testing.T) {
+	input := &errorInput{}
+	ri := NewRunningInput(input, &InputConfig{Name: "test"})
+
+	// Errors reported during a gather fail the gather.
+	input.report = func() { ri.ReportError(errors.New("reported")) }
+	require.NoError(t, ri.Gather(nil))
+	status := ri.Status()
+	require.Equal(t, 1, status.ConsecutiveFailures)
+	require.Equal(t, "reported", status.LastError)
+
+	// Errors reported outside of a gather are recorded only.
+	input.report = nil
+	ri.ReportError(errors.New("background"))
+	require.NoError(t, ri.Gather(nil))
+	status = ri.Status()
+	require.Equal(t, 0, status.ConsecutiveFailures)
+	require.Equal(t, "background", status.LastError)
+}
+
+type errorInput struct {
+	err    error
+	report func()
+}
+
+func (t *errorInput) Description() string  { return "" }
+func (t *errorInput) SampleConfig() string { return "" }
+func (t *errorInput) Gather(acc telegraf.Accumulator) error {
+	if t.report != nil {
+		t.report()
+	}
+	return t.err
+}
+
 type testInput struct{}
 
+type namedInput struct {
+	testInput
+	logName string
+	alias   string
+}
+
+func (t *namedInput) SetLogName(name string) { t.logName = name }
+func (t *namedInput) SetAlias(alias string)  { t.alias = alias }
+
 func (t *testInput) Description() string                   { return "" }
 func (t *testInput) SampleConfig() string                  { return "" }
 func (t *testInput) Gather(acc telegraf.Accumulator) error { return nil }
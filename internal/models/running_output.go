@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"log"
 	"sync"
 	"sync/atomic"
@@ -16,16 +17,38 @@ const (
 
 	// Default number of metrics kept. It should be a multiple of batch size.
 	DEFAULT_METRIC_BUFFER_LIMIT = 10000
+
+	// Default time the circuit breaker stays open.
+	DEFAULT_CIRCUIT_BREAKER_TIMEOUT = time.Minute
 )
 
 // OutputConfig containing name and filter
 type OutputConfig struct {
-	Name   string
-	Filter Filter
+	Name     string
+	Alias    string
+	LogLevel string
+	Filter   Filter
+
+	// Routes are the routes of the metrics sent to the output, the output
+	// receives all metrics if empty.  See RouteTag.
+	Routes []string
 
 	FlushInterval     time.Duration
 	MetricBufferLimit int
 	MetricBatchSize   int
+
+	// Disk buffer settings, the metrics are buffered in memory if
+	// BufferDirectory is empty.
+	BufferDirectory string
+	BufferDiskLimit int64
+	BufferFsync     string
+
+	// Retry is the policy for retrying failed writes.
+	Retry RetryConfig
+
+	// Hash identifies the plugin settings, it is used to detect changes when
+	// the configuration is reloaded.
+	Hash string
 }
 
 // RunningOutput contains the output configuration
@@ -40,13 +63,45 @@ type RunningOutput struct {
 	MetricBatchSize   int
 
 	MetricsFiltered selfstat.Stat
+	MetricsExpired  selfstat.Stat
 	WriteTime       selfstat.Stat
+	WriteErrors     selfstat.Stat
+	CircuitState    selfstat.Stat
 
 	BatchReady chan time.Time
 
-	buffer *Buffer
+	buffer MetricBuffer
 
 	aggMutex sync.Mutex
+
+	statusMu sync.Mutex
+	status   OutputStatus
+	retry    retryPolicy
+
+	connected bool
+}
+
+// OutputStatus is the state of the buffer and the outcome of the recent
+// writes of an output.
+type OutputStatus struct {
+	// BufferSize is the number of metrics in the buffer.
+	BufferSize int
+
+	// BufferFill is the fraction of the buffer capacity in use.
+	BufferFill float64
+
+	// LastWrite is the time of the last successful write.
+	LastWrite time.Time
+
+	// LastError is the error of the last failed write.
+	LastError     string
+	LastErrorTime time.Time
+
+	// ConsecutiveFailures is the number of writes in a row that failed.
+	ConsecutiveFailures int
+
+	// CircuitState is the state of the circuit breaker.
+	CircuitState CircuitState
 }
 
 func NewRunningOutput(
@@ -68,9 +123,13 @@ func NewRunningOutput(
 	if batchSize == 0 {
 		batchSize = DEFAULT_METRIC_BATCH_SIZE
 	}
+	retry := conf.Retry
+	if retry.CircuitBreakerTimeout == 0 {
+		retry.CircuitBreakerTimeout = DEFAULT_CIRCUIT_BREAKER_TIMEOUT
+	}
+	tags := statTags("output", name, conf.Alias)
 	ro := &RunningOutput{
 		Name:              name,
-		buffer:            NewBuffer(name, bufferLimit),
 		BatchReady:        make(chan time.Time, 1),
 		Output:            output,
 		Config:            conf,
@@ -79,23 +138,98 @@ func NewRunningOutput(
 		MetricsFiltered: selfstat.Register(
 			"write",
 			"metrics_filtered",
-			map[string]string{"output": name},
+			tags,
+		),
+		MetricsExpired: selfstat.Register(
+			"write",
+			"metrics_expired",
+			tags,
 		),
 		WriteTime: selfstat.RegisterTiming(
 			"write",
 			"write_time_ns",
-			map[string]string{"output": name},
+			tags,
 		),
+		WriteErrors: selfstat.Register(
+			"write",
+			"errors",
+			tags,
+		),
+		CircuitState: selfstat.Register(
+			"write",
+			"circuit_state",
+			tags,
+		),
+		retry: retryPolicy{config: retry},
+	}
+	setLogName(output, ro.LogName())
+	setAlias(output, conf.Alias)
+
+	// The disk buffer is opened by Init, when the output is started.
+	if conf.BufferDirectory == "" {
+		ro.buffer = NewBuffer(name, conf.Alias, bufferLimit)
 	}
 
 	return ro
 }
 
+// Init opens the disk buffer if a buffer directory is configured, it must be
+// called before any metrics are added to the output.
+//
+// The stats of the output are shared with any other output with the same
+// name and alias, such as the unused outputs of a reloaded config, so they
+// are only set here, when the output is started.
+func (ro *RunningOutput) Init() error {
+	ro.CircuitState.Set(int64(ro.retry.state))
+	if buffer, ok := ro.buffer.(*Buffer); ok {
+		buffer.setStats()
+	}
+	if ro.buffer != nil {
+		return nil
+	}
+
+	buffer, err := NewDiskBuffer(ro.Name, ro.Config.Alias, ro.Config.BufferDirectory,
+		ro.Config.BufferDiskLimit, ro.Config.BufferFsync)
+	if err != nil {
+		return fmt.Errorf("opening buffer directory %q: %v", ro.Config.BufferDirectory, err)
+	}
+	ro.buffer = buffer
+	return nil
+}
+
+// Connect connects the output.
+func (ro *RunningOutput) Connect() error {
+	err := ro.Output.Connect()
+	if err != nil {
+		return err
+	}
+	ro.connected = true
+	return nil
+}
+
+// LogName returns the name of the output including its alias.
+func (ro *RunningOutput) LogName() string {
+	return logName("outputs", ro.Name, ro.Config.Alias)
+}
+
 func (ro *RunningOutput) metricFiltered(metric telegraf.Metric) {
 	ro.MetricsFiltered.Incr(1)
 	metric.Drop()
 }
 
+// Routed returns true if metrics with the route are sent to the output.
+func (ro *RunningOutput) Routed(route string) bool {
+	if len(ro.Config.Routes) == 0 {
+		return true
+	}
+	for _, r := range ro.Config.Routes {
+		if r == route {
+			return true
+		}
+	}
+	return false
+}
+
 // AddMetric adds a metric to the output.
 //
 // Takes ownership of metric
@@ -133,6 +267,24 @@ func (ro *RunningOutput) AddMetric(metric telegraf.Metric) {
 // Write writes all metrics to the output, stopping when all have been sent on
 // or error.
 func (ro *RunningOutput) Write() error {
+	return ro.writeAll(false)
+}
+
+// WriteFinal writes all metrics to the output before it is stopped.  The
+// write is attempted even while backing off from failed writes or while the
+// circuit breaker is open, as there is no later flush.
+func (ro *RunningOutput) WriteFinal() error {
+	err := ro.writeAll(true)
+	if err != nil {
+		if _, ok := ro.buffer.(*DiskBuffer); !ok {
+			log.Printf("E! [%s] %d metrics lost, the final write failed",
+				ro.LogName(), ro.buffer.Len())
+		}
+	}
+	return err
+}
+
+func (ro *RunningOutput) writeAll(force bool) error {
 	if output, ok := ro.Output.(telegraf.AggregatingOutput); ok {
 		ro.aggMutex.Lock()
 		metrics := output.Push()
@@ -148,6 +300,10 @@ func (ro *RunningOutput) Write() error {
 	nBuffer := ro.buffer.Len()
 	nBatches := nBuffer/ro.MetricBatchSize + 1
 	for i := 0; i < nBatches; i++ {
+		if !force && !ro.allowWrite() {
+			return nil
+		}
+
 		batch := ro.buffer.Batch(ro.MetricBatchSize)
 		if len(batch) == 0 {
 			break
@@ -155,7 +311,7 @@ func (ro *RunningOutput) Write() error {
 
 		err := ro.write(batch)
 		if err != nil {
-			ro.buffer.Reject(batch)
+			ro.failBatch(batch)
 			return err
 		}
 		ro.buffer.Accept(batch)
@@ -165,6 +321,10 @@ func (ro *RunningOutput) Write() error {
 
 // WriteBatch writes a single batch of metrics to the output.
 func (ro *RunningOutput) WriteBatch() error {
+	if !ro.allowWrite() {
+		return nil
+	}
+
 	batch := ro.buffer.Batch(ro.MetricBatchSize)
 	if len(batch) == 0 {
 		return nil
@@ -172,7 +332,7 @@ func (ro *RunningOutput) WriteBatch() error {
 
 	err := ro.write(batch)
 	if err != nil {
-		ro.buffer.Reject(batch)
+		ro.failBatch(batch)
 		return err
 	}
 	ro.buffer.Accept(batch)
@@ -180,10 +340,76 @@ func (ro *RunningOutput) WriteBatch() error {
 	return nil
 }
 
+// allowWrite returns true if the retry policy allows a write, writes are
+// deferred while backing off from failed writes or while the circuit breaker
+// is open.  Metrics older than the max retry age are dropped while writes are
+// deferred.
+func (ro *RunningOutput) allowWrite() bool {
+	ro.statusMu.Lock()
+	state := ro.retry.state
+	allowed := ro.retry.allow(time.Now())
+	if !allowed {
+		log.Printf("D! [%s] write deferred, retrying after %d failed writes",
+			ro.LogName(), ro.retry.failures)
+	} else if ro.retry.state != state {
+		log.Printf("I! [%s] circuit breaker half-open, trying a write", ro.LogName())
+		ro.setCircuitState(ro.retry.state)
+	}
+	ro.statusMu.Unlock()
+
+	if !allowed {
+		ro.dropExpired()
+	}
+	return allowed
+}
+
+// failBatch returns the batch to the buffer after a failed write and drops
+// the metrics older than the max retry age.
+func (ro *RunningOutput) failBatch(batch []telegraf.Metric) {
+	ro.buffer.Reject(batch)
+	ro.dropExpired()
+}
+
+// dropExpired drops the metrics older than the max retry age, by the time of
+// the metrics.  It is called while writes are failing.
+func (ro *RunningOutput) dropExpired() {
+	maxAge := ro.Config.Retry.MaxAge
+	if maxAge <= 0 {
+		return
+	}
+
+	expired := ro.buffer.Expire(time.Now().Add(-maxAge))
+	if expired > 0 {
+		log.Printf("W! [%s] dropped %d metrics older than the max retry age of %s",
+			ro.LogName(), expired, maxAge)
+		ro.MetricsExpired.Incr(int64(expired))
+	}
+}
+
+// setCircuitState updates the circuit state statistic, must be called with
+// the status lock held.
+func (ro *RunningOutput) setCircuitState(state CircuitState) {
+	ro.status.CircuitState = state
+	ro.CircuitState.Set(int64(state))
+}
+
+// Close closes the output if it was connected and the buffer if it was
+// opened.
 func (ro *RunningOutput) Close() {
-	err := ro.Output.Close()
+	if ro.connected {
+		err := ro.Output.Close()
+		if err != nil {
+			log.Printf("E! [%s] Error closing output: %v", ro.LogName(), err)
+		}
+		ro.connected = false
+	}
+
+	if ro.buffer == nil {
+		return
+	}
+	err := ro.buffer.Close()
 	if err != nil {
-		log.Printf("E! [outputs.%s] Error closing output: %v", ro.Name, err)
+		log.Printf("E! [%s] Error closing buffer: %v", ro.LogName(), err)
 	}
 }
 
@@ -193,15 +419,51 @@ func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 	elapsed := time.Since(start)
 	ro.WriteTime.Incr(elapsed.Nanoseconds())
 
+	ro.statusMu.Lock()
+	state := ro.retry.state
+	if err == nil {
+		ro.status.LastWrite = time.Now()
+		ro.status.ConsecutiveFailures = 0
+		ro.retry.success()
+	} else {
+		ro.WriteErrors.Incr(1)
+		ro.status.LastError = err.Error()
+		ro.status.LastErrorTime = time.Now()
+		ro.status.ConsecutiveFailures++
+		ro.retry.failure(time.Now())
+	}
+	if ro.retry.state != state {
+		log.Printf("I! [%s] circuit breaker %s", ro.LogName(), ro.retry.state)
+		ro.setCircuitState(ro.retry.state)
+	}
+	ro.statusMu.Unlock()
+
 	if err == nil {
-		log.Printf("D! [outputs.%s] wrote batch of %d metrics in %s\n",
-			ro.Name, len(metrics), elapsed)
+		log.Printf("D! [%s] wrote batch of %d metrics in %s\n",
+			ro.LogName(), len(metrics), elapsed)
 	}
 	return err
 }
 
+// Status returns the state of the buffer and the outcome of the recent
+// writes.
+func (ro *RunningOutput) Status() OutputStatus {
+	ro.statusMu.Lock()
+	status := ro.status
+	ro.statusMu.Unlock()
+
+	status.BufferSize = ro.buffer.Len()
+	status.BufferFill = ro.buffer.Fill()
+	return status
+}
+
 func (ro *RunningOutput) LogBufferStatus() {
 	nBuffer := ro.buffer.Len()
-	log.Printf("D! [outputs.%s] buffer fullness: %d / %d metrics. ",
-		ro.Name, nBuffer, ro.MetricBufferLimit)
+	if _, ok := ro.buffer.(*DiskBuffer); ok {
+		log.Printf("D! [%s] buffer fullness: %d metrics on disk. ",
+			ro.LogName(), nBuffer)
+		return
+	}
+	log.Printf("D! [%s] buffer fullness: %d / %d metrics. ",
+		ro.LogName(), nBuffer, ro.MetricBufferLimit)
 }
@@ -2,8 +2,11 @@ package models
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/testutil"
@@ -107,6 +110,34 @@ func TestRunningOutput_DropFilter(t *testing.T) {
 	assert.Len(t, m.Metrics(), 8)
 }
 
+func TestRunningOutput_Routed(t *testing.T) {
+	ro := NewRunningOutput("test", &mockOutput{}, &OutputConfig{}, 1000, 10000)
+	assert.True(t, ro.Routed(""))
+	assert.True(t, ro.Routed("a"))
+
+	ro = NewRunningOutput("test", &mockOutput{}, &OutputConfig{
+		Routes: []string{"a", "b"},
+	}, 1000, 10000)
+	assert.False(t, ro.Routed(""))
+	assert.True(t, ro.Routed("a"))
+	assert.True(t, ro.Routed("b"))
+	assert.False(t, ro.Routed("c"))
+}
+
+func TestRunningOutputAlias(t *testing.T) {
+	ro := NewRunningOutput("influxdb", &mockOutput{}, &OutputConfig{
+		Name:  "influxdb",
+		Alias: "primary",
+	}, 1000, 10000)
+
+	require.Equal(t, "outputs.influxdb::primary", ro.LogName())
+	require.Equal(t, map[string]string{"output": "influxdb", "alias": "primary"}, ro.WriteTime.Tags())
+
+	buffer, ok := ro.buffer.(*Buffer)
+	require.True(t, ok)
+	require.Equal(t, map[string]string{"output": "influxdb", "alias": "primary"}, buffer.BufferSize.Tags())
+}
+
 // Test that NameDrop filters without a match do nothing.
 func TestRunningOutput_PassFilter(t *testing.T) {
 	conf := &OutputConfig{
@@ -238,6 +269,42 @@ func TestRunningOutputDefault(t *testing.T) {
 	assert.Len(t, m.Metrics(), 10)
 }
 
+func TestRunningOutputDiskBuffer(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	conf := &OutputConfig{
+		Filter:          Filter{},
+		BufferDirectory: dir,
+	}
+
+	m := &mockOutput{}
+	ro := NewRunningOutput("test", m, conf, 1000, 10000)
+	require.NoError(t, ro.Init())
+	defer ro.Close()
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 5)
+}
+
+func TestRunningOutputDiskBufferError(t *testing.T) {
+	file, err := ioutil.TempFile("", "telegraf-buffer")
+	require.NoError(t, err)
+	file.Close()
+	defer os.Remove(file.Name())
+
+	conf := &OutputConfig{
+		Filter:          Filter{},
+		BufferDirectory: file.Name(),
+	}
+
+	ro := NewRunningOutput("test", &mockOutput{}, conf, 1000, 10000)
+	require.Error(t, ro.Init())
+}
+
 func TestRunningOutputWriteFail(t *testing.T) {
 	conf := &OutputConfig{
 		Filter: Filter{},
@@ -270,6 +337,233 @@ func TestRunningOutputWriteFail(t *testing.T) {
 	assert.Len(t, m.Metrics(), 10)
 }
 
+func TestRunningOutputStatus(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 4, 12)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	status := ro.Status()
+	require.Equal(t, 5, status.BufferSize)
+	require.InDelta(t, 5.0/12.0, status.BufferFill, 0.001)
+	require.True(t, status.LastWrite.IsZero())
+	require.Equal(t, 0, status.ConsecutiveFailures)
+
+	require.Error(t, ro.Write())
+	require.Error(t, ro.Write())
+
+	status = ro.Status()
+	require.Equal(t, 2, status.ConsecutiveFailures)
+	require.Equal(t, "Failed Write!", status.LastError)
+	require.False(t, status.LastErrorTime.IsZero())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+
+	status = ro.Status()
+	require.Equal(t, 0, status.BufferSize)
+	require.Equal(t, 0, status.ConsecutiveFailures)
+	require.False(t, status.LastWrite.IsZero())
+	require.Equal(t, "Failed Write!", status.LastError)
+}
+
+func TestRunningOutputRetryBackoff(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Retry: RetryConfig{
+			InitialInterval: time.Hour,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 4, 12)
+	ro.WriteErrors.Set(0)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	require.Equal(t, int64(1), ro.WriteErrors.Get())
+
+	// The write is deferred until the backoff has elapsed.
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 0)
+	require.Equal(t, 5, ro.Status().BufferSize)
+
+	ro.statusMu.Lock()
+	ro.retry.nextAttempt = time.Now()
+	ro.statusMu.Unlock()
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 5)
+}
+
+func TestRunningOutputRetryMaxAge(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Retry: RetryConfig{
+			MaxAge: time.Hour,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 4, 12)
+	ro.MetricsExpired.Set(0)
+
+	// All metrics older than the max age are dropped.
+	for _, metric := range append(first5, next5...) {
+		ro.AddMetric(metric)
+	}
+	ro.AddMetric(testutil.TestMetric(101, "metric11"))
+	ro.AddMetric(testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{"value": 42},
+		time.Now(),
+	))
+	require.Error(t, ro.Write())
+	require.Equal(t, 1, ro.Status().BufferSize)
+	require.Equal(t, int64(11), ro.MetricsExpired.Get())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 1)
+}
+
+func TestRunningOutputRetryMaxAgeCircuitOpen(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Retry: RetryConfig{
+			MaxAge:                 time.Hour,
+			CircuitBreakerFailures: 1,
+			CircuitBreakerTimeout:  time.Hour,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 4, 12)
+	ro.MetricsExpired.Set(0)
+
+	ro.AddMetric(testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{"value": 42},
+		time.Now().Add(-time.Minute),
+	))
+	require.Error(t, ro.Write())
+	require.Equal(t, CircuitOpen, ro.Status().CircuitState)
+	require.Equal(t, 1, ro.Status().BufferSize)
+
+	// The metrics expire while no writes are attempted.
+	conf.Retry.MaxAge = time.Second
+	require.NoError(t, ro.Write())
+	require.Equal(t, 0, ro.Status().BufferSize)
+	require.Equal(t, int64(1), ro.MetricsExpired.Get())
+}
+
+func TestRunningOutputWriteFinal(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Retry: RetryConfig{
+			CircuitBreakerFailures: 1,
+			CircuitBreakerTimeout:  time.Hour,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 4, 12)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	require.Equal(t, CircuitOpen, ro.Status().CircuitState)
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 0)
+
+	require.NoError(t, ro.WriteFinal())
+	require.Len(t, m.Metrics(), 5)
+}
+
+func TestRunningOutputCircuitBreaker(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Retry: RetryConfig{
+			CircuitBreakerFailures: 2,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 4, 12)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	require.Equal(t, CircuitClosed, ro.Status().CircuitState)
+	require.Error(t, ro.Write())
+	require.Equal(t, CircuitOpen, ro.Status().CircuitState)
+	require.Equal(t, int64(CircuitOpen), ro.CircuitState.Get())
+
+	// No writes are attempted while the circuit is open.
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 0)
+
+	ro.statusMu.Lock()
+	ro.retry.openedAt = time.Now().Add(-DEFAULT_CIRCUIT_BREAKER_TIMEOUT)
+	ro.statusMu.Unlock()
+	require.NoError(t, ro.Write())
+	require.Len(t, m.Metrics(), 5)
+	require.Equal(t, CircuitClosed, ro.Status().CircuitState)
+}
+
+func TestRunningOutputStatsNotReset(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Alias:  "stats",
+		Retry: RetryConfig{
+			CircuitBreakerFailures: 1,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 4, 12)
+	require.NoError(t, ro.Init())
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	require.Equal(t, int64(CircuitOpen), ro.CircuitState.Get())
+	require.Equal(t, int64(5), ro.buffer.(*Buffer).BufferSize.Get())
+
+	// An output built with the same name and alias, as by a config reload,
+	// does not change the stats until it is started.
+	unused := NewRunningOutput("test", &mockOutput{}, conf, 4, 24)
+	require.Equal(t, int64(CircuitOpen), ro.CircuitState.Get())
+	require.Equal(t, int64(5), ro.buffer.(*Buffer).BufferSize.Get())
+	require.Equal(t, int64(12), ro.buffer.(*Buffer).BufferLimit.Get())
+
+	require.NoError(t, unused.Init())
+	require.Equal(t, int64(CircuitClosed), ro.CircuitState.Get())
+	require.Equal(t, int64(0), ro.buffer.(*Buffer).BufferSize.Get())
+	require.Equal(t, int64(24), ro.buffer.(*Buffer).BufferLimit.Get())
+}
+
 // Verify that the order of points is preserved during a write failure.
 func TestRunningOutputWriteFailOrder(t *testing.T) {
 	conf := &OutputConfig{
@@ -20,11 +20,41 @@ func (rp RunningProcessors) Len() int           { return len(rp) }
 func (rp RunningProcessors) Swap(i, j int)      { rp[i], rp[j] = rp[j], rp[i] }
 func (rp RunningProcessors) Less(i, j int) bool { return rp[i].Config.Order < rp[j].Config.Order }
 
+func NewRunningProcessor(
+	name string,
+	processor telegraf.Processor,
+	config *ProcessorConfig,
+) *RunningProcessor {
+	rp := &RunningProcessor{
+		Name:      name,
+		Processor: processor,
+		Config:    config,
+	}
+	setLogName(processor, rp.LogName())
+	setAlias(processor, config.Alias)
+	return rp
+}
+
 // FilterConfig containing a name and filter
 type ProcessorConfig struct {
-	Name   string
-	Order  int64
-	Filter Filter
+	Name     string
+	Alias    string
+	LogLevel string
+	Order    int64
+	Filter   Filter
+
+	// Route is set on each metric emitted for the metrics selected by the
+	// filter, see RouteTag.
+	Route string
+
+	// Hash identifies the plugin settings, it is used to detect changes when
+	// the configuration is reloaded.
+	Hash string
+}
+
+// LogName returns the name of the processor including its alias.
+func (rp *RunningProcessor) LogName() string {
+	return logName("processors", rp.Config.Name, rp.Config.Alias)
 }
 
 func (rp *RunningProcessor) metricFiltered(metric telegraf.Metric) {
@@ -62,7 +92,10 @@ func (rp *RunningProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
 
 		// This metric should pass through the filter, so call the filter Apply
 		// function and append results to the output slice.
-		ret = append(ret, rp.Processor.Apply(metric)...)
+		for _, m := range rp.Processor.Apply(metric) {
+			setRoute(m, rp.Config.Route)
+			ret = append(ret, m)
+		}
 	}
 
 	return ret
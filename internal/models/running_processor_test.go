@@ -164,6 +164,33 @@ func TestRunningProcessor_Apply(t *testing.T) {
 	}
 }
 
+func TestRunningProcessor_Route(t *testing.T) {
+	rp := &RunningProcessor{
+		Processor: TagProcessor("apply", "true"),
+		Config: &ProcessorConfig{
+			Filter: Filter{
+				NamePass: []string{"cpu"},
+			},
+			Route: "metrics",
+		},
+	}
+	require.NoError(t, rp.Config.Filter.Compile())
+
+	cpu := testutil.MustMetric("cpu", map[string]string{},
+		map[string]interface{}{"value": 1}, time.Unix(0, 0))
+	mem := testutil.MustMetric("mem", map[string]string{},
+		map[string]interface{}{"value": 1}, time.Unix(0, 0))
+
+	actual := rp.Apply(cpu, mem)
+	require.Len(t, actual, 2)
+
+	route, ok := actual[0].GetTag(RouteTag)
+	require.True(t, ok)
+	require.Equal(t, "metrics", route)
+
+	require.False(t, actual[1].HasTag(RouteTag))
+}
+
 func TestRunningProcessor_Order(t *testing.T) {
 	rp1 := &RunningProcessor{
 		Config: &ProcessorConfig{
@@ -0,0 +1,327 @@
+// Package process runs a long-lived child process, restarting it when it
+// exits, for plugins that communicate with an external program.
+package process
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRestartDelay limits the backoff between restarts of a process
+	// that keeps exiting.
+	maxRestartDelay = 5 * time.Minute
+
+	// defaultMaxLineSize is the default maximum length of the lines read by
+	// ReadLines.
+	defaultMaxLineSize = 16 * 1024 * 1024
+)
+
+// ErrNotRunning is returned when the process is not currently running.
+var ErrNotRunning = errors.New("process is not running")
+
+// Process is a child process that is restarted when it exits.
+type Process struct {
+	// ReadStdoutFn is called with the stdout of each run of the process,
+	// it should read until EOF.
+	ReadStdoutFn func(io.Reader)
+
+	// ReadStderrFn is called with the stderr of each run of the process,
+	// it should read until EOF.  By default each line is logged as an
+	// error.
+	ReadStderrFn func(io.Reader)
+
+	// RestartDelay is the initial delay before a process that exited is
+	// started again.  The delay doubles each time the process exits soon
+	// after starting.
+	RestartDelay time.Duration
+
+	// StopTimeout is how long Stop waits for the process to exit after its
+	// stdin is closed, before it is killed.
+	StopTimeout time.Duration
+
+	// MaxLineSize is the maximum length of the lines read by ReadLines,
+	// longer lines are skipped.
+	MaxLineSize int
+
+	// LogName is the plugin name used in log messages.
+	LogName string
+
+	name string
+	args []string
+
+	// writeMu serializes the writes to stdin, it is not held by Stop so that
+	// a write blocked on a process that does not read its stdin does not
+	// prevent the process from being stopped.
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	readers *sync.WaitGroup
+	started time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates a Process for the command and its arguments.
+func New(command []string) (*Process, error) {
+	if len(command) == 0 {
+		return nil, errors.New("no command")
+	}
+
+	p := &Process{
+		RestartDelay: 5 * time.Second,
+		StopTimeout:  5 * time.Second,
+		MaxLineSize:  defaultMaxLineSize,
+		name:         command[0],
+		args:         command[1:],
+	}
+	p.ReadStderrFn = p.logStderr
+	return p, nil
+}
+
+// Start starts the process.  If the first start fails an error is returned,
+// after that the process is restarted in the background until Stop is
+// called.
+func (p *Process) Start() error {
+	if err := p.cmdStart(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+		p.cmdLoop(ctx)
+	}()
+
+	return nil
+}
+
+// Stop closes the stdin of the process, giving it a chance to exit cleanly,
+// and kills it if it is still running after a timeout.  Stop waits until
+// the output of the process has been read.
+func (p *Process) Stop() {
+	if p.cancel == nil {
+		return
+	}
+	p.cancel()
+
+	p.closeStdin()
+
+	select {
+	case <-p.done:
+	case <-time.After(p.StopTimeout):
+		log.Printf("W! [%s] Process %s did not exit, killing", p.LogName, p.name)
+		p.mu.Lock()
+		if p.cmd != nil && p.cmd.Process != nil {
+			p.cmd.Process.Kill()
+		}
+		p.mu.Unlock()
+		<-p.done
+	}
+}
+
+func (p *Process) closeStdin() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stdin != nil {
+		p.stdin.Close()
+	}
+}
+
+// Write writes to the stdin of the running process.  A write blocked on the
+// process is interrupted by Stop.
+func (p *Process) Write(b []byte) (int, error) {
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+
+	p.mu.Lock()
+	stdin := p.stdin
+	p.mu.Unlock()
+
+	if stdin == nil {
+		return 0, ErrNotRunning
+	}
+	return stdin.Write(b)
+}
+
+// Signal sends a signal to the running process.
+func (p *Process) Signal(sig os.Signal) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cmd == nil || p.cmd.Process == nil {
+		return ErrNotRunning
+	}
+	return p.cmd.Process.Signal(sig)
+}
+
+func (p *Process) cmdStart() error {
+	cmd := exec.Command(p.name, p.args...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("error opening stdin pipe: %v", err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("error opening stdout pipe: %v", err)
+	}
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("error opening stderr pipe: %v", err)
+	}
+
+	log.Printf("D! [%s] Starting process: %s %s", p.LogName, p.name, p.args)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error starting process: %v", err)
+	}
+
+	// Wait must not be called before the output has been read, cmdWait
+	// waits for the readers first.
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		p.ReadStdoutFn(stdout)
+	}()
+	go func() {
+		defer wg.Done()
+		p.ReadStderrFn(stderr)
+	}()
+
+	p.mu.Lock()
+	p.cmd = cmd
+	p.stdin = stdin
+	p.readers = &wg
+	p.started = time.Now()
+	p.mu.Unlock()
+	return nil
+}
+
+// cmdWait waits for the readers to finish and the process to exit.
+func (p *Process) cmdWait() error {
+	p.mu.Lock()
+	cmd := p.cmd
+	readers := p.readers
+	p.mu.Unlock()
+
+	readers.Wait()
+	err := cmd.Wait()
+
+	p.mu.Lock()
+	p.stdin = nil
+	p.mu.Unlock()
+	return err
+}
+
+// cmdLoop restarts the process each time it exits, until the context is
+// done.
+func (p *Process) cmdLoop(ctx context.Context) {
+	delay := p.RestartDelay
+	for {
+		err := p.cmdWait()
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			log.Printf("E! [%s] Process %s exited: %v", p.LogName, p.name, err)
+		} else {
+			log.Printf("E! [%s] Process %s exited", p.LogName, p.name)
+		}
+
+		// Reset the backoff if the process ran for a while.
+		p.mu.Lock()
+		started := p.started
+		p.mu.Unlock()
+		if time.Since(started) > maxRestartDelay {
+			delay = p.RestartDelay
+		}
+
+		for {
+			log.Printf("I! [%s] Restarting in %s...", p.LogName, delay)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+
+			delay *= 2
+			if delay > maxRestartDelay {
+				delay = maxRestartDelay
+			}
+
+			err := p.cmdStart()
+			if err == nil {
+				break
+			}
+			log.Printf("E! [%s] %v", p.LogName, err)
+		}
+
+		// Stop may have been called while the process was starting.
+		if ctx.Err() != nil {
+			p.closeStdin()
+		}
+	}
+}
+
+// ReadLines calls fn with each line read from r, without the line ending,
+// until EOF.  The line is only valid until fn returns.  Lines longer than
+// MaxLineSize are logged and skipped, the reading goes on so that the
+// process is not blocked writing its output.
+func (p *Process) ReadLines(r io.Reader, fn func(line []byte)) {
+	reader := bufio.NewReader(r)
+	var line []byte
+	var skip bool
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("E! [%s] Error reading output: %v", p.LogName, err)
+			}
+			return
+		}
+
+		if !skip && len(line)+len(chunk) > p.MaxLineSize {
+			log.Printf("E! [%s] Skipping line longer than %d bytes", p.LogName, p.MaxLineSize)
+			skip = true
+		}
+		if !skip {
+			line = append(line, chunk...)
+		}
+		if isPrefix {
+			continue
+		}
+
+		if !skip {
+			fn(line)
+		}
+		line = line[:0]
+		skip = false
+	}
+}
+
+func (p *Process) logStderr(r io.Reader) {
+	p.ReadLines(r, func(line []byte) {
+		log.Printf("E! [%s] stderr: %s", p.LogName, line)
+	})
+}
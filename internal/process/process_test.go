@@ -0,0 +1,151 @@
+// +build !windows
+
+package process
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// lineCollector records the lines read from the process.
+type lineCollector struct {
+	sync.Mutex
+	lines []string
+	added chan struct{}
+}
+
+func newLineCollector() *lineCollector {
+	return &lineCollector{added: make(chan struct{}, 100)}
+}
+
+func (c *lineCollector) read(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		c.Lock()
+		c.lines = append(c.lines, scanner.Text())
+		c.Unlock()
+		c.added <- struct{}{}
+	}
+}
+
+func (c *lineCollector) wait(t *testing.T, n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-c.added:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for output")
+		}
+	}
+}
+
+func TestProcess_Write(t *testing.T) {
+	p, err := New([]string{"cat"})
+	require.NoError(t, err)
+
+	c := newLineCollector()
+	p.ReadStdoutFn = c.read
+
+	require.NoError(t, p.Start())
+
+	_, err = p.Write([]byte("hello\nworld\n"))
+	require.NoError(t, err)
+	c.wait(t, 2)
+
+	p.Stop()
+	require.Equal(t, []string{"hello", "world"}, c.lines)
+
+	_, err = p.Write([]byte("late\n"))
+	require.Error(t, err)
+}
+
+func TestProcess_StopBlockedWrite(t *testing.T) {
+	// The process does not read its stdin.
+	p, err := New([]string{"sleep", "30"})
+	require.NoError(t, err)
+	p.ReadStdoutFn = func(io.Reader) {}
+	p.StopTimeout = 100 * time.Millisecond
+
+	require.NoError(t, p.Start())
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := p.Write(make([]byte, 1024*1024))
+		written <- err
+	}()
+
+	stopped := make(chan struct{})
+	go func() {
+		p.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the process to stop")
+	}
+	require.Error(t, <-written)
+}
+
+func TestProcess_ReadLinesTooLong(t *testing.T) {
+	p, err := New([]string{"sh", "-c", `echo short; head -c 1000 /dev/zero | tr '\0' x; echo; echo last`})
+	require.NoError(t, err)
+	p.MaxLineSize = 100
+
+	var lines []string
+	done := make(chan struct{})
+	p.ReadStdoutFn = func(r io.Reader) {
+		p.ReadLines(r, func(line []byte) {
+			lines = append(lines, string(line))
+		})
+		close(done)
+	}
+
+	require.NoError(t, p.Start())
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for output")
+	}
+	p.Stop()
+
+	require.Equal(t, []string{"short", "last"}, lines)
+}
+
+func TestProcess_Restart(t *testing.T) {
+	p, err := New([]string{"echo", "started"})
+	require.NoError(t, err)
+
+	c := newLineCollector()
+	p.ReadStdoutFn = c.read
+	p.RestartDelay = time.Millisecond
+
+	require.NoError(t, p.Start())
+	c.wait(t, 3)
+	p.Stop()
+
+	c.Lock()
+	defer c.Unlock()
+	for _, line := range c.lines {
+		require.Equal(t, "started", line)
+	}
+}
+
+func TestProcess_StartError(t *testing.T) {
+	p, err := New([]string{"/does/not/exist"})
+	require.NoError(t, err)
+	p.ReadStdoutFn = func(io.Reader) {}
+
+	require.Error(t, p.Start())
+	p.Stop()
+}
+
+func TestProcess_NoCommand(t *testing.T) {
+	_, err := New(nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,78 @@
+// Package promconv converts the names and values of Telegraf metrics to the
+// ones of Prometheus, the same way for the outputs and serializers.
+package promconv
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+)
+
+var (
+	invalidNameCharRE = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+	validLabelNameRE  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// MetricName returns the name of the metric of the field.  The "value" field,
+// and the "counter" and "gauge" fields of counters and gauges, are named after
+// the measurement; this supports metrics from the prometheus input.
+func MetricName(m telegraf.Metric, key string) string {
+	switch {
+	case key == "value",
+		m.Type() == telegraf.Counter && key == "counter",
+		m.Type() == telegraf.Gauge && key == "gauge":
+		return Sanitize(m.Name())
+	}
+	return Sanitize(m.Name() + "_" + key)
+}
+
+// Labels returns the label values of the tags, and of the string fields if
+// stringAsLabel is set, by label name.  Tags and fields with invalid or
+// reserved label names are left out.
+func Labels(m telegraf.Metric, stringAsLabel bool) map[string]string {
+	values := make(map[string]string, len(m.TagList()))
+	for _, tag := range m.TagList() {
+		values[Sanitize(tag.Key)] = tag.Value
+	}
+	if stringAsLabel {
+		for _, field := range m.FieldList() {
+			if v, ok := field.Value.(string); ok {
+				values[Sanitize(field.Key)] = v
+			}
+		}
+	}
+
+	for name := range values {
+		if !validLabelNameRE.MatchString(name) || strings.HasPrefix(name, "__") {
+			delete(values, name)
+		}
+	}
+	return values
+}
+
+// Sanitize replaces the characters not allowed in metric names with
+// underscores.
+func Sanitize(name string) string {
+	return invalidNameCharRE.ReplaceAllString(name, "_")
+}
+
+// IsValidMetricName returns true if the sanitized name is a valid metric
+// name, it must not be empty or start with a digit.
+func IsValidMetricName(name string) bool {
+	return name != "" && (name[0] < '0' || name[0] > '9')
+}
+
+// ToFloat returns the value of a numeric field, it returns false for other
+// field types.
+func ToFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
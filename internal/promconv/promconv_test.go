@@ -0,0 +1,69 @@
+package promconv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricName(t *testing.T) {
+	tests := []struct {
+		name     string
+		typ      telegraf.ValueType
+		key      string
+		expected string
+	}{
+		{"cpu", telegraf.Untyped, "time_idle", "cpu_time_idle"},
+		{"cpu", telegraf.Untyped, "value", "cpu"},
+		{"http-requests", telegraf.Counter, "counter", "http_requests"},
+		{"temp", telegraf.Gauge, "gauge", "temp"},
+		{"temp", telegraf.Counter, "gauge", "temp_gauge"},
+	}
+	for _, tt := range tests {
+		m := testutil.MustMetric(tt.name, nil, map[string]interface{}{tt.key: 1.0}, time.Unix(0, 0), tt.typ)
+		require.Equal(t, tt.expected, MetricName(m, tt.key))
+	}
+}
+
+func TestLabels(t *testing.T) {
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{
+			"host.name": "example.org",
+			"0cpu":      "cpu0",
+			"__name__":  "reserved",
+		},
+		map[string]interface{}{
+			"status": "ok",
+			"value":  1.0,
+		},
+		time.Unix(0, 0),
+	)
+
+	require.Equal(t, map[string]string{"host_name": "example.org"}, Labels(m, false))
+	require.Equal(t, map[string]string{
+		"host_name": "example.org",
+		"status":    "ok",
+	}, Labels(m, true))
+}
+
+func TestIsValidMetricName(t *testing.T) {
+	require.True(t, IsValidMetricName("cpu_time_idle"))
+	require.False(t, IsValidMetricName(""))
+	require.False(t, IsValidMetricName("0cpu"))
+}
+
+func TestToFloat(t *testing.T) {
+	for _, v := range []interface{}{int64(2), uint64(2), 2.0} {
+		f, ok := ToFloat(v)
+		require.True(t, ok)
+		require.Equal(t, 2.0, f)
+	}
+	_, ok := ToFloat("2")
+	require.False(t, ok)
+	_, ok = ToFloat(true)
+	require.False(t, ok)
+}
@@ -0,0 +1,122 @@
+// Package prompb implements the messages of the Prometheus remote write
+// protocol.  Only the fields used by the protocol version 0.1.0 are supported,
+// other fields are skipped when decoding.
+package prompb
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/influxdata/telegraf/internal/protowire"
+)
+
+// WriteRequest is the body of a remote write request.
+type WriteRequest struct {
+	Timeseries []TimeSeries
+}
+
+// TimeSeries is a series and its samples, the labels must be sorted by name
+// and include the __name__ label.
+type TimeSeries struct {
+	Labels  []Label
+	Samples []Sample
+}
+
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a value and its timestamp in milliseconds since the epoch.
+type Sample struct {
+	Value     float64
+	Timestamp int64
+}
+
+// Marshal returns the protobuf encoding of the request.
+func (r *WriteRequest) Marshal() []byte {
+	var buf []byte
+	for i := range r.Timeseries {
+		buf = protowire.AppendBytes(buf, 1, r.Timeseries[i].marshal())
+	}
+	return buf
+}
+
+func (ts *TimeSeries) marshal() []byte {
+	var buf []byte
+	for _, l := range ts.Labels {
+		var label []byte
+		label = protowire.AppendBytes(label, 1, []byte(l.Name))
+		label = protowire.AppendBytes(label, 2, []byte(l.Value))
+		buf = protowire.AppendBytes(buf, 1, label)
+	}
+	for _, s := range ts.Samples {
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.Value))
+		sample = protowire.AppendTag(sample, 2, protowire.Varint)
+		sample = protowire.AppendVarint(sample, uint64(s.Timestamp))
+		buf = protowire.AppendBytes(buf, 2, sample)
+	}
+	return buf
+}
+
+// Unmarshal decodes the protobuf encoding of a request.
+func (r *WriteRequest) Unmarshal(buf []byte) error {
+	r.Timeseries = r.Timeseries[:0]
+	return protowire.Decode(buf, func(field int32, wire int, data []byte, _ uint64) error {
+		if field != 1 || wire != protowire.Bytes {
+			return nil
+		}
+		var ts TimeSeries
+		if err := ts.unmarshal(data); err != nil {
+			return fmt.Errorf("timeseries: %v", err)
+		}
+		r.Timeseries = append(r.Timeseries, ts)
+		return nil
+	})
+}
+
+func (ts *TimeSeries) unmarshal(buf []byte) error {
+	return protowire.Decode(buf, func(field int32, wire int, data []byte, _ uint64) error {
+		if wire != protowire.Bytes {
+			return nil
+		}
+		switch field {
+		case 1:
+			var l Label
+			err := protowire.Decode(data, func(field int32, wire int, data []byte, _ uint64) error {
+				if wire != protowire.Bytes {
+					return nil
+				}
+				switch field {
+				case 1:
+					l.Name = string(data)
+				case 2:
+					l.Value = string(data)
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("label: %v", err)
+			}
+			ts.Labels = append(ts.Labels, l)
+		case 2:
+			var s Sample
+			err := protowire.Decode(data, func(field int32, wire int, _ []byte, value uint64) error {
+				switch {
+				case field == 1 && wire == protowire.Fixed64:
+					s.Value = math.Float64frombits(value)
+				case field == 2 && wire == protowire.Varint:
+					s.Timestamp = int64(value)
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("sample: %v", err)
+			}
+			ts.Samples = append(ts.Samples, s)
+		}
+		return nil
+	})
+}
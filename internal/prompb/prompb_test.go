@@ -0,0 +1,81 @@
+package prompb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var upRequest = WriteRequest{
+	Timeseries: []TimeSeries{
+		{
+			Labels:  []Label{{Name: "__name__", Value: "up"}},
+			Samples: []Sample{{Value: 1, Timestamp: 1000}},
+		},
+	},
+}
+
+var upEncoded = []byte{
+	0x0a, 0x1e, // timeseries
+	0x0a, 0x0e, // label
+	0x0a, 0x08, '_', '_', 'n', 'a', 'm', 'e', '_', '_',
+	0x12, 0x02, 'u', 'p',
+	0x12, 0x0c, // sample
+	0x09, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f,
+	0x10, 0xe8, 0x07,
+}
+
+func TestMarshal(t *testing.T) {
+	require.Equal(t, upEncoded, upRequest.Marshal())
+}
+
+func TestUnmarshal(t *testing.T) {
+	var req WriteRequest
+	require.NoError(t, req.Unmarshal(upEncoded))
+	require.Equal(t, upRequest, req)
+}
+
+func TestUnmarshalSkipsUnknownFields(t *testing.T) {
+	buf := append([]byte{}, upEncoded...)
+	// metadata of the request, and a fixed32 and varint field
+	buf = append(buf, 0x1a, 0x02, 0x08, 0x01)
+	buf = append(buf, 0x25, 0x01, 0x02, 0x03, 0x04)
+	buf = append(buf, 0x28, 0x96, 0x01)
+
+	var req WriteRequest
+	require.NoError(t, req.Unmarshal(buf))
+	require.Equal(t, upRequest, req)
+}
+
+func TestRoundTrip(t *testing.T) {
+	expected := WriteRequest{
+		Timeseries: []TimeSeries{
+			{
+				Labels: []Label{
+					{Name: "__name__", Value: "cpu_usage"},
+					{Name: "host", Value: "example.org"},
+					{Name: "empty", Value: ""},
+				},
+				Samples: []Sample{
+					{Value: -1.5, Timestamp: -1000},
+					{Value: 0, Timestamp: 0},
+					{Value: 42, Timestamp: 1565980943000},
+				},
+			},
+			{
+				Labels: []Label{{Name: "__name__", Value: "up"}},
+			},
+		},
+	}
+
+	var req WriteRequest
+	require.NoError(t, req.Unmarshal(expected.Marshal()))
+	require.Equal(t, expected, req)
+}
+
+func TestUnmarshalTruncated(t *testing.T) {
+	for i := 1; i < len(upEncoded); i++ {
+		var req WriteRequest
+		require.Error(t, req.Unmarshal(upEncoded[:i]), "length %d", i)
+	}
+}
@@ -0,0 +1,106 @@
+// Package protowire implements the protobuf wire format, for the messages
+// encoded and decoded without generated code.
+package protowire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Wire types of the fields.
+const (
+	Varint  = 0
+	Fixed64 = 1
+	Bytes   = 2
+	Fixed32 = 5
+)
+
+// ErrTruncated is returned for messages ending in the middle of a field.
+var ErrTruncated = errors.New("truncated message")
+
+// Decode calls fn for each field of the message, with the data of the
+// length-delimited fields or the value of the numeric fields.
+func Decode(buf []byte, fn func(field int32, wire int, data []byte, value uint64) error) error {
+	for len(buf) > 0 {
+		tag, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return ErrTruncated
+		}
+		buf = buf[n:]
+
+		field, wire := int32(tag>>3), int(tag&7)
+		var data []byte
+		var value uint64
+		if wire == Bytes {
+			length, n := binary.Uvarint(buf)
+			if n <= 0 || uint64(len(buf)-n) < length {
+				return ErrTruncated
+			}
+			data = buf[n : n+int(length)]
+			buf = buf[n+int(length):]
+		} else {
+			var err error
+			value, n, err = DecodeValue(buf, wire)
+			if err != nil {
+				return err
+			}
+			buf = buf[n:]
+		}
+
+		if err := fn(field, wire, data, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeValue decodes the numeric value of the wire type at the start of the
+// buffer, it returns the value and its encoded length.
+func DecodeValue(buf []byte, wire int) (uint64, int, error) {
+	switch wire {
+	case Varint:
+		value, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return 0, 0, ErrTruncated
+		}
+		return value, n, nil
+	case Fixed64:
+		if len(buf) < 8 {
+			return 0, 0, ErrTruncated
+		}
+		return binary.LittleEndian.Uint64(buf), 8, nil
+	case Fixed32:
+		if len(buf) < 4 {
+			return 0, 0, ErrTruncated
+		}
+		return uint64(binary.LittleEndian.Uint32(buf)), 4, nil
+	}
+	return 0, 0, fmt.Errorf("unsupported wire type %d", wire)
+}
+
+// AppendTag appends the tag of a field of the wire type.
+func AppendTag(buf []byte, field int32, wire int) []byte {
+	return AppendVarint(buf, uint64(field)<<3|uint64(wire))
+}
+
+// AppendVarint appends a varint value.
+func AppendVarint(buf []byte, v uint64) []byte {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], v)
+	return append(buf, b[:n]...)
+}
+
+// AppendFixed64 appends a fixed 64-bit value.
+func AppendFixed64(buf []byte, v uint64) []byte {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	return append(buf, b[:]...)
+}
+
+// AppendBytes appends a length-delimited field.
+func AppendBytes(buf []byte, field int32, data []byte) []byte {
+	buf = AppendTag(buf, field, Bytes)
+	buf = AppendVarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
@@ -0,0 +1,58 @@
+package protowire
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type field struct {
+	number int32
+	wire   int
+	data   string
+	value  uint64
+}
+
+func TestDecode(t *testing.T) {
+	var buf []byte
+	buf = AppendTag(buf, 1, Varint)
+	buf = AppendVarint(buf, 300)
+	buf = AppendTag(buf, 2, Fixed64)
+	buf = AppendFixed64(buf, math.Float64bits(1.5))
+	buf = AppendBytes(buf, 3, []byte("abc"))
+	buf = append(buf, 4<<3|Fixed32, 1, 0, 0, 0)
+
+	var fields []field
+	err := Decode(buf, func(number int32, wire int, data []byte, value uint64) error {
+		fields = append(fields, field{number, wire, string(data), value})
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []field{
+		{1, Varint, "", 300},
+		{2, Fixed64, "", math.Float64bits(1.5)},
+		{3, Bytes, "abc", 0},
+		{4, Fixed32, "", 1},
+	}, fields)
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		buf  []byte
+	}{
+		{"truncated tag", []byte{0x80}},
+		{"truncated varint", []byte{1 << 3, 0x80}},
+		{"truncated fixed64", []byte{1<<3 | Fixed64, 0, 0}},
+		{"truncated fixed32", []byte{1<<3 | Fixed32, 0}},
+		{"truncated bytes", []byte{1<<3 | Bytes, 4, 'a'}},
+		{"unsupported wire type", []byte{1<<3 | 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Decode(tt.buf, func(int32, int, []byte, uint64) error { return nil })
+			require.Error(t, err)
+		})
+	}
+}
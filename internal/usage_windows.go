@@ -31,8 +31,12 @@ The commands & flags are:
                                  'processors', 'aggregators' and 'inputs'
   --test                         gather metrics, print them out, and exit;
                                  processors, aggregators, and outputs are not run
+  --test-routes                  gather metrics, apply processors, print each metric
+                                 with the outputs it is routed to, and exit
   --usage <plugin>               print usage for a plugin, ie, 'telegraf --usage mysql'
   --version                      display the version and exit
+  --watch-config                 reload the configuration when files in
+                                 --config-directory change
 
   --console                      run as console application (windows only)
   --service <service>            operate on the service (windows only)
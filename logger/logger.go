@@ -1,11 +1,16 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"os"
 	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf/internal"
@@ -13,13 +18,43 @@ import (
 	"github.com/influxdata/wlog"
 )
 
+const (
+	// FormatText writes each message as a line of text.
+	FormatText = "text"
+	// FormatJSON writes each message as a JSON object on one line.
+	FormatJSON = "json"
+
+	// TargetFile logs to the logfile, or stderr if no logfile is set.
+	TargetFile = "file"
+	// TargetStderr logs to stderr.
+	TargetStderr = "stderr"
+	// TargetSyslog logs to a syslog server.
+	TargetSyslog = "syslog"
+)
+
 var prefixRegex = regexp.MustCompile("^[DIWE]!")
 
+// sourceRegex matches the level prefix and the plugin or component that
+// logged a message, such as "E! [inputs.http::api]".
+var sourceRegex = regexp.MustCompile(`^([DIWE])! \[([^\]]+)\]:?\s*`)
+
+// pluginTypes are the types of plugins, log messages from plugins are
+// prefixed with the plugin type and name.
+var pluginTypes = map[string]bool{
+	"inputs":       true,
+	"outputs":      true,
+	"processors":   true,
+	"aggregators":  true,
+	"secretstores": true,
+}
+
 // newTelegrafWriter returns a logging-wrapped writer.
 func newTelegrafWriter(w io.Writer) io.Writer {
 	return &telegrafLog{
-		writer:         wlog.NewWriter(w),
+		writer:         w,
 		internalWriter: w,
+		format:         FormatText,
+		level:          wlog.LogLevel(),
 	}
 }
 
@@ -39,24 +74,154 @@ type LogConfig struct {
 	RotationMaxSize internal.Size
 	// maximum rotated files to keep (older ones will be deleted)
 	RotationMaxArchives int
+	// format of the messages, "text" or "json"
+	Format string
+	// destination of the messages, "file", "stderr" or "syslog"
+	Target string
+	// address of the syslog server, the local syslog socket is used if empty
+	SyslogAddress string
+}
+
+// syslogWriter writes messages to syslog with a severity.
+type syslogWriter interface {
+	Debug(m string) error
+	Info(m string) error
+	Warning(m string) error
+	Err(m string) error
+	Close() error
 }
 
 type telegrafLog struct {
 	writer         io.Writer
 	internalWriter io.Writer
+	syslog         syslogWriter
+	format         string
+
+	mu           sync.RWMutex
+	level        wlog.Level
+	pluginLevels map[string]wlog.Level
+}
+
+// jsonEntry is a log message in the JSON format.
+type jsonEntry struct {
+	Time       string `json:"time"`
+	Level      string `json:"level"`
+	PluginType string `json:"plugin_type,omitempty"`
+	PluginName string `json:"plugin_name,omitempty"`
+	Alias      string `json:"alias,omitempty"`
+	Component  string `json:"component,omitempty"`
+	Message    string `json:"message"`
 }
 
 func (t *telegrafLog) Write(b []byte) (n int, err error) {
 	var line []byte
 	if !prefixRegex.Match(b) {
-		line = append([]byte(time.Now().UTC().Format(time.RFC3339)+" I! "), b...)
+		line = append([]byte("I! "), b...)
 	} else {
-		line = append([]byte(time.Now().UTC().Format(time.RFC3339)+" "), b...)
+		line = b
+	}
+
+	level := levelOf(line[0])
+	source := ""
+	if match := sourceRegex.FindSubmatch(line); match != nil {
+		source = string(match[2])
+	}
+
+	if level < t.threshold(source) {
+		return len(b), nil
+	}
+
+	now := time.Now().UTC()
+	switch t.format {
+	case FormatJSON:
+		line, err = t.formatJSON(now, line)
+		if err != nil {
+			return 0, err
+		}
+	default:
+		if t.syslog == nil {
+			line = append([]byte(now.Format(time.RFC3339)+" "), line...)
+		}
+	}
+
+	if t.syslog != nil {
+		msg := string(bytes.TrimRight(line, "\n"))
+		switch level {
+		case wlog.DEBUG:
+			err = t.syslog.Debug(msg)
+		case wlog.WARN:
+			err = t.syslog.Warning(msg)
+		case wlog.ERROR:
+			err = t.syslog.Err(msg)
+		default:
+			err = t.syslog.Info(msg)
+		}
+		if err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	_, err = t.writer.Write(line)
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// threshold returns the minimum level of the messages logged by the source.
+func (t *telegrafLog) threshold(source string) wlog.Level {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if level, ok := t.pluginLevels[source]; ok {
+		return level
+	}
+	return t.level
+}
+
+func (t *telegrafLog) formatJSON(now time.Time, line []byte) ([]byte, error) {
+	entry := jsonEntry{
+		Time:  now.Format(time.RFC3339Nano),
+		Level: levelName(levelOf(line[0])),
+	}
+
+	msg := line[3:]
+	if match := sourceRegex.FindSubmatch(line); match != nil {
+		msg = line[len(match[0]):]
+
+		source := string(match[2])
+		parts := strings.SplitN(source, ".", 2)
+		if len(parts) == 2 && pluginTypes[parts[0]] {
+			entry.PluginType = parts[0]
+			entry.PluginName = parts[1]
+			if i := strings.Index(parts[1], "::"); i >= 0 {
+				entry.PluginName = parts[1][:i]
+				entry.Alias = parts[1][i+2:]
+			}
+		} else {
+			entry.Component = source
+		}
+	}
+	entry.Message = strings.TrimSpace(string(msg))
+
+	out, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
 	}
-	return t.writer.Write(line)
+	return append(out, '\n'), nil
+}
+
+func (t *telegrafLog) setPluginLevels(levels map[string]wlog.Level) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pluginLevels = levels
 }
 
 func (t *telegrafLog) Close() error {
+	if t.syslog != nil {
+		return t.syslog.Close()
+	}
 	closer, isCloser := t.internalWriter.(io.Closer)
 	if !isCloser {
 		return errors.New("the underlying writer cannot be closed")
@@ -64,32 +229,166 @@ func (t *telegrafLog) Close() error {
 	return closer.Close()
 }
 
+var (
+	currentMu  sync.Mutex
+	currentLog *telegrafLog
+)
+
 // SetupLogging configures the logging output.
 func SetupLogging(config LogConfig) {
 	newLogWriter(config)
 }
 
+// SetPluginLevels sets the log levels of plugins that override the global
+// level, by the name the plugin uses in its log messages, such as
+// "inputs.snmp::core".  Messages that only include the plugin name, without
+// the alias, are logged at the most verbose of these levels if it is more
+// verbose than the global level.
+func SetPluginLevels(levels map[string]string) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	if currentLog == nil {
+		return
+	}
+
+	parsed := make(map[string]wlog.Level, len(levels))
+	for name, value := range levels {
+		level, err := ParseLevel(value)
+		if err != nil {
+			log.Printf("E! [logger] Invalid log level for %s: %v", name, err)
+			continue
+		}
+		parsed[name] = level
+	}
+
+	plain := make(map[string]wlog.Level)
+	for name, level := range parsed {
+		i := strings.Index(name, "::")
+		if i < 0 || level >= currentLog.level {
+			continue
+		}
+		if l, ok := plain[name[:i]]; !ok || level < l {
+			plain[name[:i]] = level
+		}
+	}
+	for name, level := range plain {
+		if _, ok := parsed[name]; !ok {
+			parsed[name] = level
+		}
+	}
+
+	currentLog.setPluginLevels(parsed)
+	setWlogLevel(currentLog.level, parsed)
+}
+
+// setWlogLevel sets the wlog level to the most verbose level in use, so
+// that plugins checking the level do not skip work for debug messages.
+func setWlogLevel(level wlog.Level, levels map[string]wlog.Level) {
+	for _, l := range levels {
+		if l < level {
+			level = l
+		}
+	}
+	wlog.SetLevel(level)
+}
+
+// ParseLevel returns the level with the name debug, info, warn or error.
+func ParseLevel(s string) (wlog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return wlog.DEBUG, nil
+	case "info":
+		return wlog.INFO, nil
+	case "warn", "warning":
+		return wlog.WARN, nil
+	case "error":
+		return wlog.ERROR, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q", s)
+	}
+}
+
+func levelOf(prefix byte) wlog.Level {
+	switch prefix {
+	case 'D':
+		return wlog.DEBUG
+	case 'W':
+		return wlog.WARN
+	case 'E':
+		return wlog.ERROR
+	default:
+		return wlog.INFO
+	}
+}
+
+func levelName(level wlog.Level) string {
+	switch level {
+	case wlog.DEBUG:
+		return "debug"
+	case wlog.WARN:
+		return "warn"
+	case wlog.ERROR:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
 func newLogWriter(config LogConfig) io.Writer {
 	log.SetFlags(0)
+	level := wlog.INFO
 	if config.Debug {
-		wlog.SetLevel(wlog.DEBUG)
+		level = wlog.DEBUG
 	}
 	if config.Quiet {
-		wlog.SetLevel(wlog.ERROR)
+		level = wlog.ERROR
 	}
+	wlog.SetLevel(level)
 
-	var writer io.Writer
-	if config.Logfile != "" {
+	format := config.Format
+	switch format {
+	case "":
+		format = FormatText
+	case FormatText, FormatJSON:
+	default:
+		log.Printf("E! Unknown log format %q, using %s", format, FormatText)
+		format = FormatText
+	}
+
+	var writer io.Writer = os.Stderr
+	var syslog syslogWriter
+	switch config.Target {
+	case "", TargetFile:
+		if config.Logfile != "" {
+			var err error
+			if writer, err = rotate.NewFileWriter(config.Logfile, config.RotationInterval.Duration, config.RotationMaxSize.Size, config.RotationMaxArchives); err != nil {
+				log.Printf("E! Unable to open %s (%s), using stderr", config.Logfile, err)
+				writer = os.Stderr
+			}
+		}
+	case TargetStderr:
+	case TargetSyslog:
 		var err error
-		if writer, err = rotate.NewFileWriter(config.Logfile, config.RotationInterval.Duration, config.RotationMaxSize.Size, config.RotationMaxArchives); err != nil {
-			log.Printf("E! Unable to open %s (%s), using stderr", config.Logfile, err)
-			writer = os.Stderr
+		if syslog, err = newSyslogWriter(config.SyslogAddress); err != nil {
+			log.Printf("E! Unable to connect to syslog (%s), using stderr", err)
+			syslog = nil
 		}
-	} else {
-		writer = os.Stderr
+	default:
+		log.Printf("E! Unknown log target %q, using stderr", config.Target)
 	}
 
-	telegrafLog := newTelegrafWriter(writer)
+	telegrafLog := &telegrafLog{
+		writer:         writer,
+		internalWriter: writer,
+		syslog:         syslog,
+		format:         format,
+		level:          level,
+	}
 	log.SetOutput(telegrafLog)
+
+	currentMu.Lock()
+	currentLog = telegrafLog
+	currentMu.Unlock()
+
 	return telegrafLog
 }
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"io/ioutil"
 	"log"
@@ -10,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/wlog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -113,6 +115,89 @@ func TestWriteToFileInRotation(t *testing.T) {
 	assert.Equal(t, 2, len(files))
 }
 
+func TestWriteJSONLogToFile(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	defer func() { os.Remove(tmpfile.Name()) }()
+	config := createBasicLogConfig(tmpfile.Name())
+	config.Format = FormatJSON
+	SetupLogging(config)
+	log.Printf("E! [inputs.http::api] Error in plugin: timeout")
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	require.NoError(t, err)
+
+	var entry map[string]string
+	require.NoError(t, json.Unmarshal(f, &entry))
+	require.NotEmpty(t, entry["time"])
+	delete(entry, "time")
+	require.Equal(t, map[string]string{
+		"level":       "error",
+		"plugin_type": "inputs",
+		"plugin_name": "http",
+		"alias":       "api",
+		"message":     "Error in plugin: timeout",
+	}, entry)
+}
+
+func TestWriteJSONLogComponent(t *testing.T) {
+	var buf bytes.Buffer
+	w := newTelegrafWriter(&buf).(*telegrafLog)
+	w.format = FormatJSON
+	w.Write([]byte("I! [agent] Starting"))
+
+	var entry map[string]string
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	require.Equal(t, "info", entry["level"])
+	require.Equal(t, "agent", entry["component"])
+	require.Equal(t, "Starting", entry["message"])
+	require.Empty(t, entry["plugin_type"])
+}
+
+func TestPluginLogLevels(t *testing.T) {
+	tmpfile, err := ioutil.TempFile("", "")
+	require.NoError(t, err)
+	defer func() { os.Remove(tmpfile.Name()) }()
+	config := createBasicLogConfig(tmpfile.Name())
+	SetupLogging(config)
+	SetPluginLevels(map[string]string{
+		"inputs.snmp::core": "debug",
+		"outputs.influxdb":  "error",
+	})
+	defer SetPluginLevels(nil)
+
+	log.Printf("D! [inputs.snmp::core] logged")
+	log.Printf("D! [inputs.snmp] logged")
+	log.Printf("D! [inputs.snmp::edge] ignored")
+	log.Printf("D! [agent] ignored")
+	log.Printf("W! [outputs.influxdb] ignored")
+	log.Printf("E! [outputs.influxdb] logged")
+
+	f, err := ioutil.ReadFile(tmpfile.Name())
+	require.NoError(t, err)
+	require.Equal(t, 3, bytes.Count(f, []byte("logged")))
+	require.NotContains(t, string(f), "ignored")
+}
+
+func TestPluginLogLevelsWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newTelegrafWriter(&buf).(*telegrafLog)
+	w.setPluginLevels(map[string]wlog.Level{"inputs.cpu": wlog.ERROR})
+	w.Write([]byte("I! [inputs.cpu] ignored"))
+	w.Write([]byte("I! [inputs.mem] logged"))
+	require.NotContains(t, buf.String(), "ignored")
+	require.Contains(t, buf.String(), "logged")
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("Warning")
+	require.NoError(t, err)
+	require.Equal(t, wlog.WARN, level)
+
+	_, err = ParseLevel("verbose")
+	require.Error(t, err)
+}
+
 func BenchmarkTelegrafLogWrite(b *testing.B) {
 	var msg = []byte("test")
 	var buf bytes.Buffer
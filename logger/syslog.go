@@ -0,0 +1,34 @@
+// +build !windows
+
+package logger
+
+import (
+	"fmt"
+	"log/syslog"
+	"net/url"
+)
+
+// newSyslogWriter connects to the syslog server at the address, such as
+// "udp://localhost:514" or "unixgram:///dev/log".  The local syslog socket is
+// used if the address is empty.
+func newSyslogWriter(address string) (syslogWriter, error) {
+	var network, raddr string
+	if address != "" {
+		u, err := url.Parse(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid syslog address %q: %v", address, err)
+		}
+
+		network = u.Scheme
+		switch network {
+		case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
+			raddr = u.Host
+		case "unix", "unixgram":
+			raddr = u.Path
+		default:
+			return nil, fmt.Errorf("unsupported syslog network %q", network)
+		}
+	}
+
+	return syslog.Dial(network, raddr, syslog.LOG_DAEMON|syslog.LOG_INFO, "telegraf")
+}
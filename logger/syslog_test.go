@@ -0,0 +1,42 @@
+// +build !windows
+
+package logger
+
+import (
+	"log"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteLogToSyslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	config := LogConfig{
+		Target:        TargetSyslog,
+		SyslogAddress: "udp://" + conn.LocalAddr().String(),
+	}
+	writer := newLogWriter(config)
+	defer writer.(*telegrafLog).Close()
+	log.Printf("W! [inputs.cpu] TEST")
+
+	buf := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+
+	// LOG_DAEMON|LOG_WARNING
+	msg := string(buf[:n])
+	require.True(t, strings.HasPrefix(msg, "<28>"), msg)
+	require.True(t, strings.HasSuffix(msg, "W! [inputs.cpu] TEST\n"), msg)
+}
+
+func TestSyslogAddressUnsupported(t *testing.T) {
+	_, err := newSyslogWriter("http://localhost:514")
+	require.Error(t, err)
+}
@@ -0,0 +1,7 @@
+package logger
+
+import "errors"
+
+func newSyslogWriter(address string) (syslogWriter, error) {
+	return nil, errors.New("syslog is not supported on windows")
+}
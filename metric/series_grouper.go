@@ -57,6 +57,29 @@ func (g *SeriesGrouper) Add(
 	return nil
 }
 
+// AddMetric adds the fields of the metric to its series.  The metric is not
+// retained, the first metric of each series and time is copied.
+func (g *SeriesGrouper) AddMetric(
+	metric telegraf.Metric,
+) error {
+	var err error
+	id := groupID(metric.Name(), metric.Tags(), metric.Time())
+	m := g.metrics[id]
+	if m == nil {
+		m, err = New(metric.Name(), metric.Tags(), metric.Fields(), metric.Time(), metric.Type())
+		if err != nil {
+			return err
+		}
+		g.metrics[id] = m
+		g.ordered = append(g.ordered, m)
+	} else {
+		for _, field := range metric.FieldList() {
+			m.AddField(field.Key, field.Value)
+		}
+	}
+	return nil
+}
+
 // Metrics returns the metrics grouped by series and time.
 func (g *SeriesGrouper) Metrics() []telegraf.Metric {
 	return g.ordered
@@ -0,0 +1,33 @@
+package metric
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeriesGrouperAddMetric(t *testing.T) {
+	now := time.Unix(0, 0)
+	g := NewSeriesGrouper()
+
+	a, err := New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"usage_time": 42}, now)
+	require.NoError(t, err)
+	b, err := New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"idle_time": 42}, now)
+	require.NoError(t, err)
+	c, err := New("cpu", map[string]string{"host": "a"}, map[string]interface{}{"idle_time": 43}, now.Add(time.Second))
+	require.NoError(t, err)
+
+	for _, m := range []telegraf.Metric{a, b, c} {
+		require.NoError(t, g.AddMetric(m))
+	}
+
+	metrics := g.Metrics()
+	require.Len(t, metrics, 2)
+	require.Equal(t, map[string]interface{}{"usage_time": int64(42), "idle_time": int64(42)}, metrics[0].Fields())
+	require.Equal(t, map[string]interface{}{"idle_time": int64(43)}, metrics[1].Fields())
+
+	// The added metrics are not modified.
+	require.Equal(t, map[string]interface{}{"usage_time": int64(42)}, a.Fields())
+}
@@ -0,0 +1,67 @@
+package merge
+
+import (
+	"log"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+const description = "Merge metrics into multifield metrics by series key"
+const sampleConfig = `
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = true
+`
+
+type Merge struct {
+	grouper *metric.SeriesGrouper
+
+	logName string
+}
+
+func (a *Merge) Description() string {
+	return description
+}
+
+// SetLogName sets the name of the plugin in log messages.
+func (a *Merge) SetLogName(name string) {
+	a.logName = name
+}
+
+func (a *Merge) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *Merge) Add(m telegraf.Metric) {
+	if err := a.grouper.AddMetric(m); err != nil {
+		log.Printf("E! [%s] Error merging metric: %v", a.logName, err)
+	}
+}
+
+func (a *Merge) Push(acc telegraf.Accumulator) {
+	// Always use nanosecond precision to avoid rounding metrics that were
+	// produced at a precision higher than the agent default.
+	acc.SetPrecision(time.Nanosecond)
+
+	for _, m := range a.grouper.Metrics() {
+		acc.AddMetric(m)
+	}
+}
+
+func (a *Merge) Reset() {
+	a.grouper = metric.NewSeriesGrouper()
+}
+
+func init() {
+	aggregators.Add("merge", func() telegraf.Aggregator {
+		return &Merge{
+			grouper: metric.NewSeriesGrouper(),
+			logName: "aggregators.merge",
+		}
+	})
+}
@@ -0,0 +1,200 @@
+package quantile
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+var sampleConfig = `
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Quantiles to output in the range [0,1]
+  # quantiles = [0.5, 0.9, 0.99]
+
+  ## Fields to compute the quantiles of, globs are supported.  The quantiles of
+  ## all numeric fields are computed if empty.
+  # fields = []
+
+  ## Relative accuracy of the quantiles, with 0.01 the values are within 1% of
+  ## the exact quantiles.  Better accuracy uses more memory.
+  # relative_accuracy = 0.01
+
+  ## Maximum number of bins of each sketch, this bounds the memory used per
+  ## field.  When the limit is reached the accuracy of the lowest values is
+  ## reduced.
+  # max_bins = 2048
+`
+
+type Quantile struct {
+	Quantiles        []float64 `toml:"quantiles"`
+	Fields           []string  `toml:"fields"`
+	RelativeAccuracy float64   `toml:"relative_accuracy"`
+	MaxBins          int       `toml:"max_bins"`
+
+	cache       map[uint64]aggregate
+	fieldFilter filter.Filter
+	suffixes    []string
+	initialized bool
+
+	logName string
+}
+
+type aggregate struct {
+	name   string
+	tags   map[string]string
+	fields map[string]*sketch
+}
+
+func NewQuantile() *Quantile {
+	q := &Quantile{
+		Quantiles:        []float64{0.5, 0.9, 0.99},
+		RelativeAccuracy: 0.01,
+		MaxBins:          2048,
+		logName:          "aggregators.quantile",
+	}
+	q.Reset()
+	return q
+}
+
+func (q *Quantile) SampleConfig() string {
+	return sampleConfig
+}
+
+func (q *Quantile) Description() string {
+	return "Keep the aggregate quantiles of each metric passing through."
+}
+
+// SetLogName sets the name of the plugin in log messages.
+func (q *Quantile) SetLogName(name string) {
+	q.logName = name
+}
+
+func (q *Quantile) init() {
+	q.initialized = true
+
+	var err error
+	q.fieldFilter, err = filter.Compile(q.Fields)
+	if err != nil {
+		log.Printf("E! [%s] Error compiling fields: %v", q.logName, err)
+	}
+
+	if q.RelativeAccuracy <= 0 || q.RelativeAccuracy >= 1 {
+		log.Printf("W! [%s] Invalid relative_accuracy %v, using 0.01", q.logName, q.RelativeAccuracy)
+		q.RelativeAccuracy = 0.01
+	}
+	if q.MaxBins <= 0 {
+		q.MaxBins = 2048
+	}
+
+	quantiles := q.Quantiles[:0]
+	for _, quantile := range q.Quantiles {
+		if quantile < 0 || quantile > 1 {
+			log.Printf("W! [%s] Quantile %v is not in the range [0,1], ignoring", q.logName, quantile)
+			continue
+		}
+		quantiles = append(quantiles, quantile)
+		q.suffixes = append(q.suffixes, suffix(quantile))
+	}
+	q.Quantiles = quantiles
+}
+
+// suffix returns the field suffix of the quantile, such as "_p50" for 0.5
+// and "_p99_9" for 0.999.
+func suffix(quantile float64) string {
+	// Rounded to hide the floating point error of the percentage.
+	p := strconv.FormatFloat(math.Round(quantile*100*1e6)/1e6, 'f', -1, 64)
+	return "_p" + strings.Replace(p, ".", "_", -1)
+}
+
+func (q *Quantile) Add(in telegraf.Metric) {
+	if !q.initialized {
+		q.init()
+	}
+
+	id := in.HashID()
+	a, ok := q.cache[id]
+	if !ok {
+		a = aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]*sketch),
+		}
+	}
+
+	for _, field := range in.FieldList() {
+		if q.fieldFilter != nil && !q.fieldFilter.Match(field.Key) {
+			continue
+		}
+		fv, ok := convert(field.Value)
+		if !ok {
+			continue
+		}
+		// The sketch only holds finite values.
+		if math.IsNaN(fv) || math.IsInf(fv, 0) {
+			continue
+		}
+
+		s, ok := a.fields[field.Key]
+		if !ok {
+			s = newSketch(q.RelativeAccuracy, q.MaxBins)
+			a.fields[field.Key] = s
+		}
+		s.add(fv)
+	}
+
+	if !ok && len(a.fields) > 0 {
+		q.cache[id] = a
+	}
+}
+
+func (q *Quantile) Push(acc telegraf.Accumulator) {
+	if !q.initialized {
+		q.init()
+	}
+
+	for _, a := range q.cache {
+		fields := map[string]interface{}{}
+		for k, s := range a.fields {
+			for i, quantile := range q.Quantiles {
+				fields[k+q.suffixes[i]] = s.quantile(quantile)
+			}
+		}
+
+		if len(fields) > 0 {
+			acc.AddFields(a.name, fields, a.tags)
+		}
+	}
+}
+
+func (q *Quantile) Reset() {
+	q.cache = make(map[uint64]aggregate)
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("quantile", func() telegraf.Aggregator {
+		return NewQuantile()
+	})
+}
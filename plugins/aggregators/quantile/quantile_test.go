@@ -0,0 +1,104 @@
+package quantile
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(fields map[string]interface{}) telegraf.Metric {
+	return testutil.MustMetric("latency",
+		map[string]string{"host": "a"},
+		fields,
+		time.Now())
+}
+
+func TestQuantileFields(t *testing.T) {
+	acc := testutil.Accumulator{}
+	q := NewQuantile()
+	q.Quantiles = []float64{0.5, 0.999}
+
+	for i := 1; i <= 1000; i++ {
+		q.Add(newMetric(map[string]interface{}{
+			"value":  float64(i),
+			"status": "ok",
+		}))
+	}
+	q.Push(&acc)
+
+	require.Len(t, acc.Metrics, 1)
+	fields := acc.Metrics[0].Fields
+	require.Len(t, fields, 2)
+	require.InDelta(t, 500, fields["value_p50"], 500*0.01)
+	require.InDelta(t, 999, fields["value_p99_9"], 999*0.01)
+	require.Equal(t, map[string]string{"host": "a"}, acc.Metrics[0].Tags)
+}
+
+func TestQuantileSelectedFields(t *testing.T) {
+	acc := testutil.Accumulator{}
+	q := NewQuantile()
+	q.Quantiles = []float64{0.5}
+	q.Fields = []string{"response_*"}
+
+	q.Add(newMetric(map[string]interface{}{
+		"response_time": int64(10),
+		"size":          int64(100),
+	}))
+	q.Push(&acc)
+
+	require.Len(t, acc.Metrics, 1)
+	require.Contains(t, acc.Metrics[0].Fields, "response_time_p50")
+	require.NotContains(t, acc.Metrics[0].Fields, "size_p50")
+}
+
+func TestQuantileReset(t *testing.T) {
+	acc := testutil.Accumulator{}
+	q := NewQuantile()
+
+	q.Add(newMetric(map[string]interface{}{"value": 1.0}))
+	q.Reset()
+	q.Push(&acc)
+	require.Len(t, acc.Metrics, 0)
+}
+
+func TestQuantileSkipsNonFinite(t *testing.T) {
+	tests := []struct {
+		name  string
+		value float64
+	}{
+		{name: "NaN", value: math.NaN()},
+		{name: "positive infinity", value: math.Inf(1)},
+		{name: "negative infinity", value: math.Inf(-1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			acc := testutil.Accumulator{}
+			q := NewQuantile()
+			q.Quantiles = []float64{0, 0.5, 1}
+
+			q.Add(newMetric(map[string]interface{}{"value": tt.value}))
+			q.Push(&acc)
+			require.Len(t, acc.Metrics, 0)
+
+			q.Add(newMetric(map[string]interface{}{"value": 1.0}))
+			q.Add(newMetric(map[string]interface{}{"value": tt.value}))
+			q.Push(&acc)
+			require.Len(t, acc.Metrics, 1)
+			for _, v := range acc.Metrics[0].Fields {
+				require.InDelta(t, 1.0, v, 0.01)
+			}
+		})
+	}
+}
+
+func TestQuantileSuffix(t *testing.T) {
+	require.Equal(t, "_p0", suffix(0))
+	require.Equal(t, "_p25", suffix(0.25))
+	require.Equal(t, "_p29", suffix(0.29))
+	require.Equal(t, "_p99_9", suffix(0.999))
+	require.Equal(t, "_p100", suffix(1))
+}
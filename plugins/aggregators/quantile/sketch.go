@@ -0,0 +1,127 @@
+package quantile
+
+import (
+	"math"
+)
+
+// minIndexable is the smallest magnitude tracked by the sketch, values
+// closer to zero are counted as zero.
+const minIndexable = 1e-9
+
+// sketch is a DDSketch, it estimates quantiles with a bounded relative error
+// using logarithmically sized bins.  The positive and negative values are
+// binned by magnitude, when the number of bins exceeds the limit the bins of
+// the smallest magnitudes are collapsed.  This reduces the accuracy of the
+// values closest to zero: the lowest positive and the highest negative
+// values.
+type sketch struct {
+	gamma    float64
+	logGamma float64
+
+	positive store
+	negative store
+	zero     float64
+	count    float64
+}
+
+func newSketch(relativeAccuracy float64, maxBins int) *sketch {
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &sketch{
+		gamma:    gamma,
+		logGamma: math.Log(gamma),
+		positive: store{maxBins: maxBins},
+		negative: store{maxBins: maxBins},
+	}
+}
+
+func (s *sketch) index(v float64) int {
+	return int(math.Ceil(math.Log(v) / s.logGamma))
+}
+
+func (s *sketch) value(index int) float64 {
+	return 2 * math.Pow(s.gamma, float64(index)) / (1 + s.gamma)
+}
+
+func (s *sketch) add(v float64) {
+	switch {
+	case v > minIndexable:
+		s.positive.add(s.index(v))
+	case v < -minIndexable:
+		s.negative.add(s.index(-v))
+	default:
+		s.zero++
+	}
+	s.count++
+}
+
+// quantile returns the estimated value at the quantile q in [0, 1].
+func (s *sketch) quantile(q float64) float64 {
+	rank := q * (s.count - 1)
+
+	// Negative values, from the largest magnitude to the smallest.
+	var n float64
+	for i := len(s.negative.bins) - 1; i >= 0; i-- {
+		n += s.negative.bins[i]
+		if n > rank {
+			return -s.value(s.negative.offset + i)
+		}
+	}
+
+	n += s.zero
+	if n > rank {
+		return 0
+	}
+
+	for i, c := range s.positive.bins {
+		n += c
+		if n > rank {
+			return s.value(s.positive.offset + i)
+		}
+	}
+	return s.value(s.positive.offset + len(s.positive.bins) - 1)
+}
+
+// store counts the values in contiguous bins, starting at the bin index
+// offset.
+type store struct {
+	bins    []float64
+	offset  int
+	maxBins int
+}
+
+func (s *store) add(index int) {
+	if len(s.bins) == 0 {
+		s.bins = []float64{0}
+		s.offset = index
+	}
+
+	switch {
+	case index < s.offset:
+		// Grow to the left as far as the limit allows, the values below
+		// the lowest bin are counted in it.
+		grow := s.offset - index
+		if free := s.maxBins - len(s.bins); grow > free {
+			grow = free
+		}
+		if grow > 0 {
+			s.bins = append(make([]float64, grow), s.bins...)
+			s.offset -= grow
+		}
+		if index < s.offset {
+			index = s.offset
+		}
+	case index >= s.offset+len(s.bins):
+		s.bins = append(s.bins, make([]float64, index-s.offset-len(s.bins)+1)...)
+		if extra := len(s.bins) - s.maxBins; extra > 0 {
+			var collapsed float64
+			for _, c := range s.bins[:extra+1] {
+				collapsed += c
+			}
+			s.bins = s.bins[extra:]
+			s.bins[0] = collapsed
+			s.offset += extra
+		}
+	}
+
+	s.bins[index-s.offset]++
+}
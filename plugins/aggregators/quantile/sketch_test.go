@@ -0,0 +1,57 @@
+package quantile
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func exactQuantile(values []float64, q float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	return sorted[int(q*float64(len(sorted)-1))]
+}
+
+func TestSketchRelativeAccuracy(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	var values []float64
+	for i := 0; i < 10000; i++ {
+		values = append(values, rng.ExpFloat64()*100-20)
+	}
+
+	s := newSketch(0.01, 2048)
+	for _, v := range values {
+		s.add(v)
+	}
+
+	for _, q := range []float64{0, 0.1, 0.25, 0.5, 0.9, 0.99, 1} {
+		expected := exactQuantile(values, q)
+		actual := s.quantile(q)
+		require.InDelta(t, expected, actual, math.Abs(expected)*0.01+1e-9, "quantile %v", q)
+	}
+}
+
+func TestSketchZero(t *testing.T) {
+	s := newSketch(0.01, 2048)
+	s.add(-1)
+	s.add(0)
+	s.add(1)
+	require.Equal(t, 0.0, s.quantile(0.5))
+	require.InDelta(t, -1, s.quantile(0), 0.01)
+	require.InDelta(t, 1, s.quantile(1), 0.01)
+}
+
+func TestSketchMaxBins(t *testing.T) {
+	s := newSketch(0.01, 64)
+	for i := 1; i <= 100000; i++ {
+		s.add(float64(i))
+	}
+	require.True(t, len(s.positive.bins) <= 64)
+
+	// The highest quantiles keep their accuracy.
+	require.InDelta(t, 99000, s.quantile(0.99), 99000*0.01)
+	require.InDelta(t, 100000, s.quantile(1), 100000*0.01)
+}
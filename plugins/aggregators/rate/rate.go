@@ -0,0 +1,319 @@
+package rate
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+var sampleConfig = `
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Fields of the counters to compute the rate of, globs are supported.
+  # fields = []
+
+  ## Compute the rate of all fields of metrics with the counter value type,
+  ## such as those of the prometheus and snmp inputs.
+  # use_counter_type = true
+
+  ## Configures which stats to push as fields
+  # stats = ["rate", "delta"]
+
+  ## Width in bits of counters that wrap around when they reach their maximum
+  ## value, 32 or 64.  When 0, or if the wrapped increase would be more than
+  ## half the counter range, a decreasing counter is considered to have been
+  ## reset to zero.
+  # counter_bits = 0
+
+  ## The time a series is kept without being updated, the increase to the
+  ## next value is included in the rate if it is updated within this time.
+  # series_timeout = "5m"
+`
+
+type Rate struct {
+	Fields         []string          `toml:"fields"`
+	UseCounterType bool              `toml:"use_counter_type"`
+	Stats          []string          `toml:"stats"`
+	CounterBits    int               `toml:"counter_bits"`
+	SeriesTimeout  internal.Duration `toml:"series_timeout"`
+
+	cache       map[uint64]*series
+	fieldFilter filter.Filter
+	statsConfig *configuredStats
+	initialized bool
+
+	// The series are expired by the time of the metrics, latest is the
+	// newest metric time seen.
+	latest time.Time
+
+	logName string
+}
+
+type configuredStats struct {
+	rate  bool
+	delta bool
+}
+
+// series holds the counters of a series.
+type series struct {
+	name     string
+	tags     map[string]string
+	counters map[string]*counter
+}
+
+// counter tracks the increase of a counter during the period.  The increase
+// is measured from the last value before the period started.
+type counter struct {
+	last     interface{}
+	lastTime time.Time
+
+	start   time.Time
+	delta   float64
+	updated bool
+}
+
+func NewRate() *Rate {
+	r := &Rate{
+		UseCounterType: true,
+		SeriesTimeout:  internal.Duration{Duration: 5 * time.Minute},
+		cache:          make(map[uint64]*series),
+		logName:        "aggregators.rate",
+	}
+	return r
+}
+
+func (r *Rate) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Rate) Description() string {
+	return "Compute the rate and increase of counters over the period."
+}
+
+// SetLogName sets the name of the plugin in log messages.
+func (r *Rate) SetLogName(name string) {
+	r.logName = name
+}
+
+func (r *Rate) init() {
+	r.initialized = true
+
+	var err error
+	r.fieldFilter, err = filter.Compile(r.Fields)
+	if err != nil {
+		log.Printf("E! [%s] Error compiling fields: %v", r.logName, err)
+	}
+
+	r.statsConfig = &configuredStats{}
+	if r.Stats == nil {
+		r.statsConfig.rate = true
+		r.statsConfig.delta = true
+	}
+	for _, name := range r.Stats {
+		switch name {
+		case "rate":
+			r.statsConfig.rate = true
+		case "delta":
+			r.statsConfig.delta = true
+		default:
+			log.Printf("W! [%s] Unrecognized stat '%s', ignoring", r.logName, name)
+		}
+	}
+
+	switch r.CounterBits {
+	case 0, 32, 64:
+	default:
+		log.Printf("W! [%s] Unsupported counter_bits %d, counters are not wrapped", r.logName, r.CounterBits)
+		r.CounterBits = 0
+	}
+}
+
+// selected returns true if the rate of the field is computed.
+func (r *Rate) selected(in telegraf.Metric, key string) bool {
+	if r.UseCounterType && in.Type() == telegraf.Counter {
+		return true
+	}
+	return r.fieldFilter != nil && r.fieldFilter.Match(key)
+}
+
+func (r *Rate) Add(in telegraf.Metric) {
+	if !r.initialized {
+		r.init()
+	}
+
+	if in.Time().After(r.latest) {
+		r.latest = in.Time()
+	}
+
+	id := in.HashID()
+	s, ok := r.cache[id]
+	if !ok {
+		s = &series{
+			name:     in.Name(),
+			tags:     in.Tags(),
+			counters: make(map[string]*counter),
+		}
+	}
+
+	for _, field := range in.FieldList() {
+		if !r.selected(in, field.Key) {
+			continue
+		}
+		if _, ok := convert(field.Value); !ok {
+			continue
+		}
+
+		c, ok := s.counters[field.Key]
+		if !ok {
+			s.counters[field.Key] = &counter{
+				last:     field.Value,
+				lastTime: in.Time(),
+				start:    in.Time(),
+			}
+			continue
+		}
+
+		// Metrics older than the last value are out of order.
+		if !in.Time().After(c.lastTime) {
+			continue
+		}
+
+		c.delta += r.increase(c.last, field.Value)
+		c.last = field.Value
+		c.lastTime = in.Time()
+		c.updated = true
+	}
+
+	if !ok && len(s.counters) > 0 {
+		r.cache[id] = s
+	}
+}
+
+// increase returns the increase of a counter from the previous to the current
+// value, accounting for counter wraparound and resets.
+func (r *Rate) increase(prevValue, curValue interface{}) float64 {
+	// Unsigned counters are subtracted as integers to keep the precision of
+	// large values.
+	if prevUint, ok := prevValue.(uint64); ok {
+		if curUint, ok := curValue.(uint64); ok {
+			return r.increaseUint(prevUint, curUint)
+		}
+	}
+
+	prev, _ := convert(prevValue)
+	cur, _ := convert(curValue)
+	if cur >= prev {
+		return cur - prev
+	}
+
+	if r.CounterBits > 0 {
+		max := math.Pow(2, float64(r.CounterBits))
+		if wrapped := max - prev + cur; prev < max && wrapped <= max/2 {
+			return wrapped
+		}
+	}
+
+	// The counter was reset and has been counting from zero.
+	if cur < 0 {
+		return 0
+	}
+	return cur
+}
+
+func (r *Rate) increaseUint(prev, cur uint64) float64 {
+	if cur >= prev {
+		return float64(cur - prev)
+	}
+
+	switch r.CounterBits {
+	case 32:
+		if prev <= math.MaxUint32 && cur <= math.MaxUint32 {
+			if wrapped := math.MaxUint32 - prev + cur + 1; wrapped <= math.MaxUint32/2 {
+				return float64(wrapped)
+			}
+		}
+	case 64:
+		// The subtraction wraps around like the counter.
+		if wrapped := cur - prev; wrapped <= math.MaxUint64/2 {
+			return float64(wrapped)
+		}
+	}
+	return float64(cur)
+}
+
+func (r *Rate) Push(acc telegraf.Accumulator) {
+	if !r.initialized {
+		r.init()
+	}
+
+	for _, s := range r.cache {
+		fields := map[string]interface{}{}
+		for k, c := range s.counters {
+			if !c.updated {
+				continue
+			}
+
+			if r.statsConfig.delta {
+				fields[k+"_delta"] = c.delta
+			}
+			if r.statsConfig.rate {
+				elapsed := c.lastTime.Sub(c.start).Seconds()
+				if elapsed > 0 {
+					fields[k+"_rate"] = c.delta / elapsed
+				}
+			}
+		}
+
+		if len(fields) > 0 {
+			acc.AddFields(s.name, fields, s.tags)
+		}
+	}
+}
+
+// Reset starts a new period, the increase in the new period is measured from
+// the last value of each counter.  Series that have not been updated within
+// the series timeout of the newest metric are removed.
+func (r *Rate) Reset() {
+	for id, s := range r.cache {
+		for k, c := range s.counters {
+			if r.latest.Sub(c.lastTime) > r.SeriesTimeout.Duration {
+				delete(s.counters, k)
+				continue
+			}
+			c.start = c.lastTime
+			c.delta = 0
+			c.updated = false
+		}
+		if len(s.counters) == 0 {
+			delete(r.cache, id)
+		}
+	}
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("rate", func() telegraf.Aggregator {
+		return NewRate()
+	})
+}
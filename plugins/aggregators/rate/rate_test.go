@@ -0,0 +1,209 @@
+package rate
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func counterMetric(value interface{}, ts time.Time) telegraf.Metric {
+	return testutil.MustMetric("net",
+		map[string]string{"interface": "eth0"},
+		map[string]interface{}{"bytes_recv": value},
+		ts,
+		telegraf.Counter,
+	)
+}
+
+func gaugeMetric(value interface{}, ts time.Time) telegraf.Metric {
+	return testutil.MustMetric("net",
+		map[string]string{"interface": "eth0"},
+		map[string]interface{}{"bytes_recv": value},
+		ts,
+	)
+}
+
+func TestRateCounterType(t *testing.T) {
+	acc := testutil.Accumulator{}
+	r := NewRate()
+
+	now := time.Now()
+	r.Add(counterMetric(int64(100), now))
+	r.Add(counterMetric(int64(200), now.Add(10*time.Second)))
+	r.Add(counterMetric(int64(400), now.Add(20*time.Second)))
+	r.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "net",
+		map[string]interface{}{
+			"bytes_recv_delta": float64(300),
+			"bytes_recv_rate":  float64(15),
+		},
+		map[string]string{"interface": "eth0"})
+}
+
+func TestRateSkipsUnselectedFields(t *testing.T) {
+	acc := testutil.Accumulator{}
+	r := NewRate()
+
+	now := time.Now()
+	r.Add(gaugeMetric(int64(100), now))
+	r.Add(gaugeMetric(int64(200), now.Add(10*time.Second)))
+	r.Push(&acc)
+	require.Len(t, acc.Metrics, 0)
+
+	r = NewRate()
+	r.Fields = []string{"bytes_*"}
+	r.Add(gaugeMetric(int64(100), now))
+	r.Add(gaugeMetric(int64(200), now.Add(10*time.Second)))
+	r.Push(&acc)
+	acc.AssertContainsFields(t, "net",
+		map[string]interface{}{
+			"bytes_recv_delta": float64(100),
+			"bytes_recv_rate":  float64(10),
+		})
+}
+
+func TestRateAcrossPeriods(t *testing.T) {
+	r := NewRate()
+
+	now := time.Now()
+	r.Add(counterMetric(int64(100), now))
+	r.Add(counterMetric(int64(200), now.Add(10*time.Second)))
+	acc := testutil.Accumulator{}
+	r.Push(&acc)
+	r.Reset()
+
+	// The increase is measured from the last value of the previous period.
+	r.Add(counterMetric(int64(250), now.Add(20*time.Second)))
+	acc = testutil.Accumulator{}
+	r.Push(&acc)
+	acc.AssertContainsFields(t, "net",
+		map[string]interface{}{
+			"bytes_recv_delta": float64(50),
+			"bytes_recv_rate":  float64(5),
+		})
+	r.Reset()
+
+	// Nothing is pushed for periods without new values.
+	acc = testutil.Accumulator{}
+	r.Push(&acc)
+	require.Len(t, acc.Metrics, 0)
+}
+
+func TestRateCounterReset(t *testing.T) {
+	acc := testutil.Accumulator{}
+	r := NewRate()
+
+	now := time.Now()
+	r.Add(counterMetric(int64(1000), now))
+	r.Add(counterMetric(int64(50), now.Add(10*time.Second)))
+	r.Push(&acc)
+
+	acc.AssertContainsFields(t, "net",
+		map[string]interface{}{
+			"bytes_recv_delta": float64(50),
+			"bytes_recv_rate":  float64(5),
+		})
+}
+
+func TestRateCounterWraparound(t *testing.T) {
+	tests := []struct {
+		name     string
+		bits     int
+		prev     interface{}
+		cur      interface{}
+		expected float64
+	}{
+		{
+			name:     "uint32",
+			bits:     32,
+			prev:     uint64(math.MaxUint32 - 9),
+			cur:      uint64(10),
+			expected: 20,
+		},
+		{
+			name:     "uint32 as int",
+			bits:     32,
+			prev:     int64(math.MaxUint32 - 9),
+			cur:      int64(10),
+			expected: 20,
+		},
+		{
+			name:     "uint64",
+			bits:     64,
+			prev:     uint64(math.MaxUint64 - 9),
+			cur:      uint64(10),
+			expected: 20,
+		},
+		{
+			name:     "reset is not wrapped",
+			bits:     32,
+			prev:     uint64(1000),
+			cur:      uint64(10),
+			expected: 10,
+		},
+		{
+			name:     "no wraparound",
+			bits:     0,
+			prev:     uint64(math.MaxUint32 - 9),
+			cur:      uint64(10),
+			expected: 10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			acc := testutil.Accumulator{}
+			r := NewRate()
+			r.CounterBits = tt.bits
+			r.Stats = []string{"delta"}
+
+			now := time.Now()
+			r.Add(counterMetric(tt.prev, now))
+			r.Add(counterMetric(tt.cur, now.Add(10*time.Second)))
+			r.Push(&acc)
+
+			acc.AssertContainsFields(t, "net",
+				map[string]interface{}{
+					"bytes_recv_delta": tt.expected,
+				})
+		})
+	}
+}
+
+func TestRateSeriesTimeout(t *testing.T) {
+	r := NewRate()
+
+	now := time.Now()
+	r.Add(counterMetric(int64(100), now.Add(-10*time.Minute)))
+	r.Add(testutil.MustMetric("net",
+		map[string]string{"interface": "eth1"},
+		map[string]interface{}{"bytes_recv": int64(100)},
+		now,
+		telegraf.Counter,
+	))
+	r.Reset()
+	require.Len(t, r.cache, 1)
+}
+
+func TestRateSeriesTimeoutByMetricTime(t *testing.T) {
+	r := NewRate()
+
+	// Metrics older than the series timeout, such as those of a backfill,
+	// are kept while no newer metrics are seen.
+	past := time.Now().Add(-time.Hour)
+	r.Add(counterMetric(int64(100), past))
+	r.Reset()
+	r.Add(counterMetric(int64(150), past.Add(time.Minute)))
+
+	acc := testutil.Accumulator{}
+	r.Push(&acc)
+	acc.AssertContainsFields(t, "net",
+		map[string]interface{}{
+			"bytes_recv_delta": float64(50),
+			"bytes_recv_rate":  float64(50) / 60,
+		})
+}
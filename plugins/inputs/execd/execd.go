@@ -0,0 +1,141 @@
+package execd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/process"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+const sampleConfig = `
+  ## Program to run as daemon, and its arguments.
+  command = ["/usr/bin/mycollector", "--foo=bar"]
+
+  ## Define how the process is signaled on each collection interval.
+  ## Valid values are:
+  ##   "none"   : Do not signal anything.
+  ##              The process must output metrics by itself.
+  ##   "STDIN"   : Send a newline on STDIN.
+  ##   "SIGHUP"  : Send a HUP signal. Not available on Windows.
+  ##   "SIGUSR1" : Send a USR1 signal. Not available on Windows.
+  ##   "SIGUSR2" : Send a USR2 signal. Not available on Windows.
+  signal = "none"
+
+  ## Delay before the process is restarted after an unexpected termination.
+  ## The delay doubles each time the process exits again soon after starting.
+  restart_delay = "10s"
+
+  ## Data format to consume.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
+  data_format = "influx"
+`
+
+type Execd struct {
+	Command      []string          `toml:"command"`
+	Signal       string            `toml:"signal"`
+	RestartDelay internal.Duration `toml:"restart_delay"`
+
+	acc     telegraf.Accumulator
+	parser  parsers.Parser
+	process *process.Process
+
+	logName string
+}
+
+func New() *Execd {
+	return &Execd{
+		Signal:       "none",
+		RestartDelay: internal.Duration{Duration: 10 * time.Second},
+		logName:      "inputs.execd",
+	}
+}
+
+func (e *Execd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Execd) Description() string {
+	return "Run executable as long-running input plugin"
+}
+
+// SetLogName sets the name of the plugin in log messages.
+func (e *Execd) SetLogName(name string) {
+	e.logName = name
+}
+
+func (e *Execd) SetParser(parser parsers.Parser) {
+	e.parser = parser
+}
+
+func (e *Execd) Start(acc telegraf.Accumulator) error {
+	if len(e.Command) == 0 {
+		return errors.New("command must be set")
+	}
+
+	if err := checkSignal(e.Signal); err != nil {
+		return err
+	}
+
+	var err error
+	e.process, err = process.New(e.Command)
+	if err != nil {
+		return err
+	}
+	e.process.LogName = e.logName
+	e.process.RestartDelay = e.RestartDelay.Duration
+	e.process.ReadStdoutFn = e.readStdout
+
+	e.acc = acc
+	return e.process.Start()
+}
+
+func (e *Execd) Stop() {
+	e.process.Stop()
+}
+
+// Gather signals the process to output metrics, the metrics are added to
+// the accumulator as they are read.
+func (e *Execd) Gather(acc telegraf.Accumulator) error {
+	if e.process == nil {
+		return nil
+	}
+
+	switch e.Signal {
+	case "none":
+		return nil
+	case "STDIN":
+		if _, err := e.process.Write([]byte{'\n'}); err != nil {
+			return fmt.Errorf("error writing to stdin: %v", err)
+		}
+		return nil
+	default:
+		return e.signal()
+	}
+}
+
+func (e *Execd) readStdout(r io.Reader) {
+	e.process.ReadLines(r, func(line []byte) {
+		metrics, err := e.parser.Parse(line)
+		if err != nil {
+			e.acc.AddError(fmt.Errorf("parse error: %v", err))
+		}
+
+		for _, m := range metrics {
+			e.acc.AddMetric(m)
+		}
+	})
+}
+
+func init() {
+	inputs.Add("execd", func() telegraf.Input {
+		return New()
+	})
+}
@@ -0,0 +1,33 @@
+// +build !windows
+
+package execd
+
+import (
+	"fmt"
+	"syscall"
+)
+
+func checkSignal(signal string) error {
+	switch signal {
+	case "none", "STDIN", "SIGHUP", "SIGUSR1", "SIGUSR2":
+		return nil
+	default:
+		return fmt.Errorf("invalid signal: %s", signal)
+	}
+}
+
+func (e *Execd) signal() error {
+	var err error
+	switch e.Signal {
+	case "SIGHUP":
+		err = e.process.Signal(syscall.SIGHUP)
+	case "SIGUSR1":
+		err = e.process.Signal(syscall.SIGUSR1)
+	case "SIGUSR2":
+		err = e.process.Signal(syscall.SIGUSR2)
+	}
+	if err != nil {
+		return fmt.Errorf("error signaling process: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+// +build !windows
+
+package execd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestExecd(t *testing.T, script string, signal string) *Execd {
+	parser, err := parsers.NewInfluxParser()
+	require.NoError(t, err)
+
+	e := New()
+	e.Command = []string{"sh", "-c", script}
+	e.Signal = signal
+	e.SetParser(parser)
+	return e
+}
+
+func TestExecd_SignalStdin(t *testing.T) {
+	e := newTestExecd(t,
+		`while read line; do echo "cpu,host=localhost value=42 0"; done`,
+		"STDIN")
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, e.Start(acc))
+	defer e.Stop()
+
+	require.NoError(t, e.Gather(acc))
+	acc.Wait(1)
+	require.NoError(t, e.Gather(acc))
+	acc.Wait(2)
+
+	expected := testutil.MustMetric("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": 42.0},
+		time.Unix(0, 0),
+	)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{expected, expected},
+		acc.GetTelegrafMetrics())
+}
+
+func TestExecd_SignalUSR1(t *testing.T) {
+	e := newTestExecd(t,
+		`trap 'echo "cpu value=1 0"' USR1; while read line; do :; done`,
+		"SIGUSR1")
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, e.Start(acc))
+	defer e.Stop()
+
+	// Give the shell time to install the trap.
+	time.Sleep(500 * time.Millisecond)
+	require.NoError(t, e.Gather(acc))
+	acc.Wait(1)
+	require.True(t, acc.HasFloatField("cpu", "value"))
+}
+
+func TestExecd_NoSignal(t *testing.T) {
+	e := newTestExecd(t, `echo "cpu value=1 0"; cat >/dev/null`, "none")
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, e.Start(acc))
+	defer e.Stop()
+
+	acc.Wait(1)
+	require.NoError(t, e.Gather(acc))
+	require.Equal(t, uint64(1), acc.NMetrics())
+}
+
+func TestExecd_ParseError(t *testing.T) {
+	e := newTestExecd(t, `echo "not line protocol"; cat >/dev/null`, "none")
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, e.Start(acc))
+	defer e.Stop()
+
+	acc.WaitError(1)
+}
+
+func TestExecd_LongLine(t *testing.T) {
+	// The line is longer than the default buffer of a bufio.Scanner.
+	e := newTestExecd(t,
+		`printf 'cpu value=1 0 %0100000d\n' 0; echo "cpu value=2 0"; cat >/dev/null`,
+		"none")
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, e.Start(acc))
+	defer e.Stop()
+
+	acc.WaitError(1)
+	acc.Wait(1)
+	expected := testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{"value": 2.0},
+		time.Unix(0, 0),
+	)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{expected},
+		acc.GetTelegrafMetrics())
+}
+
+func TestExecd_InvalidSignal(t *testing.T) {
+	e := newTestExecd(t, `true`, "SIGKILL")
+	require.Error(t, e.Start(&testutil.Accumulator{}))
+}
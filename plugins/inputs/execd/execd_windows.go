@@ -0,0 +1,20 @@
+// +build windows
+
+package execd
+
+import (
+	"fmt"
+)
+
+func checkSignal(signal string) error {
+	switch signal {
+	case "none", "STDIN":
+		return nil
+	default:
+		return fmt.Errorf("invalid signal: %s, only none and STDIN are supported on Windows", signal)
+	}
+}
+
+func (e *Execd) signal() error {
+	return nil
+}
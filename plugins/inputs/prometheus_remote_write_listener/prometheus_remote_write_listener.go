@@ -0,0 +1,263 @@
+package prometheus_remote_write_listener
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"io/ioutil"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/prompb"
+	tlsint "github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+// defaultMaxBodySize is the default maximum request body size, in bytes.
+// if the request body is over this size, we will return an HTTP 413 error.
+// 500 MB
+const defaultMaxBodySize = 500 * 1024 * 1024
+
+type PrometheusRemoteWriteListener struct {
+	ServiceAddress string            `toml:"service_address"`
+	Path           string            `toml:"path"`
+	ReadTimeout    internal.Duration `toml:"read_timeout"`
+	WriteTimeout   internal.Duration `toml:"write_timeout"`
+	MaxBodySize    internal.Size     `toml:"max_body_size"`
+	Port           int
+
+	tlsint.ServerConfig
+
+	BasicUsername string `toml:"basic_username"`
+	BasicPassword string `toml:"basic_password"`
+
+	wg sync.WaitGroup
+
+	listener net.Listener
+
+	acc telegraf.Accumulator
+
+	logName string
+}
+
+const sampleConfig = `
+  ## Address and port to host the remote write listener on.
+  service_address = ":9201"
+
+  ## Path to listen to.
+  # path = "/write"
+
+  ## maximum duration before timing out read of the request
+  # read_timeout = "10s"
+  ## maximum duration before timing out write of the response
+  # write_timeout = "10s"
+
+  ## Maximum allowed http request body size in bytes, before and after
+  ## decompression.
+  ## 0 means to use the default of 524,288,000 bytes (500 mebibytes)
+  # max_body_size = "500MB"
+
+  ## Set one or more allowed client CA certificate file names to
+  ## enable mutually authenticated TLS connections
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
+  ## Add service certificate and key
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+
+  ## Optional username and password to accept for HTTP basic authentication.
+  ## You probably want to make sure you have TLS configured above for this.
+  # basic_username = "foobar"
+  # basic_password = "barfoo"
+`
+
+func (p *PrometheusRemoteWriteListener) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PrometheusRemoteWriteListener) Description() string {
+	return "Prometheus remote write listener"
+}
+
+// SetLogName sets the name of the plugin in log messages.
+func (p *PrometheusRemoteWriteListener) SetLogName(name string) {
+	p.logName = name
+}
+
+func (p *PrometheusRemoteWriteListener) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+// Start starts the remote write listener service.
+func (p *PrometheusRemoteWriteListener) Start(acc telegraf.Accumulator) error {
+	if p.MaxBodySize.Size == 0 {
+		p.MaxBodySize.Size = defaultMaxBodySize
+	}
+
+	if p.ReadTimeout.Duration < time.Second {
+		p.ReadTimeout.Duration = time.Second * 10
+	}
+	if p.WriteTimeout.Duration < time.Second {
+		p.WriteTimeout.Duration = time.Second * 10
+	}
+
+	p.acc = acc
+
+	tlsConf, err := p.ServerConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:         p.ServiceAddress,
+		Handler:      p,
+		ReadTimeout:  p.ReadTimeout.Duration,
+		WriteTimeout: p.WriteTimeout.Duration,
+		TLSConfig:    tlsConf,
+	}
+
+	var listener net.Listener
+	if tlsConf != nil {
+		listener, err = tls.Listen("tcp", p.ServiceAddress, tlsConf)
+	} else {
+		listener, err = net.Listen("tcp", p.ServiceAddress)
+	}
+	if err != nil {
+		return err
+	}
+	p.listener = listener
+	p.Port = listener.Addr().(*net.TCPAddr).Port
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		server.Serve(p.listener)
+	}()
+
+	log.Printf("I! [%s] Started listener on %s", p.logName, p.ServiceAddress)
+
+	return nil
+}
+
+// Stop cleans up all resources
+func (p *PrometheusRemoteWriteListener) Stop() {
+	p.listener.Close()
+	p.wg.Wait()
+
+	log.Printf("I! [%s] Stopped listener on %s", p.logName, p.ServiceAddress)
+}
+
+func (p *PrometheusRemoteWriteListener) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == p.Path {
+		p.authenticateIfSet(p.serveWrite, res, req)
+	} else {
+		p.authenticateIfSet(http.NotFound, res, req)
+	}
+}
+
+func (p *PrometheusRemoteWriteListener) serveWrite(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(res, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Check that the content length is not too large for us to handle.
+	if req.ContentLength > p.MaxBodySize.Size {
+		http.Error(res, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	body := http.MaxBytesReader(res, req.Body, p.MaxBodySize.Size)
+	compressed, err := ioutil.ReadAll(body)
+	if err != nil {
+		http.Error(res, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Check the decoded length before allocating the buffer for it.
+	n, err := snappy.DecodedLen(compressed)
+	if err != nil {
+		log.Printf("D! [%s] Error decoding request: %v", p.logName, err)
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if int64(n) > p.MaxBodySize.Size {
+		http.Error(res, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	buf, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		log.Printf("D! [%s] Error decoding request: %v", p.logName, err)
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var writeReq prompb.WriteRequest
+	if err := writeReq.Unmarshal(buf); err != nil {
+		log.Printf("D! [%s] Error decoding request: %v", p.logName, err)
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, ts := range writeReq.Timeseries {
+		p.addSeries(ts)
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// addSeries adds a metric per sample of the series, named after the __name__
+// label with the other labels as tags.  NaN values, used by Prometheus as
+// staleness markers, are skipped.
+func (p *PrometheusRemoteWriteListener) addSeries(ts prompb.TimeSeries) {
+	var name string
+	tags := make(map[string]string, len(ts.Labels))
+	for _, l := range ts.Labels {
+		if l.Name == "__name__" {
+			name = l.Value
+			continue
+		}
+		tags[l.Name] = l.Value
+	}
+	if name == "" {
+		return
+	}
+
+	for _, s := range ts.Samples {
+		if math.IsNaN(s.Value) {
+			continue
+		}
+		fields := map[string]interface{}{"value": s.Value}
+		p.acc.AddFields(name, fields, tags, time.Unix(0, s.Timestamp*int64(time.Millisecond)))
+	}
+}
+
+func (p *PrometheusRemoteWriteListener) authenticateIfSet(handler http.HandlerFunc, res http.ResponseWriter, req *http.Request) {
+	if p.BasicUsername != "" && p.BasicPassword != "" {
+		reqUsername, reqPassword, ok := req.BasicAuth()
+		if !ok ||
+			subtle.ConstantTimeCompare([]byte(reqUsername), []byte(p.BasicUsername)) != 1 ||
+			subtle.ConstantTimeCompare([]byte(reqPassword), []byte(p.BasicPassword)) != 1 {
+
+			http.Error(res, "Unauthorized.", http.StatusUnauthorized)
+			return
+		}
+	}
+	handler(res, req)
+}
+
+func init() {
+	inputs.Add("prometheus_remote_write_listener", func() telegraf.Input {
+		return &PrometheusRemoteWriteListener{
+			ServiceAddress: ":9201",
+			Path:           "/write",
+			logName:        "inputs.prometheus_remote_write_listener",
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package prometheus_remote_write_listener
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/prompb"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	basicUsername = "test-username-please-ignore"
+	basicPassword = "super-secure-password!"
+)
+
+func newTestListener() *PrometheusRemoteWriteListener {
+	return &PrometheusRemoteWriteListener{
+		ServiceAddress: "localhost:0",
+		Path:           "/write",
+		MaxBodySize:    internal.Size{Size: 70000},
+	}
+}
+
+func writeURL(listener *PrometheusRemoteWriteListener) string {
+	return "http://localhost:" + strconv.Itoa(listener.Port) + listener.Path
+}
+
+func encode(req *prompb.WriteRequest) []byte {
+	return snappy.Encode(nil, req.Marshal())
+}
+
+var testRequest = &prompb.WriteRequest{
+	Timeseries: []prompb.TimeSeries{
+		{
+			Labels: []prompb.Label{
+				{Name: "__name__", Value: "http_requests_total"},
+				{Name: "code", Value: "200"},
+				{Name: "job", Value: "api"},
+			},
+			Samples: []prompb.Sample{
+				{Value: 1027, Timestamp: 1565980943000},
+				{Value: math.NaN(), Timestamp: 1565980944000},
+				{Value: 1030, Timestamp: 1565980945000},
+			},
+		},
+		{
+			Labels:  []prompb.Label{{Name: "job", Value: "unnamed"}},
+			Samples: []prompb.Sample{{Value: 1, Timestamp: 1565980943000}},
+		},
+		{
+			Labels:  []prompb.Label{{Name: "__name__", Value: "up"}},
+			Samples: []prompb.Sample{{Value: 1, Timestamp: 1565980943500}},
+		},
+	},
+}
+
+func TestWrite(t *testing.T) {
+	listener := newTestListener()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	resp, err := http.Post(writeURL(listener), "application/x-protobuf", bytes.NewBuffer(encode(testRequest)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 204, resp.StatusCode)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"http_requests_total",
+			map[string]string{"code": "200", "job": "api"},
+			map[string]interface{}{"value": 1027.0},
+			time.Unix(1565980943, 0),
+		),
+		testutil.MustMetric(
+			"http_requests_total",
+			map[string]string{"code": "200", "job": "api"},
+			map[string]interface{}{"value": 1030.0},
+			time.Unix(1565980945, 0),
+		),
+		testutil.MustMetric(
+			"up",
+			map[string]string{},
+			map[string]interface{}{"value": 1.0},
+			time.Unix(1565980943, 500000000),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestWriteInvalidBody(t *testing.T) {
+	listener := newTestListener()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	// not snappy compressed
+	resp, err := http.Post(writeURL(listener), "application/x-protobuf", bytes.NewBuffer(testRequest.Marshal()))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 400, resp.StatusCode)
+
+	// truncated message
+	buf := testRequest.Marshal()
+	resp, err = http.Post(writeURL(listener), "application/x-protobuf", bytes.NewBuffer(snappy.Encode(nil, buf[:len(buf)-1])))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 400, resp.StatusCode)
+
+	require.Len(t, acc.GetTelegrafMetrics(), 0)
+}
+
+func TestWriteTooLarge(t *testing.T) {
+	listener := newTestListener()
+	listener.MaxBodySize = internal.Size{Size: 16}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	resp, err := http.Post(writeURL(listener), "application/x-protobuf", bytes.NewBuffer(encode(testRequest)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 413, resp.StatusCode)
+}
+
+func TestWriteDecodedTooLarge(t *testing.T) {
+	listener := newTestListener()
+	listener.MaxBodySize = internal.Size{Size: 10000}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	// compresses to less than the maximum body size
+	body := snappy.Encode(nil, make([]byte, 100000))
+	require.True(t, len(body) < 10000)
+
+	resp, err := http.Post(writeURL(listener), "application/x-protobuf", bytes.NewBuffer(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 413, resp.StatusCode)
+}
+
+func TestWriteMethodNotAllowed(t *testing.T) {
+	listener := newTestListener()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	resp, err := http.Get(writeURL(listener))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 405, resp.StatusCode)
+}
+
+func TestWriteBasicAuth(t *testing.T) {
+	listener := newTestListener()
+	listener.BasicUsername = basicUsername
+	listener.BasicPassword = basicPassword
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	resp, err := http.Post(writeURL(listener), "application/x-protobuf", bytes.NewBuffer(encode(testRequest)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 401, resp.StatusCode)
+
+	req, err := http.NewRequest("POST", writeURL(listener), bytes.NewBuffer(encode(testRequest)))
+	require.NoError(t, err)
+	req.SetBasicAuth(basicUsername, basicPassword)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.EqualValues(t, 204, resp.StatusCode)
+	require.Len(t, acc.GetTelegrafMetrics(), 3)
+}
@@ -0,0 +1,116 @@
+package execd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/process"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+const sampleConfig = `
+  ## Program to run as daemon, and its arguments.
+  command = ["/usr/bin/mycollector", "--output"]
+
+  ## Delay before the process is restarted after an unexpected termination.
+  ## The delay doubles each time the process exits again soon after starting.
+  restart_delay = "10s"
+
+  ## Data format to export.
+  ## Each data format has its own unique set of configuration options, read
+  ## more about them here:
+  ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
+  data_format = "influx"
+`
+
+type Execd struct {
+	Command      []string          `toml:"command"`
+	RestartDelay internal.Duration `toml:"restart_delay"`
+
+	serializer serializers.Serializer
+	process    *process.Process
+
+	logName string
+}
+
+func New() *Execd {
+	return &Execd{
+		RestartDelay: internal.Duration{Duration: 10 * time.Second},
+		logName:      "outputs.execd",
+	}
+}
+
+func (e *Execd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Execd) Description() string {
+	return "Run executable as long-running output plugin"
+}
+
+// SetLogName sets the name of the plugin in log messages.
+func (e *Execd) SetLogName(name string) {
+	e.logName = name
+}
+
+func (e *Execd) SetSerializer(serializer serializers.Serializer) {
+	e.serializer = serializer
+}
+
+func (e *Execd) Connect() error {
+	if len(e.Command) == 0 {
+		return errors.New("command must be set")
+	}
+
+	var err error
+	e.process, err = process.New(e.Command)
+	if err != nil {
+		return err
+	}
+	e.process.LogName = e.logName
+	e.process.RestartDelay = e.RestartDelay.Duration
+	e.process.ReadStdoutFn = e.readStdout
+
+	return e.process.Start()
+}
+
+func (e *Execd) Close() error {
+	if e.process != nil {
+		e.process.Stop()
+	}
+	return nil
+}
+
+func (e *Execd) Write(metrics []telegraf.Metric) error {
+	for _, m := range metrics {
+		b, err := e.serializer.Serialize(m)
+		if err != nil {
+			log.Printf("D! [%s] Could not serialize metric: %v", e.logName, err)
+			continue
+		}
+
+		if _, err = e.process.Write(b); err != nil {
+			return fmt.Errorf("error writing metrics to process: %v", err)
+		}
+	}
+	return nil
+}
+
+// readStdout logs anything the process writes to stdout.
+func (e *Execd) readStdout(r io.Reader) {
+	e.process.ReadLines(r, func(line []byte) {
+		log.Printf("I! [%s] stdout: %s", e.logName, line)
+	})
+}
+
+func init() {
+	outputs.Add("execd", func() telegraf.Output {
+		return New()
+	})
+}
@@ -0,0 +1,63 @@
+// +build !windows
+
+package execd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-execd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	serializer, err := serializers.NewInfluxSerializer()
+	require.NoError(t, err)
+
+	e := New()
+	e.Command = []string{"sh", "-c", "cat > " + out}
+	e.SetSerializer(serializer)
+
+	require.NoError(t, e.Connect())
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "localhost"},
+			map[string]interface{}{"value": 42.0},
+			time.Unix(0, 0),
+		),
+	}
+	require.NoError(t, e.Write(metrics))
+	require.NoError(t, e.Close())
+
+	b, err := ioutil.ReadFile(out)
+	require.NoError(t, err)
+	require.Equal(t, "cpu,host=localhost value=42 0\n", string(b))
+}
+
+func TestExecd_WriteNotRunning(t *testing.T) {
+	serializer, err := serializers.NewInfluxSerializer()
+	require.NoError(t, err)
+
+	e := New()
+	e.Command = []string{"true"}
+	e.SetSerializer(serializer)
+	require.NoError(t, e.Connect())
+	defer e.Close()
+
+	// Wait for the process to exit.
+	time.Sleep(500 * time.Millisecond)
+
+	err = e.Write([]telegraf.Metric{testutil.TestMetric(1.0)})
+	require.Error(t, err)
+}
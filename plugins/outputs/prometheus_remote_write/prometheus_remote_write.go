@@ -0,0 +1,293 @@
+package prometheus_remote_write
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/promconv"
+	"github.com/influxdata/telegraf/internal/prompb"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+var sampleConfig = `
+  ## URL of the remote write endpoint.
+  url = "http://localhost:9090/api/v1/write"
+
+  ## Timeout for HTTP requests.
+  # timeout = "5s"
+
+  ## HTTP Basic Auth credentials.
+  # username = "username"
+  # password = "pa$$word"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Send string metrics as Prometheus labels.
+  ## Unless set to false all string metrics will be sent as labels.
+  # string_as_label = true
+
+  ## Additional HTTP headers
+  # [outputs.prometheus_remote_write.headers]
+  #   X-Scope-OrgID = "telegraf"
+`
+
+const (
+	defaultClientTimeout = 5 * time.Second
+	remoteWriteVersion   = "0.1.0"
+)
+
+type PrometheusRemoteWrite struct {
+	URL           string            `toml:"url"`
+	Timeout       internal.Duration `toml:"timeout"`
+	Username      string            `toml:"username"`
+	Password      string            `toml:"password"`
+	Headers       map[string]string `toml:"headers"`
+	StringAsLabel bool              `toml:"string_as_label"`
+	tls.ClientConfig
+
+	client *http.Client
+
+	logName string
+}
+
+func (p *PrometheusRemoteWrite) Description() string {
+	return "Write metrics to a Prometheus remote write endpoint"
+}
+
+// SetLogName sets the name of the plugin in log messages.
+func (p *PrometheusRemoteWrite) SetLogName(name string) {
+	p.logName = name
+}
+
+func (p *PrometheusRemoteWrite) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PrometheusRemoteWrite) Connect() error {
+	if p.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if p.Timeout.Duration == 0 {
+		p.Timeout.Duration = defaultClientTimeout
+	}
+
+	tlsCfg, err := p.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	p.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: p.Timeout.Duration,
+	}
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) Close() error {
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) Write(metrics []telegraf.Metric) error {
+	req := p.writeRequest(metrics)
+	if len(req.Timeseries) == 0 {
+		return nil
+	}
+	return p.write(snappy.Encode(nil, req.Marshal()))
+}
+
+func (p *PrometheusRemoteWrite) write(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if p.Username != "" || p.Password != "" {
+		req.SetBasicAuth(p.Username, p.Password)
+	}
+
+	req.Header.Set("User-Agent", "Telegraf/"+internal.Version())
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", remoteWriteVersion)
+	for k, v := range p.Headers {
+		if strings.ToLower(k) == "host" {
+			req.Host = v
+		}
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	// Client errors other than rate limiting are not retried, resending the
+	// same samples would fail again.
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		log.Printf("E! [%s] Samples rejected by [%s], status code: %d: %s", p.logName,
+			p.URL, resp.StatusCode, strings.TrimSpace(string(msg)))
+		return nil
+	}
+	return fmt.Errorf("when writing to [%s] received status code: %d", p.URL, resp.StatusCode)
+}
+
+// writeRequest converts the metrics to time series.  The series are named as
+// done by the prometheus_client output, the samples of a series are sorted by
+// time.
+func (p *PrometheusRemoteWrite) writeRequest(metrics []telegraf.Metric) *prompb.WriteRequest {
+	series := make(map[string]*prompb.TimeSeries)
+	add := func(name string, labels []prompb.Label, value float64, timestamp int64) {
+		if !promconv.IsValidMetricName(name) {
+			return
+		}
+		labels = append([]prompb.Label{{Name: "__name__", Value: name}}, labels...)
+		sort.Slice(labels, func(i, j int) bool {
+			return labels[i].Name < labels[j].Name
+		})
+
+		key := seriesKey(labels)
+		ts, ok := series[key]
+		if !ok {
+			ts = &prompb.TimeSeries{Labels: labels}
+			series[key] = ts
+		}
+		ts.Samples = append(ts.Samples, prompb.Sample{Value: value, Timestamp: timestamp})
+	}
+
+	for _, m := range metrics {
+		labels := p.labels(m)
+		timestamp := m.Time().UnixNano() / int64(time.Millisecond)
+		name := promconv.Sanitize(m.Name())
+
+		switch m.Type() {
+		case telegraf.Summary, telegraf.Histogram:
+			label, suffix := "quantile", ""
+			if m.Type() == telegraf.Histogram {
+				label, suffix = "le", "_bucket"
+			}
+
+			var count float64
+			var hasCount, hasInf bool
+			for _, field := range m.FieldList() {
+				value, ok := promconv.ToFloat(field.Value)
+				if !ok {
+					continue
+				}
+				switch field.Key {
+				case "count":
+					count, hasCount = value, true
+					add(name+"_count", labels, value, timestamp)
+				case "sum":
+					add(name+"_sum", labels, value, timestamp)
+				default:
+					bound, err := strconv.ParseFloat(field.Key, 64)
+					if err != nil {
+						continue
+					}
+					hasInf = hasInf || math.IsInf(bound, 1)
+					add(name+suffix, withLabel(labels, label, formatFloat(bound)), value, timestamp)
+				}
+			}
+			if m.Type() == telegraf.Histogram && hasCount && !hasInf {
+				add(name+suffix, withLabel(labels, label, "+Inf"), count, timestamp)
+			}
+		default:
+			for _, field := range m.FieldList() {
+				value, ok := promconv.ToFloat(field.Value)
+				if !ok {
+					continue
+				}
+				add(promconv.MetricName(m, field.Key), labels, value, timestamp)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	req := &prompb.WriteRequest{Timeseries: make([]prompb.TimeSeries, 0, len(series))}
+	for _, key := range keys {
+		ts := series[key]
+		sort.SliceStable(ts.Samples, func(i, j int) bool {
+			return ts.Samples[i].Timestamp < ts.Samples[j].Timestamp
+		})
+		req.Timeseries = append(req.Timeseries, *ts)
+	}
+	return req
+}
+
+// labels returns the labels of the tags, and of the string fields if enabled.
+func (p *PrometheusRemoteWrite) labels(m telegraf.Metric) []prompb.Label {
+	values := promconv.Labels(m, p.StringAsLabel)
+	labels := make([]prompb.Label, 0, len(values))
+	for name, value := range values {
+		labels = append(labels, prompb.Label{Name: name, Value: value})
+	}
+	return labels
+}
+
+func withLabel(labels []prompb.Label, name, value string) []prompb.Label {
+	result := make([]prompb.Label, 0, len(labels)+1)
+	result = append(result, labels...)
+	return append(result, prompb.Label{Name: name, Value: value})
+}
+
+func seriesKey(labels []prompb.Label) string {
+	var sb strings.Builder
+	for _, l := range labels {
+		sb.WriteString(l.Name)
+		sb.WriteByte('=')
+		sb.WriteString(l.Value)
+		sb.WriteByte(0xff)
+	}
+	return sb.String()
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func init() {
+	outputs.Add("prometheus_remote_write", func() telegraf.Output {
+		return &PrometheusRemoteWrite{
+			Timeout:       internal.Duration{Duration: defaultClientTimeout},
+			StringAsLabel: true,
+			logName:       "outputs.prometheus_remote_write",
+		}
+	})
+}
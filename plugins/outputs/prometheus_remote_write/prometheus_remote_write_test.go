@@ -0,0 +1,258 @@
+package prometheus_remote_write
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/prompb"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// stub is a remote write endpoint recording the requests.
+type stub struct {
+	status   int
+	requests []*prompb.WriteRequest
+	headers  []http.Header
+}
+
+func (s *stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	buf, err := snappy.Decode(nil, body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	req := &prompb.WriteRequest{}
+	if err := req.Unmarshal(buf); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.requests = append(s.requests, req)
+	s.headers = append(s.headers, r.Header)
+	w.WriteHeader(s.status)
+}
+
+func newOutput(t *testing.T, status int) (*PrometheusRemoteWrite, *stub, *httptest.Server) {
+	s := &stub{status: status}
+	ts := httptest.NewServer(s)
+
+	output := &PrometheusRemoteWrite{
+		URL:           ts.URL + "/api/v1/write",
+		StringAsLabel: true,
+	}
+	require.NoError(t, output.Connect())
+	return output, s, ts
+}
+
+func series(name string, samples []prompb.Sample, labels ...string) prompb.TimeSeries {
+	ts := prompb.TimeSeries{
+		Labels:  []prompb.Label{{Name: "__name__", Value: name}},
+		Samples: samples,
+	}
+	for i := 0; i < len(labels); i += 2 {
+		ts.Labels = append(ts.Labels, prompb.Label{Name: labels[i], Value: labels[i+1]})
+	}
+	return ts
+}
+
+func TestWriteScalar(t *testing.T) {
+	output, s, ts := newOutput(t, http.StatusNoContent)
+	defer ts.Close()
+	output.Headers = map[string]string{"X-Scope-OrgID": "telegraf"}
+
+	err := output.Write([]telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "example.org"},
+			map[string]interface{}{
+				"time_idle": 42.0,
+				"state":     "running",
+				"ok":        true,
+			},
+			time.Unix(2, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "example.org"},
+			map[string]interface{}{
+				"time_idle": int64(41),
+				"state":     "running",
+			},
+			time.Unix(1, 0),
+		),
+		testutil.MustMetric(
+			"http.requests",
+			map[string]string{"__reserved": "x"},
+			map[string]interface{}{"counter": uint64(1027)},
+			time.Unix(1, 0),
+			telegraf.Counter,
+		),
+	})
+	require.NoError(t, err)
+	require.Len(t, s.requests, 1)
+
+	require.Equal(t, []prompb.TimeSeries{
+		series("cpu_time_idle", []prompb.Sample{
+			{Value: 41, Timestamp: 1000},
+			{Value: 42, Timestamp: 2000},
+		}, "host", "example.org", "state", "running"),
+		series("http_requests", []prompb.Sample{{Value: 1027, Timestamp: 1000}}),
+	}, s.requests[0].Timeseries)
+
+	h := s.headers[0]
+	require.Equal(t, "snappy", h.Get("Content-Encoding"))
+	require.Equal(t, "application/x-protobuf", h.Get("Content-Type"))
+	require.Equal(t, "0.1.0", h.Get("X-Prometheus-Remote-Write-Version"))
+	require.Equal(t, "telegraf", h.Get("X-Scope-OrgID"))
+}
+
+func TestWriteHistogram(t *testing.T) {
+	output, s, ts := newOutput(t, http.StatusOK)
+	defer ts.Close()
+
+	err := output.Write([]telegraf.Metric{
+		testutil.MustMetric(
+			"request_seconds",
+			map[string]string{},
+			map[string]interface{}{
+				"0.5":   3.0,
+				"count": 4.0,
+				"sum":   2.5,
+			},
+			time.Unix(1, 0),
+			telegraf.Histogram,
+		),
+	})
+	require.NoError(t, err)
+	require.Len(t, s.requests, 1)
+
+	samples := func(v float64) []prompb.Sample {
+		return []prompb.Sample{{Value: v, Timestamp: 1000}}
+	}
+	require.Equal(t, []prompb.TimeSeries{
+		series("request_seconds_bucket", samples(4), "le", "+Inf"),
+		series("request_seconds_bucket", samples(3), "le", "0.5"),
+		series("request_seconds_count", samples(4)),
+		series("request_seconds_sum", samples(2.5)),
+	}, s.requests[0].Timeseries)
+}
+
+func TestWriteSummary(t *testing.T) {
+	output, s, ts := newOutput(t, http.StatusOK)
+	defer ts.Close()
+	output.StringAsLabel = false
+
+	err := output.Write([]telegraf.Metric{
+		testutil.MustMetric(
+			"rpc_duration_seconds",
+			map[string]string{"service": "api"},
+			map[string]interface{}{
+				"0.9":   9001.0,
+				"count": 2693.0,
+				"sum":   1.7560473e+07,
+				"name":  "ignored",
+			},
+			time.Unix(1, 0),
+			telegraf.Summary,
+		),
+	})
+	require.NoError(t, err)
+	require.Len(t, s.requests, 1)
+
+	samples := func(v float64) []prompb.Sample {
+		return []prompb.Sample{{Value: v, Timestamp: 1000}}
+	}
+	require.Equal(t, []prompb.TimeSeries{
+		series("rpc_duration_seconds_count", samples(2693), "service", "api"),
+		series("rpc_duration_seconds_sum", samples(1.7560473e+07), "service", "api"),
+		series("rpc_duration_seconds", samples(9001), "quantile", "0.9", "service", "api"),
+	}, s.requests[0].Timeseries)
+}
+
+func TestWriteNothing(t *testing.T) {
+	output, s, ts := newOutput(t, http.StatusOK)
+	defer ts.Close()
+
+	err := output.Write([]telegraf.Metric{
+		testutil.MustMetric(
+			"1xx",
+			map[string]string{},
+			map[string]interface{}{"value": 1.0},
+			time.Unix(1, 0),
+		),
+	})
+	require.NoError(t, err)
+	require.Len(t, s.requests, 0)
+}
+
+func TestWriteStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		err    bool
+	}{
+		{"rejected samples are dropped", http.StatusBadRequest, false},
+		{"rate limited", http.StatusTooManyRequests, true},
+		{"server error", http.StatusServiceUnavailable, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, _, ts := newOutput(t, tt.status)
+			defer ts.Close()
+			err := output.Write([]telegraf.Metric{
+				testutil.MustMetric(
+					"up",
+					map[string]string{},
+					map[string]interface{}{"value": 1.0},
+					time.Unix(1, 0),
+				),
+			})
+			if tt.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWriteBasicAuth(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "username", username)
+		require.Equal(t, "pa$$word", password)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	output := &PrometheusRemoteWrite{
+		URL:      ts.URL,
+		Username: "username",
+		Password: "pa$$word",
+	}
+	require.NoError(t, output.Connect())
+	require.NoError(t, output.Write([]telegraf.Metric{
+		testutil.MustMetric(
+			"up",
+			map[string]string{},
+			map[string]interface{}{"value": 1.0},
+			time.Unix(1, 0),
+		),
+	}))
+}
+
+func TestConnectRequiresURL(t *testing.T) {
+	output := &PrometheusRemoteWrite{}
+	require.Error(t, output.Connect())
+}
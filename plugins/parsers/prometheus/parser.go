@@ -0,0 +1,557 @@
+package prometheus
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Parser parses the Prometheus text exposition format and OpenMetrics.
+//
+// The metrics have the same layout as the ones of the prometheus input: the
+// name of the metric family is the measurement and the labels are the tags.
+// Counters, gauges and untyped metrics have a "counter", "gauge" or "value"
+// field; summaries have a field per quantile and histograms a field per bucket
+// upper bound, along with "count" and "sum" fields.
+type Parser struct {
+	DefaultTags map[string]string
+	TimeFunc    func() time.Time
+
+	// OpenMetrics parses the input as OpenMetrics even without the EOF
+	// marker, as when the exposition is parsed a line at a time.
+	OpenMetrics bool
+
+	// Exemplars adds a metric for each exemplar, named after the metric
+	// family with an "_exemplar" suffix.
+	Exemplars bool
+}
+
+func NewParser(defaultTags map[string]string) *Parser {
+	return &Parser{
+		DefaultTags: defaultTags,
+		TimeFunc:    time.Now,
+	}
+}
+
+// sample is a line of the exposition.
+type sample struct {
+	name     string
+	labels   []label
+	value    float64
+	time     *time.Time
+	exemplar *exemplar
+}
+
+// exemplar is the exemplar following a sample of OpenMetrics.
+type exemplar struct {
+	labels []label
+	value  float64
+	time   *time.Time
+}
+
+type label struct {
+	name  string
+	value string
+}
+
+// series accumulates the samples of a metric.
+type series struct {
+	name   string
+	typ    string
+	tags   map[string]string
+	fields map[string]interface{}
+	time   *time.Time
+}
+
+// suffixes of the sample names by family type.
+var suffixes = map[string][]string{
+	"counter":        {"_total", "_created"},
+	"summary":        {"_count", "_sum", "_created"},
+	"histogram":      {"_bucket", "_count", "_sum", "_created"},
+	"gaugehistogram": {"_bucket", "_gcount", "_gsum"},
+	"info":           {"_info"},
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	openMetrics := p.OpenMetrics || isOpenMetrics(buf)
+	types := make(map[string]string)
+
+	var order []*series
+	var exemplars []telegraf.Metric
+	index := make(map[string]*series)
+	now := p.TimeFunc()
+
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if line[0] == '#' {
+			if line == "# EOF" {
+				break
+			}
+			if name, typ, ok := parseType(line); ok {
+				types[name] = typ
+			}
+			continue
+		}
+
+		s, err := parseSample(line, openMetrics, p.Exemplars)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+
+		family, typ, suffix := familyOf(s.name, types)
+		if suffix == "_created" {
+			continue
+		}
+
+		key, special := seriesKey(family, typ, s.labels)
+		ser, ok := index[key]
+		if !ok {
+			ser = newSeries(family, typ, s.labels, special, p.DefaultTags)
+			index[key] = ser
+			order = append(order, ser)
+		}
+		if ser.time == nil {
+			ser.time = s.time
+		}
+		if err := ser.add(s, suffix); err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		if s.exemplar != nil {
+			m, err := p.exemplarMetric(family, s, now)
+			if err != nil {
+				return nil, err
+			}
+			exemplars = append(exemplars, m)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	metrics := make([]telegraf.Metric, 0, len(order)+len(exemplars))
+	for _, ser := range order {
+		if len(ser.fields) == 0 {
+			continue
+		}
+		tm := now
+		if ser.time != nil {
+			tm = *ser.time
+		}
+		m, err := metric.New(ser.name, ser.tags, ser.fields, tm, valueType(ser.typ))
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return append(metrics, exemplars...), nil
+}
+
+// exemplarMetric returns the metric of the exemplar of a sample.  The tags are
+// the labels of the sample and of the exemplar, the time is the one of the
+// exemplar, or else of the sample.
+func (p *Parser) exemplarMetric(family string, s *sample, now time.Time) (telegraf.Metric, error) {
+	tags := make(map[string]string, len(p.DefaultTags)+len(s.labels)+len(s.exemplar.labels))
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	for _, l := range s.labels {
+		tags[l.name] = l.value
+	}
+	for _, l := range s.exemplar.labels {
+		tags[l.name] = l.value
+	}
+
+	tm := now
+	if s.exemplar.time != nil {
+		tm = *s.exemplar.time
+	} else if s.time != nil {
+		tm = *s.time
+	}
+	fields := map[string]interface{}{"value": s.exemplar.value}
+	return metric.New(family+"_exemplar", tags, fields, tm, telegraf.Untyped)
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("cannot parse line: %s", line)
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+func (p *Parser) SetTimeFunc(fn metric.TimeFunc) {
+	p.TimeFunc = fn
+}
+
+// isOpenMetrics returns true if the exposition ends with the OpenMetrics EOF
+// marker, the timestamps of OpenMetrics are in seconds instead of
+// milliseconds.
+func isOpenMetrics(buf []byte) bool {
+	for _, line := range bytes.Split(buf, []byte("\n")) {
+		if string(bytes.TrimSpace(line)) == "# EOF" {
+			return true
+		}
+	}
+	return false
+}
+
+// parseType returns the family name and type of a TYPE comment.
+func parseType(line string) (string, string, bool) {
+	parts := strings.Fields(line)
+	if len(parts) != 4 || parts[0] != "#" || parts[1] != "TYPE" {
+		return "", "", false
+	}
+	return parts[2], strings.ToLower(parts[3]), true
+}
+
+// familyOf returns the family, its type and the suffix of the sample name.
+// Samples of families without a type are untyped.
+func familyOf(name string, types map[string]string) (string, string, string) {
+	if typ, ok := types[name]; ok {
+		return name, typ, ""
+	}
+	for typ, list := range suffixes {
+		for _, suffix := range list {
+			if !strings.HasSuffix(name, suffix) {
+				continue
+			}
+			family := strings.TrimSuffix(name, suffix)
+			if types[family] == typ {
+				return family, typ, suffix
+			}
+		}
+	}
+	return name, "untyped", ""
+}
+
+// specialLabel returns the label holding the field key of the type.
+func specialLabel(typ string) string {
+	switch typ {
+	case "summary":
+		return "quantile"
+	case "histogram", "gaugehistogram":
+		return "le"
+	}
+	return ""
+}
+
+// seriesKey identifies the series of a sample, the samples of a summary or
+// histogram are combined into a single metric.
+func seriesKey(family, typ string, labels []label) (string, string) {
+	special := specialLabel(typ)
+	pairs := make([]string, 0, len(labels))
+	for _, l := range labels {
+		if l.name == special {
+			continue
+		}
+		pairs = append(pairs, l.name+"="+l.value)
+	}
+	sort.Strings(pairs)
+	return family + "\xff" + strings.Join(pairs, "\xff"), special
+}
+
+func newSeries(family, typ string, labels []label, special string, defaultTags map[string]string) *series {
+	tags := make(map[string]string, len(defaultTags)+len(labels))
+	for k, v := range defaultTags {
+		tags[k] = v
+	}
+	for _, l := range labels {
+		if l.name != special {
+			tags[l.name] = l.value
+		}
+	}
+
+	fields := make(map[string]interface{})
+	switch typ {
+	case "summary", "histogram", "gaugehistogram":
+		fields["count"] = float64(0)
+		fields["sum"] = float64(0)
+	}
+	return &series{name: family, typ: typ, tags: tags, fields: fields}
+}
+
+// add sets the field of the sample.  NaN values are skipped, except for the
+// count and sum of summaries and histograms.
+func (s *series) add(smp *sample, suffix string) error {
+	switch suffix {
+	case "_count", "_gcount":
+		s.fields["count"] = smp.value
+		return nil
+	case "_sum", "_gsum":
+		s.fields["sum"] = smp.value
+		return nil
+	}
+
+	var key string
+	switch s.typ {
+	case "summary", "histogram", "gaugehistogram":
+		special := specialLabel(s.typ)
+		if s.typ != "summary" && suffix != "_bucket" {
+			return fmt.Errorf("unexpected sample %q of histogram %q", smp.name, s.name)
+		}
+		bound, ok := labelValue(smp.labels, special)
+		if !ok {
+			return fmt.Errorf("sample %q is missing the %q label", smp.name, special)
+		}
+		v, err := strconv.ParseFloat(bound, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %q label %q", special, bound)
+		}
+		if s.typ == "summary" && math.IsNaN(smp.value) {
+			return nil
+		}
+		s.fields[fmt.Sprint(v)] = smp.value
+		return nil
+	case "counter":
+		key = "counter"
+	case "gauge", "info", "stateset":
+		key = "gauge"
+	default:
+		key = "value"
+	}
+	if !math.IsNaN(smp.value) {
+		s.fields[key] = smp.value
+	}
+	return nil
+}
+
+func labelValue(labels []label, name string) (string, bool) {
+	for _, l := range labels {
+		if l.name == name {
+			return l.value, true
+		}
+	}
+	return "", false
+}
+
+func valueType(typ string) telegraf.ValueType {
+	switch typ {
+	case "counter":
+		return telegraf.Counter
+	case "gauge", "info", "stateset":
+		return telegraf.Gauge
+	case "summary":
+		return telegraf.Summary
+	case "histogram", "gaugehistogram":
+		return telegraf.Histogram
+	default:
+		return telegraf.Untyped
+	}
+}
+
+// parseSample parses a sample line:
+//
+//	name{label="value",...} value [timestamp] [# {label="value",...} value [timestamp]]
+//
+// The exemplar following the '#' is only parsed if withExemplar is set.
+func parseSample(line string, openMetrics, withExemplar bool) (*sample, error) {
+	i := 0
+	for i < len(line) && isNameChar(line[i], i == 0) {
+		i++
+	}
+	if i == 0 {
+		return nil, fmt.Errorf("invalid metric name")
+	}
+	s := &sample{name: line[:i]}
+
+	rest := strings.TrimLeft(line[i:], " \t")
+	if strings.HasPrefix(rest, "{") {
+		labels, n, err := parseLabels(rest)
+		if err != nil {
+			return nil, err
+		}
+		s.labels = labels
+		rest = rest[n:]
+	}
+
+	if i := strings.IndexByte(rest, '#'); i >= 0 {
+		if withExemplar {
+			e, err := parseExemplar(rest[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			s.exemplar = e
+		}
+		rest = rest[:i]
+	}
+	parts := strings.Fields(rest)
+	if len(parts) < 1 || len(parts) > 2 {
+		return nil, fmt.Errorf("expected a value and an optional timestamp")
+	}
+
+	var err error
+	s.value, err = strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q", parts[0])
+	}
+
+	if len(parts) == 2 {
+		tm, err := parseTimestamp(parts[1], openMetrics)
+		if err != nil {
+			return nil, err
+		}
+		s.time = tm
+	}
+	return s, nil
+}
+
+// parseExemplar parses the exemplar following the '#' of a sample:
+//
+//	{label="value",...} value [timestamp]
+//
+// The timestamp of an exemplar is always in seconds.
+func parseExemplar(s string) (*exemplar, error) {
+	s = strings.TrimLeft(s, " \t")
+	if !strings.HasPrefix(s, "{") {
+		return nil, fmt.Errorf("expected the label set of the exemplar")
+	}
+	labels, n, err := parseLabels(s)
+	if err != nil {
+		return nil, err
+	}
+	e := &exemplar{labels: labels}
+
+	parts := strings.Fields(s[n:])
+	if len(parts) < 1 || len(parts) > 2 {
+		return nil, fmt.Errorf("expected an exemplar value and an optional timestamp")
+	}
+	e.value, err = strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exemplar value %q", parts[0])
+	}
+	if len(parts) == 2 {
+		e.time, err = parseTimestamp(parts[1], true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// parseTimestamp parses a timestamp in seconds for OpenMetrics and in
+// milliseconds otherwise.  Timestamps before the epoch are ignored, as they
+// are by the prometheus input.
+func parseTimestamp(s string, openMetrics bool) (*time.Time, error) {
+	var tm time.Time
+	if openMetrics {
+		sec, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp %q", s)
+		}
+		if sec <= 0 {
+			return nil, nil
+		}
+		// rounded to microseconds to drop the float error
+		whole, frac := math.Modf(sec)
+		tm = time.Unix(int64(whole), int64(math.Round(frac*1e6))*int64(time.Microsecond))
+	} else {
+		ms, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp %q", s)
+		}
+		if ms <= 0 {
+			return nil, nil
+		}
+		tm = time.Unix(0, ms*int64(time.Millisecond))
+	}
+	return &tm, nil
+}
+
+// parseLabels parses the labels starting at the opening brace, it returns the
+// labels and the length of the label set.
+func parseLabels(s string) ([]label, int, error) {
+	var labels []label
+	i := 1
+	for {
+		i = skipSpace(s, i)
+		if i >= len(s) {
+			return nil, 0, errors.New("unterminated label set")
+		}
+		if s[i] == '}' {
+			return labels, i + 1, nil
+		}
+
+		start := i
+		for i < len(s) && isLabelChar(s[i], i == start) {
+			i++
+		}
+		if i == start {
+			return nil, 0, fmt.Errorf("invalid label name at %q", s[start:])
+		}
+		name := s[start:i]
+
+		i = skipSpace(s, i)
+		if i >= len(s) || s[i] != '=' {
+			return nil, 0, fmt.Errorf("expected '=' after label %q", name)
+		}
+		i = skipSpace(s, i+1)
+		if i >= len(s) || s[i] != '"' {
+			return nil, 0, fmt.Errorf("expected quoted value of label %q", name)
+		}
+
+		var value strings.Builder
+		i++
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] != '\\' || i+1 >= len(s) {
+				value.WriteByte(s[i])
+				continue
+			}
+			i++
+			switch s[i] {
+			case 'n':
+				value.WriteByte('\n')
+			case '\\', '"':
+				value.WriteByte(s[i])
+			default:
+				value.WriteByte('\\')
+				value.WriteByte(s[i])
+			}
+		}
+		if i >= len(s) {
+			return nil, 0, fmt.Errorf("unterminated value of label %q", name)
+		}
+		labels = append(labels, label{name: name, value: value.String()})
+
+		i = skipSpace(s, i+1)
+		if i < len(s) && s[i] == ',' {
+			i++
+		}
+	}
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+func isNameChar(c byte, first bool) bool {
+	return c == ':' || isLabelChar(c, first)
+}
+
+func isLabelChar(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(!first && c >= '0' && c <= '9')
+}
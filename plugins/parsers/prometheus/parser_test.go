@@ -0,0 +1,285 @@
+package prometheus
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newParser() *Parser {
+	parser := NewParser(nil)
+	parser.SetTimeFunc(func() time.Time { return time.Unix(42, 0) })
+	return parser
+}
+
+func TestParseCounterAndGauge(t *testing.T) {
+	parser := newParser()
+	metrics, err := parser.Parse([]byte(`
+# HELP get_token_fail_count Counter of failed Token() requests
+# TYPE get_token_fail_count counter
+get_token_fail_count 3
+# TYPE cadvisor_version_info gauge
+cadvisor_version_info{dockerVersion="1.8.2",osVersion="CentOS Linux 7 (Core)"} 1 1500000000000
+no_type{path="/var/log"} 12.5
+`))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"get_token_fail_count",
+			map[string]string{},
+			map[string]interface{}{"counter": 3.0},
+			time.Unix(42, 0),
+			telegraf.Counter,
+		),
+		testutil.MustMetric(
+			"cadvisor_version_info",
+			map[string]string{
+				"dockerVersion": "1.8.2",
+				"osVersion":     "CentOS Linux 7 (Core)",
+			},
+			map[string]interface{}{"gauge": 1.0},
+			time.Unix(1500000000, 0),
+			telegraf.Gauge,
+		),
+		testutil.MustMetric(
+			"no_type",
+			map[string]string{"path": "/var/log"},
+			map[string]interface{}{"value": 12.5},
+			time.Unix(42, 0),
+			telegraf.Untyped,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseSummary(t *testing.T) {
+	parser := newParser()
+	metrics, err := parser.Parse([]byte(`
+# TYPE http_request_duration_microseconds summary
+http_request_duration_microseconds{handler="prometheus",quantile="0.5"} 552048.506
+http_request_duration_microseconds{handler="prometheus",quantile="0.9"} 5.876804288e+06
+http_request_duration_microseconds{handler="prometheus",quantile="0.99"} NaN
+http_request_duration_microseconds_sum{handler="prometheus"} 1.8909097205e+07
+http_request_duration_microseconds_count{handler="prometheus"} 9
+http_request_duration_microseconds{handler="query",quantile="0.5"} 1
+`))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"http_request_duration_microseconds",
+			map[string]string{"handler": "prometheus"},
+			map[string]interface{}{
+				"0.5":   552048.506,
+				"0.9":   5.876804288e+06,
+				"count": 9.0,
+				"sum":   1.8909097205e+07,
+			},
+			time.Unix(42, 0),
+			telegraf.Summary,
+		),
+		testutil.MustMetric(
+			"http_request_duration_microseconds",
+			map[string]string{"handler": "query"},
+			map[string]interface{}{
+				"0.5":   1.0,
+				"count": 0.0,
+				"sum":   0.0,
+			},
+			time.Unix(42, 0),
+			telegraf.Summary,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseHistogram(t *testing.T) {
+	parser := newParser()
+	metrics, err := parser.Parse([]byte(`
+# TYPE apiserver_request_latencies histogram
+apiserver_request_latencies_bucket{resource="bindings",verb="POST",le="125000"} 1994
+apiserver_request_latencies_bucket{resource="bindings",verb="POST",le="1e+06"} 2005
+apiserver_request_latencies_bucket{resource="bindings",verb="POST",le="+Inf"} 2025
+apiserver_request_latencies_sum{resource="bindings",verb="POST"} 1.02726334e+08
+apiserver_request_latencies_count{resource="bindings",verb="POST"} 2025
+`))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"apiserver_request_latencies",
+			map[string]string{"resource": "bindings", "verb": "POST"},
+			map[string]interface{}{
+				"125000": 1994.0,
+				"1e+06":  2005.0,
+				"+Inf":   2025.0,
+				"count":  2025.0,
+				"sum":    1.02726334e+08,
+			},
+			time.Unix(42, 0),
+			telegraf.Histogram,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseOpenMetrics(t *testing.T) {
+	parser := newParser()
+	metrics, err := parser.Parse([]byte(`# TYPE acme_http_requests counter
+# HELP acme_http_requests Number of requests.
+acme_http_requests_total{path="/api"} 1027 1520879607.789 # {trace_id="KOO5S4vxi0o"} 1 1520879607.7
+acme_http_requests_created{path="/api"} 1520430000.123
+# TYPE request_seconds histogram
+request_seconds_bucket{le="0.5"} 3 # {trace_id="oHg5SJYRHA0"} 0.3
+request_seconds_bucket{le="+Inf"} 4
+request_seconds_count 4
+request_seconds_sum 2.5
+# TYPE build info
+build_info{version="1.2.3"} 1
+# EOF
+ignored 1
+`))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"acme_http_requests",
+			map[string]string{"path": "/api"},
+			map[string]interface{}{"counter": 1027.0},
+			time.Unix(1520879607, 789000000),
+			telegraf.Counter,
+		),
+		testutil.MustMetric(
+			"request_seconds",
+			map[string]string{},
+			map[string]interface{}{
+				"0.5":   3.0,
+				"+Inf":  4.0,
+				"count": 4.0,
+				"sum":   2.5,
+			},
+			time.Unix(42, 0),
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"build",
+			map[string]string{"version": "1.2.3"},
+			map[string]interface{}{"gauge": 1.0},
+			time.Unix(42, 0),
+			telegraf.Gauge,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseOpenMetricsOption(t *testing.T) {
+	parser := newParser()
+	parser.OpenMetrics = true
+	m, err := parser.ParseLine(`acme_http_requests_total{path="/api"} 1027 1520879607.789`)
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1520879607, 789000000), m.Time())
+}
+
+func TestParseExemplars(t *testing.T) {
+	parser := newParser()
+	parser.Exemplars = true
+	metrics, err := parser.Parse([]byte(`# TYPE request_seconds histogram
+request_seconds_bucket{le="0.5"} 3 1520879607 # {trace_id="oHg5SJYRHA0"} 0.3
+request_seconds_bucket{le="+Inf"} 4 1520879607 # {trace_id="KOO5S4vxi0o"} 2 1520879606.5
+request_seconds_count 4
+request_seconds_sum 2.5
+# EOF
+`))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"request_seconds",
+			map[string]string{},
+			map[string]interface{}{
+				"0.5":   3.0,
+				"+Inf":  4.0,
+				"count": 4.0,
+				"sum":   2.5,
+			},
+			time.Unix(1520879607, 0),
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"request_seconds_exemplar",
+			map[string]string{"le": "0.5", "trace_id": "oHg5SJYRHA0"},
+			map[string]interface{}{"value": 0.3},
+			time.Unix(1520879607, 0),
+			telegraf.Untyped,
+		),
+		testutil.MustMetric(
+			"request_seconds_exemplar",
+			map[string]string{"le": "+Inf", "trace_id": "KOO5S4vxi0o"},
+			map[string]interface{}{"value": 2.0},
+			time.Unix(1520879606, 500000000),
+			telegraf.Untyped,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseLabelEscapes(t *testing.T) {
+	parser := newParser()
+	m, err := parser.ParseLine(`msg{text="a \"quoted\" # text\\n",empty="", } 1`)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"text":  `a "quoted" # text\n`,
+		"empty": "",
+	}, m.Tags())
+	require.Equal(t, map[string]interface{}{"value": 1.0}, m.Fields())
+}
+
+func TestParseNaNValue(t *testing.T) {
+	parser := newParser()
+	metrics, err := parser.Parse([]byte("# TYPE up gauge\nup NaN\n"))
+	require.NoError(t, err)
+	require.Len(t, metrics, 0)
+
+	m, err := parser.ParseLine("up +Inf")
+	require.NoError(t, err)
+	require.Equal(t, math.Inf(1), m.Fields()["value"])
+}
+
+func TestParseDefaultTags(t *testing.T) {
+	parser := newParser()
+	parser.SetDefaultTags(map[string]string{"host": "localhost", "path": "default"})
+	m, err := parser.ParseLine(`requests{path="/api"} 1`)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"host": "localhost", "path": "/api"}, m.Tags())
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"no value", "requests\n"},
+		{"invalid value", "requests abc\n"},
+		{"invalid timestamp", "requests 1 abc\n"},
+		{"too many values", "requests 1 2 3\n"},
+		{"unterminated labels", `requests{path="/api" 1`},
+		{"unquoted label", "requests{path=api} 1\n"},
+		{"missing quantile", "# TYPE rpc summary\nrpc 1\n"},
+		{"invalid bucket", "# TYPE rpc histogram\nrpc_bucket{le=\"x\"} 1\n"},
+		{"exemplar without labels", "requests 1 # 2\n"},
+		{"invalid exemplar value", "requests 1 # {id=\"a\"} abc\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := newParser()
+			parser.Exemplars = true
+			_, err := parser.Parse([]byte(tt.input))
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package protobuf
+
+import (
+	"fmt"
+	"math"
+
+	descpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/influxdata/telegraf/internal/protowire"
+)
+
+// message is a decoded message, the values are by field number in the order
+// they were decoded.  Fields unknown to the schema are skipped.
+type message struct {
+	typ    *messageType
+	values map[int32][]interface{}
+}
+
+// get returns the value of a non-repeated field, the last value wins as in
+// the protobuf encoding.  It returns nil if the message does not have the
+// field.
+func (m *message) get(f *fieldType) interface{} {
+	if m == nil {
+		return nil
+	}
+	values := m.values[f.number]
+	if len(values) == 0 {
+		return nil
+	}
+	return values[len(values)-1]
+}
+
+func decodeMessage(typ *messageType, buf []byte) (*message, error) {
+	m := &message{typ: typ, values: make(map[int32][]interface{})}
+	err := protowire.Decode(buf, func(number int32, wire int, data []byte, raw uint64) error {
+		f, ok := typ.byNumber[number]
+		if !ok {
+			return nil
+		}
+
+		if wire != protowire.Bytes {
+			v, err := f.scalar(wire, raw)
+			if err != nil {
+				return err
+			}
+			m.values[number] = append(m.values[number], v)
+			return nil
+		}
+
+		switch f.kind {
+		case descpb.FieldDescriptorProto_TYPE_STRING:
+			m.values[number] = append(m.values[number], string(data))
+		case descpb.FieldDescriptorProto_TYPE_BYTES:
+			m.values[number] = append(m.values[number], data)
+		case descpb.FieldDescriptorProto_TYPE_MESSAGE:
+			sub, err := decodeMessage(f.message, data)
+			if err != nil {
+				return fmt.Errorf("%s: %v", f.name, err)
+			}
+			m.values[number] = append(m.values[number], sub)
+		default:
+			values, err := f.packed(data)
+			if err != nil {
+				return err
+			}
+			m.values[number] = append(m.values[number], values...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// packed decodes the values of a packed repeated numeric field.
+func (f *fieldType) packed(buf []byte) ([]interface{}, error) {
+	wire := protowire.Varint
+	switch f.kind {
+	case descpb.FieldDescriptorProto_TYPE_DOUBLE,
+		descpb.FieldDescriptorProto_TYPE_FIXED64,
+		descpb.FieldDescriptorProto_TYPE_SFIXED64:
+		wire = protowire.Fixed64
+	case descpb.FieldDescriptorProto_TYPE_FLOAT,
+		descpb.FieldDescriptorProto_TYPE_FIXED32,
+		descpb.FieldDescriptorProto_TYPE_SFIXED32:
+		wire = protowire.Fixed32
+	}
+
+	var values []interface{}
+	for len(buf) > 0 {
+		raw, n, err := protowire.DecodeValue(buf, wire)
+		if err != nil {
+			return nil, err
+		}
+		buf = buf[n:]
+
+		v, err := f.scalar(wire, raw)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// scalar converts the raw value of a numeric field to a metric value:
+// signed integers are int64, unsigned integers uint64, floating point
+// numbers float64 and enums the name of their value.
+func (f *fieldType) scalar(wire int, raw uint64) (interface{}, error) {
+	expected := protowire.Varint
+	var v interface{}
+	switch f.kind {
+	case descpb.FieldDescriptorProto_TYPE_INT32:
+		v = int64(int32(raw))
+	case descpb.FieldDescriptorProto_TYPE_INT64:
+		v = int64(raw)
+	case descpb.FieldDescriptorProto_TYPE_SINT32, descpb.FieldDescriptorProto_TYPE_SINT64:
+		v = int64(raw>>1) ^ -int64(raw&1)
+	case descpb.FieldDescriptorProto_TYPE_UINT32, descpb.FieldDescriptorProto_TYPE_UINT64:
+		v = raw
+	case descpb.FieldDescriptorProto_TYPE_BOOL:
+		v = raw != 0
+	case descpb.FieldDescriptorProto_TYPE_ENUM:
+		v = f.enumName(int32(raw))
+	case descpb.FieldDescriptorProto_TYPE_DOUBLE:
+		expected, v = protowire.Fixed64, math.Float64frombits(raw)
+	case descpb.FieldDescriptorProto_TYPE_FIXED64:
+		expected, v = protowire.Fixed64, raw
+	case descpb.FieldDescriptorProto_TYPE_SFIXED64:
+		expected, v = protowire.Fixed64, int64(raw)
+	case descpb.FieldDescriptorProto_TYPE_FLOAT:
+		expected, v = protowire.Fixed32, float64(math.Float32frombits(uint32(raw)))
+	case descpb.FieldDescriptorProto_TYPE_FIXED32:
+		expected, v = protowire.Fixed32, raw
+	case descpb.FieldDescriptorProto_TYPE_SFIXED32:
+		expected, v = protowire.Fixed32, int64(int32(raw))
+	default:
+		expected = protowire.Bytes
+	}
+	if wire != expected {
+		return nil, fmt.Errorf("%s: unexpected wire type %d", f.name, wire)
+	}
+	return v, nil
+}
+
+// defaultValue returns the value of a field missing from a message.
+func (f *fieldType) defaultValue() interface{} {
+	switch f.kind {
+	case descpb.FieldDescriptorProto_TYPE_INT32,
+		descpb.FieldDescriptorProto_TYPE_INT64,
+		descpb.FieldDescriptorProto_TYPE_SINT32,
+		descpb.FieldDescriptorProto_TYPE_SINT64,
+		descpb.FieldDescriptorProto_TYPE_SFIXED32,
+		descpb.FieldDescriptorProto_TYPE_SFIXED64:
+		return int64(0)
+	case descpb.FieldDescriptorProto_TYPE_UINT32,
+		descpb.FieldDescriptorProto_TYPE_UINT64,
+		descpb.FieldDescriptorProto_TYPE_FIXED32,
+		descpb.FieldDescriptorProto_TYPE_FIXED64:
+		return uint64(0)
+	case descpb.FieldDescriptorProto_TYPE_DOUBLE, descpb.FieldDescriptorProto_TYPE_FLOAT:
+		return float64(0)
+	case descpb.FieldDescriptorProto_TYPE_BOOL:
+		return false
+	case descpb.FieldDescriptorProto_TYPE_STRING:
+		return ""
+	case descpb.FieldDescriptorProto_TYPE_ENUM:
+		return f.enumName(0)
+	}
+	return nil
+}
+
+// enumName returns the name of an enum value, or its number if the value is
+// unknown to the schema.
+func (f *fieldType) enumName(v int32) string {
+	if name, ok := f.enum[v]; ok {
+		return name
+	}
+	return fmt.Sprint(v)
+}
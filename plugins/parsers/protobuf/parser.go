@@ -0,0 +1,388 @@
+package protobuf
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Config is the configuration of the protobuf parser, the paths are field
+// names separated by dots.
+type Config struct {
+	// MetricName is the name of the metrics if NameQuery is not set or
+	// selects an empty string.
+	MetricName string
+
+	// DescriptorSet is the file of the message types, as written by protoc
+	// with the --descriptor_set_out and --include_imports options.
+	DescriptorSet string
+
+	// MessageType is the full name of the type of the messages.
+	MessageType string
+
+	// MetricSelection is the path of a repeated message field, a metric is
+	// created for each of its messages.  The message itself is the metric
+	// if empty.  The other paths are relative to the metric message, or to
+	// the message itself if they start with a slash.
+	MetricSelection string
+
+	// NameQuery is the path of the name of the metric.
+	NameQuery string
+
+	// Timestamp is the path of the time of the metric, the current time is
+	// used if empty.  The field is a number or string parsed with
+	// TimestampFormat, or a google.protobuf.Timestamp.
+	Timestamp       string
+	TimestampFormat string
+	Timezone        string
+
+	// Tags and Fields are the paths of the tags and fields by key.  Without
+	// fields, all non-repeated fields of the metric message are fields.
+	Tags   map[string]string
+	Fields map[string]string
+
+	DefaultTags map[string]string
+}
+
+type Parser struct {
+	metricName      string
+	messageType     *messageType
+	selection       *path
+	name            *path
+	timestamp       *path
+	timestampFormat string
+	timezone        string
+	tags            map[string]*path
+	fields          map[string]*path
+	defaultTags     map[string]string
+
+	TimeFunc func() time.Time
+}
+
+// path is a compiled path to a field.
+type path struct {
+	// root is true if the path is relative to the message rather than the
+	// metric message.
+	root   bool
+	fields []*fieldType
+}
+
+// New loads the descriptor set and compiles the paths of the config.
+func New(config *Config) (*Parser, error) {
+	if config.DescriptorSet == "" {
+		return nil, fmt.Errorf("descriptor set is required")
+	}
+	if config.MessageType == "" {
+		return nil, fmt.Errorf("message type is required")
+	}
+	s, err := loadSchema(config.DescriptorSet)
+	if err != nil {
+		return nil, err
+	}
+	return newParser(s, config)
+}
+
+func newParser(s *schema, config *Config) (*Parser, error) {
+	p := &Parser{
+		metricName:      config.MetricName,
+		timestampFormat: config.TimestampFormat,
+		timezone:        config.Timezone,
+		tags:            make(map[string]*path, len(config.Tags)),
+		fields:          make(map[string]*path, len(config.Fields)),
+		defaultTags:     config.DefaultTags,
+		TimeFunc:        time.Now,
+	}
+	if p.timestampFormat == "" {
+		p.timestampFormat = "unix"
+	}
+	if p.timezone == "" {
+		p.timezone = "UTC"
+	}
+
+	p.messageType = s.messages[strings.TrimPrefix(config.MessageType, ".")]
+	if p.messageType == nil {
+		return nil, fmt.Errorf("unknown message type %q", config.MessageType)
+	}
+
+	metricType := p.messageType
+	if config.MetricSelection != "" {
+		sel, err := compilePath(p.messageType, p.messageType, config.MetricSelection)
+		if err != nil {
+			return nil, fmt.Errorf("metric selection: %v", err)
+		}
+		last := sel.fields[len(sel.fields)-1]
+		if last.message == nil || !last.repeated {
+			return nil, fmt.Errorf("metric selection: %q is not a repeated message", config.MetricSelection)
+		}
+		p.selection = sel
+		metricType = last.message
+	}
+
+	var err error
+	if config.NameQuery != "" {
+		if p.name, err = compileScalarPath(p.messageType, metricType, config.NameQuery); err != nil {
+			return nil, fmt.Errorf("metric name: %v", err)
+		}
+	}
+	if config.Timestamp != "" {
+		if p.timestamp, err = compilePath(p.messageType, metricType, config.Timestamp); err != nil {
+			return nil, fmt.Errorf("timestamp: %v", err)
+		}
+		last := p.timestamp.last()
+		if last.repeated || (!last.isScalar() && (last.message == nil || last.message.name != timestampType)) {
+			return nil, fmt.Errorf("timestamp: %q is not a number, string or %s", config.Timestamp, timestampType)
+		}
+	}
+	for key, expr := range config.Tags {
+		if p.tags[key], err = compileScalarPath(p.messageType, metricType, expr); err != nil {
+			return nil, fmt.Errorf("tag %q: %v", key, err)
+		}
+	}
+	for key, expr := range config.Fields {
+		if p.fields[key], err = compileScalarPath(p.messageType, metricType, expr); err != nil {
+			return nil, fmt.Errorf("field %q: %v", key, err)
+		}
+	}
+
+	if len(p.fields) == 0 {
+		used := make(map[string]bool)
+		for _, q := range append(p.tagPaths(), p.name, p.timestamp) {
+			if q != nil && !q.root {
+				used[q.String()] = true
+			}
+		}
+		p.addDefaultFields(metricType, "", nil, used)
+		if len(p.fields) == 0 {
+			return nil, fmt.Errorf("message %s has no fields", metricType.name)
+		}
+	}
+	return p, nil
+}
+
+func (p *Parser) tagPaths() []*path {
+	paths := make([]*path, 0, len(p.tags))
+	for _, q := range p.tags {
+		paths = append(paths, q)
+	}
+	return paths
+}
+
+// addDefaultFields adds the non-repeated scalar fields of the message, the
+// fields of submessages are prefixed by the name of the submessage field.
+// Fields used for the tags, name or timestamp are skipped.
+func (p *Parser) addDefaultFields(typ *messageType, prefix string, parents []*fieldType, used map[string]bool) {
+	for _, f := range typ.fields {
+		fields := append(parents[:len(parents):len(parents)], f)
+		if f.repeated || used[(&path{fields: fields}).String()] {
+			continue
+		}
+		if f.message != nil {
+			// recursive message types are not flattened any further
+			recursive := typ == f.message
+			for _, parent := range parents {
+				recursive = recursive || parent.message == f.message
+			}
+			if !recursive {
+				p.addDefaultFields(f.message, prefix+f.name+"_", fields, used)
+			}
+			continue
+		}
+		if f.isScalar() {
+			p.fields[prefix+f.name] = &path{fields: fields}
+		}
+	}
+}
+
+func compilePath(root, typ *messageType, expr string) (*path, error) {
+	q := &path{}
+	if strings.HasPrefix(expr, "/") {
+		q.root = true
+		typ = root
+		expr = expr[1:]
+	}
+	names := strings.Split(expr, ".")
+	for i, name := range names {
+		if typ == nil {
+			return nil, fmt.Errorf("%q: %s is not a message", expr, names[i-1])
+		}
+		f, ok := typ.byName[name]
+		if !ok {
+			return nil, fmt.Errorf("%q: message %s has no field %q", expr, typ.name, name)
+		}
+		if i < len(names)-1 && f.repeated {
+			return nil, fmt.Errorf("%q: %s is repeated", expr, name)
+		}
+		q.fields = append(q.fields, f)
+		typ = f.message
+	}
+	return q, nil
+}
+
+// compileScalarPath compiles the path of a non-repeated field that can be a
+// tag or field.
+func compileScalarPath(root, typ *messageType, expr string) (*path, error) {
+	q, err := compilePath(root, typ, expr)
+	if err != nil {
+		return nil, err
+	}
+	if last := q.last(); last.repeated || !last.isScalar() {
+		return nil, fmt.Errorf("%q is not a non-repeated scalar field", expr)
+	}
+	return q, nil
+}
+
+func (q *path) String() string {
+	names := make([]string, 0, len(q.fields))
+	for _, f := range q.fields {
+		names = append(names, f.name)
+	}
+	return strings.Join(names, ".")
+}
+
+func (q *path) last() *fieldType {
+	return q.fields[len(q.fields)-1]
+}
+
+// eval returns the value of the field, or its default value and false if the
+// message or one of the submessages on the path does not have the field.
+func (q *path) eval(root, m *message) (interface{}, bool) {
+	if q.root {
+		m = root
+	}
+	for _, f := range q.fields[:len(q.fields)-1] {
+		sub, _ := m.get(f).(*message)
+		if sub == nil {
+			return q.last().defaultValue(), false
+		}
+		m = sub
+	}
+	v := m.get(q.last())
+	if v == nil {
+		return q.last().defaultValue(), false
+	}
+	return v, true
+}
+
+// selectAll returns the messages of the repeated field of the path.
+func (q *path) selectAll(root *message) []*message {
+	m := root
+	for _, f := range q.fields[:len(q.fields)-1] {
+		sub, _ := m.get(f).(*message)
+		if sub == nil {
+			return nil
+		}
+		m = sub
+	}
+	values := m.values[q.last().number]
+	msgs := make([]*message, 0, len(values))
+	for _, v := range values {
+		msgs = append(msgs, v.(*message))
+	}
+	return msgs
+}
+
+func (p *Parser) SetTimeFunc(fn metric.TimeFunc) {
+	p.TimeFunc = fn
+}
+
+// Parse decodes a single message, it returns a metric for each message
+// selected by the metric selection.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	root, err := decodeMessage(p.messageType, buf)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %v", p.messageType.name, err)
+	}
+
+	now := p.TimeFunc()
+	selected := []*message{root}
+	if p.selection != nil {
+		selected = p.selection.selectAll(root)
+	}
+
+	metrics := make([]telegraf.Metric, 0, len(selected))
+	for _, m := range selected {
+		metric, err := p.parseMessage(root, m, now)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
+}
+
+func (p *Parser) parseMessage(root, m *message, now time.Time) (telegraf.Metric, error) {
+	name := p.metricName
+	if p.name != nil {
+		if v, _ := p.name.eval(root, m); fmt.Sprint(v) != "" {
+			name = fmt.Sprint(v)
+		}
+	}
+
+	tm := now
+	if p.timestamp != nil {
+		v, ok := p.timestamp.eval(root, m)
+		if !ok {
+			return nil, fmt.Errorf("timestamp not found")
+		}
+		var err error
+		if tm, err = p.parseTimestamp(v); err != nil {
+			return nil, err
+		}
+	}
+
+	tags := make(map[string]string, len(p.defaultTags)+len(p.tags))
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	for key, q := range p.tags {
+		v, _ := q.eval(root, m)
+		tags[key] = fmt.Sprint(v)
+	}
+
+	fields := make(map[string]interface{}, len(p.fields))
+	for key, q := range p.fields {
+		fields[key], _ = q.eval(root, m)
+	}
+
+	return metric.New(name, tags, fields, tm)
+}
+
+func (p *Parser) parseTimestamp(v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case *message:
+		// google.protobuf.Timestamp
+		var seconds, nanos int64
+		if f, ok := v.typ.byName["seconds"]; ok {
+			seconds, _ = v.get(f).(int64)
+		}
+		if f, ok := v.typ.byName["nanos"]; ok {
+			nanos, _ = v.get(f).(int64)
+		}
+		return time.Unix(seconds, nanos).UTC(), nil
+	case uint64:
+		return internal.ParseTimestampWithLocation(int64(v), p.timestampFormat, p.timezone)
+	case bool:
+		return time.Time{}, fmt.Errorf("timestamp is a boolean")
+	}
+	return internal.ParseTimestampWithLocation(v, p.timestampFormat, p.timezone)
+}
+
+// ParseLine parses the line as a single message.
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("cannot parse line: %s", line)
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
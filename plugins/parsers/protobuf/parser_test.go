@@ -0,0 +1,384 @@
+package protobuf
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	descpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/protowire"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// The descriptor set of:
+//
+//	syntax = "proto3";
+//	package device;
+//	import "google/protobuf/timestamp.proto";
+//
+//	enum Status {
+//	  UNKNOWN = 0;
+//	  OK = 1;
+//	  FAILED = 2;
+//	}
+//	message Location {
+//	  string site = 1;
+//	  float lat = 2;
+//	}
+//	message Reading {
+//	  string sensor = 1;
+//	  double value = 2;
+//	  sint32 delta = 3;
+//	  Status status = 4;
+//	  repeated int32 samples = 5;
+//	}
+//	message Batch {
+//	  string device = 1;
+//	  uint64 time = 2;
+//	  google.protobuf.Timestamp created = 3;
+//	  Location location = 4;
+//	  repeated Reading readings = 5;
+//	  bool online = 6;
+//	  fixed32 seq = 7;
+//	}
+var testDescriptorSet = &descpb.FileDescriptorSet{
+	File: []*descpb.FileDescriptorProto{
+		{
+			Name:    proto.String("google/protobuf/timestamp.proto"),
+			Package: proto.String("google.protobuf"),
+			MessageType: []*descpb.DescriptorProto{
+				{
+					Name: proto.String("Timestamp"),
+					Field: []*descpb.FieldDescriptorProto{
+						field("seconds", 1, descpb.FieldDescriptorProto_TYPE_INT64, ""),
+						field("nanos", 2, descpb.FieldDescriptorProto_TYPE_INT32, ""),
+					},
+				},
+			},
+		},
+		{
+			Name:       proto.String("device.proto"),
+			Package:    proto.String("device"),
+			Dependency: []string{"google/protobuf/timestamp.proto"},
+			EnumType: []*descpb.EnumDescriptorProto{
+				{
+					Name: proto.String("Status"),
+					Value: []*descpb.EnumValueDescriptorProto{
+						{Name: proto.String("UNKNOWN"), Number: proto.Int32(0)},
+						{Name: proto.String("OK"), Number: proto.Int32(1)},
+						{Name: proto.String("FAILED"), Number: proto.Int32(2)},
+					},
+				},
+			},
+			MessageType: []*descpb.DescriptorProto{
+				{
+					Name: proto.String("Location"),
+					Field: []*descpb.FieldDescriptorProto{
+						field("site", 1, descpb.FieldDescriptorProto_TYPE_STRING, ""),
+						field("lat", 2, descpb.FieldDescriptorProto_TYPE_FLOAT, ""),
+					},
+				},
+				{
+					Name: proto.String("Reading"),
+					Field: []*descpb.FieldDescriptorProto{
+						field("sensor", 1, descpb.FieldDescriptorProto_TYPE_STRING, ""),
+						field("value", 2, descpb.FieldDescriptorProto_TYPE_DOUBLE, ""),
+						field("delta", 3, descpb.FieldDescriptorProto_TYPE_SINT32, ""),
+						field("status", 4, descpb.FieldDescriptorProto_TYPE_ENUM, ".device.Status"),
+						repeated(field("samples", 5, descpb.FieldDescriptorProto_TYPE_INT32, "")),
+					},
+				},
+				{
+					Name: proto.String("Batch"),
+					Field: []*descpb.FieldDescriptorProto{
+						field("device", 1, descpb.FieldDescriptorProto_TYPE_STRING, ""),
+						field("time", 2, descpb.FieldDescriptorProto_TYPE_UINT64, ""),
+						field("created", 3, descpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Timestamp"),
+						field("location", 4, descpb.FieldDescriptorProto_TYPE_MESSAGE, ".device.Location"),
+						repeated(field("readings", 5, descpb.FieldDescriptorProto_TYPE_MESSAGE, ".device.Reading")),
+						field("online", 6, descpb.FieldDescriptorProto_TYPE_BOOL, ""),
+						field("seq", 7, descpb.FieldDescriptorProto_TYPE_FIXED32, ""),
+					},
+				},
+			},
+		},
+	},
+}
+
+func field(name string, number int32, typ descpb.FieldDescriptorProto_Type, typeName string) *descpb.FieldDescriptorProto {
+	f := &descpb.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(number),
+		Label:  descpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Type:   typ.Enum(),
+	}
+	if typeName != "" {
+		f.TypeName = proto.String(typeName)
+	}
+	return f
+}
+
+func repeated(f *descpb.FieldDescriptorProto) *descpb.FieldDescriptorProto {
+	f.Label = descpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	return f
+}
+
+// encoder writes messages in the protobuf wire format.
+type encoder []byte
+
+func (e encoder) uvarint(v uint64) encoder {
+	return protowire.AppendVarint(e, v)
+}
+
+func (e encoder) tag(number int32, wire int) encoder {
+	return protowire.AppendTag(e, number, wire)
+}
+
+func (e encoder) varint(number int32, v uint64) encoder {
+	return e.tag(number, protowire.Varint).uvarint(v)
+}
+
+func (e encoder) fixed64(number int32, v uint64) encoder {
+	return protowire.AppendFixed64(e.tag(number, protowire.Fixed64), v)
+}
+
+func (e encoder) fixed32(number int32, v uint32) encoder {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(e.tag(number, protowire.Fixed32), buf[:]...)
+}
+
+func (e encoder) bytes(number int32, v []byte) encoder {
+	return protowire.AppendBytes(e, number, v)
+}
+
+// packed writes the values of a packed repeated varint field.
+func (e encoder) packed(number int32, values ...uint64) encoder {
+	var buf encoder
+	for _, v := range values {
+		buf = buf.uvarint(v)
+	}
+	return e.bytes(number, buf)
+}
+
+func newTestParser(t *testing.T, config *Config) *Parser {
+	s, err := newSchema(testDescriptorSet)
+	require.NoError(t, err)
+	if config.MessageType == "" {
+		config.MessageType = "device.Batch"
+	}
+	p, err := newParser(s, config)
+	require.NoError(t, err)
+	p.SetTimeFunc(func() time.Time { return time.Unix(42, 0) })
+	return p
+}
+
+var testBatch = encoder(nil).
+	bytes(1, []byte("sensor-hub-1")).
+	varint(2, 1565980943123).
+	bytes(3, encoder(nil).varint(1, 1565980943).varint(2, 500)).
+	bytes(4, encoder(nil).bytes(1, []byte("berlin")).fixed32(2, math.Float32bits(52.5))).
+	bytes(5, encoder(nil).
+		bytes(1, []byte("temperature")).
+		fixed64(2, math.Float64bits(21.5)).
+		varint(3, 3). // zigzag -2
+		varint(4, 1).
+		packed(5, 1, 2)).
+	bytes(5, encoder(nil).
+		bytes(1, []byte("humidity")).
+		varint(4, 7)).
+	varint(6, 1).
+	fixed32(7, 9).
+	varint(99, 1) // unknown to the schema
+
+func TestParseDefaultFields(t *testing.T) {
+	p := newTestParser(t, &Config{
+		MetricName:  "batch",
+		Tags:        map[string]string{"device": "device"},
+		DefaultTags: map[string]string{"region": "eu"},
+	})
+
+	metrics, err := p.Parse(testBatch)
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"batch",
+			map[string]string{"device": "sensor-hub-1", "region": "eu"},
+			map[string]interface{}{
+				"time":            uint64(1565980943123),
+				"created_seconds": int64(1565980943),
+				"created_nanos":   int64(500),
+				"location_site":   "berlin",
+				"location_lat":    52.5,
+				"online":          true,
+				"seq":             uint64(9),
+			},
+			time.Unix(42, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseMetricSelection(t *testing.T) {
+	p := newTestParser(t, &Config{
+		MetricName:      "reading",
+		MetricSelection: "readings",
+		Timestamp:       "/time",
+		TimestampFormat: "unix_ms",
+		Tags: map[string]string{
+			"sensor": "sensor",
+			"device": "/device",
+			"site":   "/location.site",
+		},
+		Fields: map[string]string{
+			"value":  "value",
+			"delta":  "delta",
+			"status": "status",
+		},
+	})
+
+	metrics, err := p.Parse(testBatch)
+	require.NoError(t, err)
+
+	tm := time.Unix(1565980943, 123000000)
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"reading",
+			map[string]string{"sensor": "temperature", "device": "sensor-hub-1", "site": "berlin"},
+			map[string]interface{}{"value": 21.5, "delta": int64(-2), "status": "OK"},
+			tm,
+		),
+		testutil.MustMetric(
+			"reading",
+			map[string]string{"sensor": "humidity", "device": "sensor-hub-1", "site": "berlin"},
+			map[string]interface{}{"value": 0.0, "delta": int64(0), "status": "7"},
+			tm,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseNameAndTimestampMessage(t *testing.T) {
+	p := newTestParser(t, &Config{
+		MetricName:      "reading",
+		MetricSelection: "readings",
+		NameQuery:       "sensor",
+		Timestamp:       "/created",
+		Fields:          map[string]string{"value": "value"},
+	})
+
+	metrics, err := p.Parse(testBatch)
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	require.Equal(t, "temperature", metrics[0].Name())
+	require.Equal(t, "humidity", metrics[1].Name())
+	require.Equal(t, time.Unix(1565980943, 500).UTC(), metrics[0].Time())
+}
+
+func TestParseEmptySelection(t *testing.T) {
+	p := newTestParser(t, &Config{
+		MetricName:      "reading",
+		MetricSelection: "readings",
+	})
+
+	metrics, err := p.Parse(encoder(nil).bytes(1, []byte("sensor-hub-1")))
+	require.NoError(t, err)
+	require.Len(t, metrics, 0)
+}
+
+func TestParseMissingTimestamp(t *testing.T) {
+	p := newTestParser(t, &Config{
+		MetricName: "batch",
+		Timestamp:  "created",
+	})
+
+	_, err := p.Parse(encoder(nil).bytes(1, []byte("sensor-hub-1")))
+	require.Error(t, err)
+}
+
+func TestParseInvalid(t *testing.T) {
+	p := newTestParser(t, &Config{MetricName: "batch"})
+
+	_, err := p.Parse(testBatch[:len(testBatch)-1])
+	require.Error(t, err)
+
+	// device is a string, not a varint
+	_, err = p.Parse(encoder(nil).varint(1, 1))
+	require.Error(t, err)
+}
+
+func TestDecodePacked(t *testing.T) {
+	s, err := newSchema(testDescriptorSet)
+	require.NoError(t, err)
+	reading := s.messages["device.Reading"]
+
+	// negative int32 values are sign extended to 64 bits
+	buf := encoder(nil).packed(5, 1, math.MaxUint64).varint(5, 300)
+
+	m, err := decodeMessage(reading, buf)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{int64(1), int64(-1), int64(300)}, m.values[5])
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+	}{
+		{"unknown message type", &Config{MessageType: "device.Unknown"}},
+		{"unknown field", &Config{Fields: map[string]string{"x": "unknown"}}},
+		{"field of a scalar", &Config{Fields: map[string]string{"x": "device.length"}}},
+		{"repeated field", &Config{Fields: map[string]string{"x": "readings"}}},
+		{"path through repeated field", &Config{Tags: map[string]string{"x": "readings.sensor"}}},
+		{"message field", &Config{Fields: map[string]string{"x": "location"}}},
+		{"selection not repeated", &Config{MetricSelection: "location"}},
+		{"timestamp message", &Config{Timestamp: "location"}},
+	}
+	s, err := newSchema(testDescriptorSet)
+	require.NoError(t, err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.config.MessageType == "" {
+				tt.config.MessageType = "device.Batch"
+			}
+			_, err := newParser(s, tt.config)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestNewDescriptorSetFile(t *testing.T) {
+	buf, err := proto.Marshal(testDescriptorSet)
+	require.NoError(t, err)
+
+	f, err := ioutil.TempFile("", "descriptor")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.Write(buf)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	p, err := New(&Config{
+		MetricName:    "batch",
+		DescriptorSet: f.Name(),
+		MessageType:   ".device.Batch",
+		Fields:        map[string]string{"seq": "seq"},
+	})
+	require.NoError(t, err)
+
+	m, err := p.ParseLine(string(testBatch))
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"seq": uint64(9)}, m.Fields())
+
+	_, err = New(&Config{DescriptorSet: f.Name()})
+	require.Error(t, err)
+	_, err = New(&Config{DescriptorSet: "/nonexistent.pb", MessageType: "device.Batch"})
+	require.Error(t, err)
+}
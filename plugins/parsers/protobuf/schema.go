@@ -0,0 +1,132 @@
+package protobuf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	descpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+const timestampType = "google.protobuf.Timestamp"
+
+// schema holds the message types of a descriptor set by full name.
+type schema struct {
+	messages map[string]*messageType
+	enums    map[string]map[int32]string
+}
+
+type messageType struct {
+	name     string
+	fields   []*fieldType
+	byName   map[string]*fieldType
+	byNumber map[int32]*fieldType
+}
+
+type fieldType struct {
+	name     string
+	number   int32
+	kind     descpb.FieldDescriptorProto_Type
+	repeated bool
+
+	// message is the type of message fields and enum the value names of
+	// enum fields.
+	message *messageType
+	enum    map[int32]string
+}
+
+// loadSchema reads a descriptor set, as written by protoc with the
+// --descriptor_set_out option.
+func loadSchema(filename string) (*schema, error) {
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var set descpb.FileDescriptorSet
+	if err := proto.Unmarshal(buf, &set); err != nil {
+		return nil, fmt.Errorf("decoding descriptor set %q: %v", filename, err)
+	}
+	return newSchema(&set)
+}
+
+func newSchema(set *descpb.FileDescriptorSet) (*schema, error) {
+	s := &schema{
+		messages: make(map[string]*messageType),
+		enums:    make(map[string]map[int32]string),
+	}
+
+	// The types are registered first as fields may refer to types declared
+	// later or in other files.
+	type pending struct {
+		msg  *messageType
+		desc *descpb.DescriptorProto
+	}
+	var todo []pending
+	var register func(prefix string, msgs []*descpb.DescriptorProto, enums []*descpb.EnumDescriptorProto)
+	register = func(prefix string, msgs []*descpb.DescriptorProto, enums []*descpb.EnumDescriptorProto) {
+		for _, e := range enums {
+			values := make(map[int32]string, len(e.GetValue()))
+			for _, v := range e.GetValue() {
+				values[v.GetNumber()] = v.GetName()
+			}
+			s.enums[prefix+e.GetName()] = values
+		}
+		for _, m := range msgs {
+			msg := &messageType{
+				name:     prefix + m.GetName(),
+				byName:   make(map[string]*fieldType),
+				byNumber: make(map[int32]*fieldType),
+			}
+			s.messages[msg.name] = msg
+			todo = append(todo, pending{msg: msg, desc: m})
+			register(msg.name+".", m.GetNestedType(), m.GetEnumType())
+		}
+	}
+	for _, file := range set.GetFile() {
+		prefix := ""
+		if file.GetPackage() != "" {
+			prefix = file.GetPackage() + "."
+		}
+		register(prefix, file.GetMessageType(), file.GetEnumType())
+	}
+
+	for _, p := range todo {
+		msg, desc := p.msg, p.desc
+		for _, f := range desc.GetField() {
+			field := &fieldType{
+				name:     f.GetName(),
+				number:   f.GetNumber(),
+				kind:     f.GetType(),
+				repeated: f.GetLabel() == descpb.FieldDescriptorProto_LABEL_REPEATED,
+			}
+			typeName := strings.TrimPrefix(f.GetTypeName(), ".")
+			switch field.kind {
+			case descpb.FieldDescriptorProto_TYPE_MESSAGE:
+				if field.message = s.messages[typeName]; field.message == nil {
+					return nil, fmt.Errorf("field %s.%s: unknown message type %q", msg.name, field.name, typeName)
+				}
+			case descpb.FieldDescriptorProto_TYPE_ENUM:
+				if field.enum = s.enums[typeName]; field.enum == nil {
+					return nil, fmt.Errorf("field %s.%s: unknown enum type %q", msg.name, field.name, typeName)
+				}
+			case descpb.FieldDescriptorProto_TYPE_GROUP:
+				return nil, fmt.Errorf("field %s.%s: groups are not supported", msg.name, field.name)
+			}
+			msg.fields = append(msg.fields, field)
+			msg.byName[field.name] = field
+			msg.byNumber[field.number] = field
+		}
+	}
+	return s, nil
+}
+
+// isScalar returns true if the values of the field can be tags or fields.
+func (f *fieldType) isScalar() bool {
+	switch f.kind {
+	case descpb.FieldDescriptorProto_TYPE_MESSAGE, descpb.FieldDescriptorProto_TYPE_BYTES:
+		return false
+	}
+	return true
+}
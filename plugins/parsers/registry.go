@@ -14,8 +14,11 @@ import (
 	"github.com/influxdata/telegraf/plugins/parsers/json"
 	"github.com/influxdata/telegraf/plugins/parsers/logfmt"
 	"github.com/influxdata/telegraf/plugins/parsers/nagios"
+	"github.com/influxdata/telegraf/plugins/parsers/prometheus"
+	"github.com/influxdata/telegraf/plugins/parsers/protobuf"
 	"github.com/influxdata/telegraf/plugins/parsers/value"
 	"github.com/influxdata/telegraf/plugins/parsers/wavefront"
+	"github.com/influxdata/telegraf/plugins/parsers/xml"
 )
 
 type ParserFunc func() (Parser, error)
@@ -141,6 +144,31 @@ type Config struct {
 	CSVTimestampColumn   string   `toml:"csv_timestamp_column"`
 	CSVTimestampFormat   string   `toml:"csv_timestamp_format"`
 	CSVTrimSpace         bool     `toml:"csv_trim_space"`
+
+	// prometheus configuration
+	PrometheusOpenMetrics bool `toml:"prometheus_openmetrics"`
+	PrometheusExemplars   bool `toml:"prometheus_exemplars"`
+
+	// xml configuration, the values are XPath expressions
+	XMLMetricSelection string            `toml:"xml_metric_selection"`
+	XMLMetricName      string            `toml:"xml_metric_name"`
+	XMLTimestamp       string            `toml:"xml_timestamp"`
+	XMLTimestampFormat string            `toml:"xml_timestamp_format"`
+	XMLTimezone        string            `toml:"xml_timezone"`
+	XMLTags            map[string]string `toml:"xml_tags"`
+	XMLFields          map[string]string `toml:"xml_fields"`
+	XMLFieldTypes      map[string]string `toml:"xml_field_types"`
+
+	// protobuf configuration, the values are field paths
+	ProtobufDescriptorSet   string            `toml:"protobuf_descriptor_set"`
+	ProtobufMessageType     string            `toml:"protobuf_message_type"`
+	ProtobufMetricSelection string            `toml:"protobuf_metric_selection"`
+	ProtobufMetricName      string            `toml:"protobuf_metric_name"`
+	ProtobufTimestamp       string            `toml:"protobuf_timestamp"`
+	ProtobufTimestampFormat string            `toml:"protobuf_timestamp_format"`
+	ProtobufTimezone        string            `toml:"protobuf_timezone"`
+	ProtobufTags            map[string]string `toml:"protobuf_tags"`
+	ProtobufFields          map[string]string `toml:"protobuf_fields"`
 }
 
 // NewParser returns a Parser interface based on the given config.
@@ -209,6 +237,38 @@ func NewParser(config *Config) (Parser, error) {
 			config.DefaultTags)
 	case "logfmt":
 		parser, err = NewLogFmtParser(config.MetricName, config.DefaultTags)
+	case "prometheus":
+		parser, err = NewPrometheusParser(
+			config.DefaultTags,
+			config.PrometheusOpenMetrics,
+			config.PrometheusExemplars)
+	case "xml":
+		parser, err = NewXMLParser(&xml.Config{
+			MetricName:      config.MetricName,
+			MetricSelection: config.XMLMetricSelection,
+			NameQuery:       config.XMLMetricName,
+			Timestamp:       config.XMLTimestamp,
+			TimestampFormat: config.XMLTimestampFormat,
+			Timezone:        config.XMLTimezone,
+			Tags:            config.XMLTags,
+			Fields:          config.XMLFields,
+			FieldTypes:      config.XMLFieldTypes,
+			DefaultTags:     config.DefaultTags,
+		})
+	case "protobuf":
+		parser, err = NewProtobufParser(&protobuf.Config{
+			MetricName:      config.MetricName,
+			DescriptorSet:   config.ProtobufDescriptorSet,
+			MessageType:     config.ProtobufMessageType,
+			MetricSelection: config.ProtobufMetricSelection,
+			NameQuery:       config.ProtobufMetricName,
+			Timestamp:       config.ProtobufTimestamp,
+			TimestampFormat: config.ProtobufTimestampFormat,
+			Timezone:        config.ProtobufTimezone,
+			Tags:            config.ProtobufTags,
+			Fields:          config.ProtobufFields,
+			DefaultTags:     config.DefaultTags,
+		})
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -400,3 +460,31 @@ func NewLogFmtParser(metricName string, defaultTags map[string]string) (Parser,
 func NewWavefrontParser(defaultTags map[string]string) (Parser, error) {
 	return wavefront.NewWavefrontParser(defaultTags), nil
 }
+
+// NewPrometheusParser returns a parser of the Prometheus text exposition
+// format and of OpenMetrics.
+func NewPrometheusParser(defaultTags map[string]string, openMetrics, exemplars bool) (Parser, error) {
+	parser := prometheus.NewParser(defaultTags)
+	parser.OpenMetrics = openMetrics
+	parser.Exemplars = exemplars
+	return parser, nil
+}
+
+// NewXMLParser returns a parser selecting the metrics from XML documents.
+func NewXMLParser(config *xml.Config) (Parser, error) {
+	parser, err := xml.New(config)
+	if err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
+
+// NewProtobufParser returns a parser of protobuf messages described by a
+// descriptor set.
+func NewProtobufParser(config *protobuf.Config) (Parser, error) {
+	parser, err := protobuf.New(config)
+	if err != nil {
+		return nil, err
+	}
+	return parser, nil
+}
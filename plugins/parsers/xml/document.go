@@ -0,0 +1,111 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+type nodeKind int
+
+const (
+	rootNode nodeKind = iota
+	elementNode
+	attributeNode
+	textNode
+)
+
+// node is a node of a parsed XML document.  Names are the local names, the
+// namespaces are ignored.
+type node struct {
+	kind     nodeKind
+	name     string
+	text     string
+	parent   *node
+	attrs    []*node
+	children []*node
+}
+
+// parseDocument returns the root node of the document.  Comments, processing
+// instructions and whitespace-only text are left out.
+func parseDocument(buf []byte) (*node, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(buf))
+	decoder.Strict = false
+
+	root := &node{kind: rootNode}
+	current := root
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			elem := &node{kind: elementNode, name: t.Name.Local, parent: current}
+			for _, attr := range t.Attr {
+				elem.attrs = append(elem.attrs, &node{
+					kind:   attributeNode,
+					name:   attr.Name.Local,
+					text:   attr.Value,
+					parent: elem,
+				})
+			}
+			current.children = append(current.children, elem)
+			current = elem
+		case xml.EndElement:
+			if current.parent != nil {
+				current = current.parent
+			}
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+			current.children = append(current.children, &node{
+				kind:   textNode,
+				text:   string(t),
+				parent: current,
+			})
+		}
+	}
+	return root, nil
+}
+
+// value returns the string value of the node, the text of an element is the
+// text of all its descendants.
+func (n *node) value() string {
+	switch n.kind {
+	case attributeNode, textNode:
+		return n.text
+	}
+
+	var sb strings.Builder
+	var walk func(*node)
+	walk = func(n *node) {
+		for _, child := range n.children {
+			if child.kind == textNode {
+				sb.WriteString(child.text)
+				continue
+			}
+			walk(child)
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+// descendantsOrSelf returns the node and its descendant elements, in document
+// order.
+func (n *node) descendantsOrSelf() []*node {
+	nodes := []*node{n}
+	for _, child := range n.children {
+		if child.kind == elementNode {
+			nodes = append(nodes, child.descendantsOrSelf()...)
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,257 @@
+package xml
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Config is the configuration of the XML parser, the queries are XPath
+// expressions.
+type Config struct {
+	// MetricName is the name of the metrics if NameQuery is not set or
+	// selects nothing.
+	MetricName string
+
+	// MetricSelection selects the nodes of the metrics, a metric is created
+	// for each node.  The other queries are relative to the metric node.
+	MetricSelection string
+
+	// NameQuery selects the name of the metric.
+	NameQuery string
+
+	// Timestamp selects the time of the metric, the current time is used if
+	// empty.
+	Timestamp       string
+	TimestampFormat string
+	Timezone        string
+
+	// Tags and Fields select the values of the tags and fields by key.
+	Tags   map[string]string
+	Fields map[string]string
+
+	// FieldTypes are the types of the fields by key: "float", "int",
+	// "uint", "bool" or "string".  The type of other fields is inferred from
+	// their values.
+	FieldTypes map[string]string
+
+	DefaultTags map[string]string
+}
+
+type Parser struct {
+	metricName      string
+	selection       query
+	name            query
+	timestamp       query
+	timestampFormat string
+	timezone        string
+	tags            map[string]query
+	fields          map[string]query
+	fieldTypes      map[string]string
+	defaultTags     map[string]string
+
+	TimeFunc func() time.Time
+}
+
+// New compiles the queries of the config.
+func New(config *Config) (*Parser, error) {
+	p := &Parser{
+		metricName:      config.MetricName,
+		timestampFormat: config.TimestampFormat,
+		timezone:        config.Timezone,
+		tags:            make(map[string]query, len(config.Tags)),
+		fields:          make(map[string]query, len(config.Fields)),
+		fieldTypes:      config.FieldTypes,
+		defaultTags:     config.DefaultTags,
+		TimeFunc:        time.Now,
+	}
+	if p.timestampFormat == "" {
+		p.timestampFormat = time.RFC3339
+	}
+	if p.timezone == "" {
+		p.timezone = "UTC"
+	}
+
+	var err error
+	selection := config.MetricSelection
+	if selection == "" {
+		selection = "/"
+	}
+	if p.selection, err = compileQuery(selection); err != nil {
+		return nil, fmt.Errorf("metric selection: %v", err)
+	}
+	if config.NameQuery != "" {
+		if p.name, err = compileQuery(config.NameQuery); err != nil {
+			return nil, fmt.Errorf("metric name: %v", err)
+		}
+	}
+	if config.Timestamp != "" {
+		if p.timestamp, err = compileQuery(config.Timestamp); err != nil {
+			return nil, fmt.Errorf("timestamp: %v", err)
+		}
+	}
+	for key, expr := range config.Tags {
+		if p.tags[key], err = compileQuery(expr); err != nil {
+			return nil, fmt.Errorf("tag %q: %v", key, err)
+		}
+	}
+	for key, expr := range config.Fields {
+		if p.fields[key], err = compileQuery(expr); err != nil {
+			return nil, fmt.Errorf("field %q: %v", key, err)
+		}
+	}
+	for key, typ := range config.FieldTypes {
+		switch typ {
+		case "float", "int", "uint", "bool", "string":
+		default:
+			return nil, fmt.Errorf("field %q: unknown type %q", key, typ)
+		}
+	}
+	return p, nil
+}
+
+func (p *Parser) SetTimeFunc(fn metric.TimeFunc) {
+	p.TimeFunc = fn
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	if len(bytes.TrimSpace(buf)) == 0 {
+		return nil, nil
+	}
+
+	doc, err := parseDocument(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	now := p.TimeFunc()
+	selected := p.selection.eval(doc)
+	if selected.kind != nodeSetResult {
+		return nil, fmt.Errorf("metric selection does not select nodes")
+	}
+
+	metrics := make([]telegraf.Metric, 0, len(selected.nodes))
+	for _, n := range selected.nodes {
+		m, err := p.parseNode(n, now)
+		if err != nil {
+			return nil, err
+		}
+		if m != nil {
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics, nil
+}
+
+// parseNode creates the metric of the node, it returns nil if the node has no
+// fields.
+func (p *Parser) parseNode(n *node, now time.Time) (telegraf.Metric, error) {
+	name := p.metricName
+	if p.name != nil {
+		if v, ok := p.name.eval(n).String(); ok && v != "" {
+			name = v
+		}
+	}
+
+	tm := now
+	if p.timestamp != nil {
+		v, ok := p.timestamp.eval(n).String()
+		if !ok {
+			return nil, fmt.Errorf("timestamp not found")
+		}
+		var err error
+		tm, err = internal.ParseTimestampWithLocation(strings.TrimSpace(v), p.timestampFormat, p.timezone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tags := make(map[string]string, len(p.defaultTags)+len(p.tags))
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	for key, q := range p.tags {
+		if v, ok := q.eval(n).String(); ok {
+			tags[key] = v
+		}
+	}
+
+	fields := make(map[string]interface{}, len(p.fields))
+	for key, q := range p.fields {
+		r := q.eval(n)
+		v, ok := r.String()
+		if !ok {
+			continue
+		}
+		value, err := p.convert(key, r, v)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %v", key, err)
+		}
+		fields[key] = value
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	return metric.New(name, tags, fields, tm)
+}
+
+// convert returns the value with the type of the field.  Without a type,
+// numbers and booleans returned by functions keep their type and node values
+// are converted to the first type they can be parsed as.
+func (p *Parser) convert(key string, r result, v string) (interface{}, error) {
+	s := strings.TrimSpace(v)
+	switch p.fieldTypes[key] {
+	case "float":
+		return strconv.ParseFloat(s, 64)
+	case "int":
+		return strconv.ParseInt(s, 10, 64)
+	case "uint":
+		return strconv.ParseUint(s, 10, 64)
+	case "bool":
+		return strconv.ParseBool(s)
+	case "string":
+		return v, nil
+	}
+
+	switch r.kind {
+	case numberResult:
+		return r.number, nil
+	case booleanResult:
+		return r.boolean, nil
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	switch strings.ToLower(s) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return v, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) < 1 {
+		return nil, fmt.Errorf("cannot parse line: %s", line)
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
@@ -0,0 +1,222 @@
+package xml
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const sensors = `<?xml version="1.0" encoding="UTF-8"?>
+<!-- status page -->
+<status xmlns:hw="http://example.org/hw">
+  <device name="appliance01" timestamp="2019-08-29T12:00:00Z">
+    <uptime>86400</uptime>
+    <sensor id="1" type="temperature">
+      <value>41.5</value>
+      <ok>true</ok>
+    </sensor>
+    <sensor id="2" type="fan">
+      <value>1200</value>
+      <ok>false</ok>
+    </sensor>
+    <sensor id="3" type="fan" disabled="true">
+      <value>0</value>
+      <ok>true</ok>
+    </sensor>
+    <hw:psu state="on">PSU 1</hw:psu>
+  </device>
+</status>
+`
+
+func newParser(t *testing.T, config *Config) *Parser {
+	parser, err := New(config)
+	require.NoError(t, err)
+	parser.SetTimeFunc(func() time.Time { return time.Unix(42, 0) })
+	return parser
+}
+
+func TestParseSingleMetric(t *testing.T) {
+	parser := newParser(t, &Config{
+		MetricName:      "appliance",
+		MetricSelection: "/status/device",
+		Timestamp:       "@timestamp",
+		Tags: map[string]string{
+			"name": "@name",
+			"psu":  "psu/@state",
+		},
+		Fields: map[string]string{
+			"uptime":    "uptime",
+			"sensors":   "count(sensor)",
+			"fans_ok":   "count(sensor[@type='fan' and ok='true'])",
+			"max_value": "sensor[last()]/value",
+			"first":     "sensor[1]/@type",
+			"psu_name":  "hw:psu",
+		},
+		FieldTypes: map[string]string{
+			"max_value": "float",
+			"sensors":   "int",
+		},
+	})
+
+	actual, err := parser.Parse([]byte(sensors))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("appliance",
+			map[string]string{
+				"name": "appliance01",
+				"psu":  "on",
+			},
+			map[string]interface{}{
+				"uptime":    int64(86400),
+				"sensors":   int64(3),
+				"fans_ok":   1.0,
+				"max_value": 0.0,
+				"first":     "temperature",
+				"psu_name":  "PSU 1",
+			},
+			time.Date(2019, 8, 29, 12, 0, 0, 0, time.UTC),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestParseSelection(t *testing.T) {
+	parser := newParser(t, &Config{
+		MetricName:      "sensor",
+		MetricSelection: "//sensor[@disabled != 'true' or count(@disabled) = 0]",
+		NameQuery:       "@type",
+		Tags: map[string]string{
+			"id":     "@id",
+			"device": "../@name",
+		},
+		Fields: map[string]string{
+			"value": "value",
+			"ok":    "ok",
+			"node":  "name(.)",
+		},
+		DefaultTags: map[string]string{"source": "status"},
+	})
+
+	actual, err := parser.Parse([]byte(sensors))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("temperature",
+			map[string]string{"id": "1", "device": "appliance01", "source": "status"},
+			map[string]interface{}{"value": 41.5, "ok": true, "node": "sensor"},
+			time.Unix(42, 0),
+		),
+		testutil.MustMetric("fan",
+			map[string]string{"id": "2", "device": "appliance01", "source": "status"},
+			map[string]interface{}{"value": int64(1200), "ok": false, "node": "sensor"},
+			time.Unix(42, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestParseUnixTimestamp(t *testing.T) {
+	parser := newParser(t, &Config{
+		MetricName:      "reading",
+		MetricSelection: "/readings/reading",
+		Timestamp:       "time",
+		TimestampFormat: "unix_ms",
+		Fields: map[string]string{
+			"value": "value",
+		},
+	})
+
+	actual, err := parser.Parse([]byte(`
+<readings>
+  <reading><time>1567080000123</time><value>1</value></reading>
+  <reading><time>1567080001123</time><value>2</value></reading>
+  <reading><time>1567080002123</time></reading>
+</readings>`))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("reading",
+			map[string]string{},
+			map[string]interface{}{"value": int64(1)},
+			time.Unix(1567080000, 123000000),
+		),
+		testutil.MustMetric("reading",
+			map[string]string{},
+			map[string]interface{}{"value": int64(2)},
+			time.Unix(1567080001, 123000000),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestParseLine(t *testing.T) {
+	parser := newParser(t, &Config{
+		MetricName: "xml",
+		Fields: map[string]string{
+			"value": "/value",
+		},
+	})
+
+	m, err := parser.ParseLine("<value>42</value>")
+	require.NoError(t, err)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{testutil.MustMetric("xml", map[string]string{},
+			map[string]interface{}{"value": int64(42)}, time.Unix(42, 0))},
+		[]telegraf.Metric{m})
+}
+
+func TestParseEmpty(t *testing.T) {
+	parser := newParser(t, &Config{MetricName: "xml"})
+	metrics, err := parser.Parse([]byte(" \n"))
+	require.NoError(t, err)
+	require.Empty(t, metrics)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+		input  string
+	}{
+		{
+			name:   "invalid document",
+			config: &Config{Fields: map[string]string{"value": "value"}},
+			input:  "<value>42</unclosed>",
+		},
+		{
+			name:   "wrong field type",
+			config: &Config{Fields: map[string]string{"value": "value"}, FieldTypes: map[string]string{"value": "int"}},
+			input:  "<value>4.2</value>",
+		},
+		{
+			name:   "missing timestamp",
+			config: &Config{Fields: map[string]string{"value": "value"}, Timestamp: "time"},
+			input:  "<value>42</value>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := newParser(t, tt.config)
+			_, err := parser.Parse([]byte(tt.input))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	tests := []*Config{
+		{MetricSelection: "//sensor["},
+		{Fields: map[string]string{"value": "unknown(value)"}},
+		{Tags: map[string]string{"id": "@"}},
+		{FieldTypes: map[string]string{"value": "decimal"}},
+		{Timestamp: "'unterminated"},
+	}
+	for _, config := range tests {
+		_, err := New(config)
+		require.Error(t, err)
+	}
+}
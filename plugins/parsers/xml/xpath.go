@@ -0,0 +1,670 @@
+package xml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// This file implements the subset of XPath 1.0 used to select nodes and
+// values:
+//
+//   - absolute and relative location paths with the / and // separators
+//   - the steps name, *, ., .., @name, @*, text() and node()
+//   - predicates with positions, last(), position(), paths, string and
+//     number literals, the comparison operators and "and" and "or"
+//   - the functions name(), local-name(), count(), string() and number()
+//
+// Namespace prefixes in names are ignored, nodes are matched by local name.
+
+// query is a compiled XPath expression.
+type query interface {
+	// eval evaluates the expression with the node as the context node.
+	eval(ctx *node) result
+}
+
+// result is either a node set or a single value.
+type result struct {
+	kind    resultKind
+	nodes   []*node
+	value   string
+	number  float64
+	boolean bool
+}
+
+type resultKind int
+
+const (
+	nodeSetResult resultKind = iota
+	stringResult
+	numberResult
+	booleanResult
+)
+
+// String returns the string value of the result, the value of the first node
+// for node sets.  It returns false for empty node sets.
+func (r result) String() (string, bool) {
+	switch r.kind {
+	case stringResult:
+		return r.value, true
+	case numberResult:
+		return strconv.FormatFloat(r.number, 'f', -1, 64), true
+	case booleanResult:
+		return strconv.FormatBool(r.boolean), true
+	}
+	if len(r.nodes) == 0 {
+		return "", false
+	}
+	return r.nodes[0].value(), true
+}
+
+func (r result) bool() bool {
+	switch r.kind {
+	case stringResult:
+		return r.value != ""
+	case numberResult:
+		return r.number != 0
+	case booleanResult:
+		return r.boolean
+	}
+	return len(r.nodes) > 0
+}
+
+// compileQuery parses an XPath expression.
+func compileQuery(s string) (query, error) {
+	tokens, err := tokenizeXPath(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &xpathParser{tokens: tokens}
+	q, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q in %q", p.peek(), s)
+	}
+	return q, nil
+}
+
+type xpathToken struct {
+	text    string
+	literal bool
+}
+
+func tokenizeXPath(s string) ([]xpathToken, error) {
+	var tokens []xpathToken
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string in %q", s)
+			}
+			tokens = append(tokens, xpathToken{text: s[i+1 : i+1+end], literal: true})
+			i += end + 2
+		case strings.HasPrefix(s[i:], "//"), strings.HasPrefix(s[i:], ".."),
+			strings.HasPrefix(s[i:], "!="), strings.HasPrefix(s[i:], "<="),
+			strings.HasPrefix(s[i:], ">="):
+			tokens = append(tokens, xpathToken{text: s[i : i+2]})
+			i += 2
+		case strings.IndexByte("/.@*()[],=<>", c) >= 0:
+			tokens = append(tokens, xpathToken{text: s[i : i+1]})
+			i++
+		case isNameChar(c, true) || c >= '0' && c <= '9':
+			start := i
+			for i < len(s) && isNameChar(s[i], false) {
+				i++
+			}
+			tokens = append(tokens, xpathToken{text: s[start:i]})
+		default:
+			return nil, fmt.Errorf("unexpected character %q in %q", c, s)
+		}
+	}
+	return tokens, nil
+}
+
+func isNameChar(c byte, first bool) bool {
+	switch {
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80:
+		return true
+	case first:
+		return false
+	}
+	return c >= '0' && c <= '9' || c == '-' || c == '.' || c == ':'
+}
+
+type xpathParser struct {
+	tokens []xpathToken
+	pos    int
+}
+
+func (p *xpathParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *xpathParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos].text
+}
+
+// accept consumes the next token if it is one of the operators or keywords.
+func (p *xpathParser) accept(texts ...string) (string, bool) {
+	if p.done() || p.tokens[p.pos].literal {
+		return "", false
+	}
+	for _, text := range texts {
+		if p.tokens[p.pos].text == text {
+			p.pos++
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (p *xpathParser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		return fmt.Errorf("expected %q, found %q", text, p.peek())
+	}
+	return nil
+}
+
+func (p *xpathParser) parseOr() (query, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("or"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalQuery{or: true, left: left, right: right}
+	}
+}
+
+func (p *xpathParser) parseAnd() (query, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("and"); !ok {
+			return left, nil
+		}
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalQuery{left: left, right: right}
+	}
+}
+
+func (p *xpathParser) parseComparison() (query, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("=", "!=", "<", "<=", ">", ">=")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return &comparisonQuery{op: op, left: left, right: right}, nil
+}
+
+func (p *xpathParser) parseOperand() (query, error) {
+	if p.done() {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	tok := p.tokens[p.pos]
+	switch {
+	case tok.literal:
+		p.pos++
+		return &literalQuery{value: tok.text}, nil
+	case tok.text[0] >= '0' && tok.text[0] <= '9':
+		p.pos++
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", tok.text)
+		}
+		return &numberQuery{value: v}, nil
+	case tok.text == "(":
+		p.pos++
+		q, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return q, p.expect(")")
+	}
+
+	// A name followed by a parenthesis is a function, except for the node
+	// tests.
+	if p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].text == "(" && isNameChar(tok.text[0], true) &&
+		tok.text != "text" && tok.text != "node" {
+		return p.parseFunction()
+	}
+	return p.parsePath()
+}
+
+func (p *xpathParser) parseFunction() (query, error) {
+	name := p.tokens[p.pos].text
+	p.pos += 2
+
+	var arg query
+	if _, ok := p.accept(")"); !ok {
+		var err error
+		arg, err = p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+
+	switch name {
+	case "last", "position":
+		if arg != nil {
+			return nil, fmt.Errorf("function %s() takes no arguments", name)
+		}
+	case "name", "local-name", "string", "number":
+	case "count":
+		if arg == nil {
+			return nil, fmt.Errorf("function count() requires an argument")
+		}
+	default:
+		return nil, fmt.Errorf("unknown function %s()", name)
+	}
+	return &functionQuery{name: name, arg: arg}, nil
+}
+
+func (p *xpathParser) parsePath() (query, error) {
+	path := &pathQuery{}
+	sep, ok := p.accept("/", "//")
+	if ok {
+		path.absolute = true
+		if sep == "/" && !p.startsStep() {
+			return path, nil
+		}
+	}
+
+	for {
+		s, err := p.parseStep()
+		if err != nil {
+			return nil, err
+		}
+		s.descendants = sep == "//"
+		path.steps = append(path.steps, s)
+
+		sep, ok = p.accept("/", "//")
+		if !ok {
+			return path, nil
+		}
+	}
+}
+
+// startsStep returns true if the next token starts a location step.
+func (p *xpathParser) startsStep() bool {
+	if p.done() || p.tokens[p.pos].literal {
+		return false
+	}
+	text := p.tokens[p.pos].text
+	switch text {
+	case ".", "..", "@", "*":
+		return true
+	}
+	return isNameChar(text[0], true)
+}
+
+func (p *xpathParser) parseStep() (*step, error) {
+	s := &step{}
+	switch {
+	case p.peek() == ".":
+		p.pos++
+		s.axis = selfAxis
+		return s, nil
+	case p.peek() == "..":
+		p.pos++
+		s.axis = parentAxis
+		return s, nil
+	case p.peek() == "@":
+		p.pos++
+		s.axis = attributeAxis
+	}
+
+	if !p.startsStep() {
+		return nil, fmt.Errorf("expected a location step, found %q", p.peek())
+	}
+	s.name = p.tokens[p.pos].text
+	p.pos++
+	if i := strings.IndexByte(s.name, ':'); i >= 0 {
+		s.name = s.name[i+1:]
+	}
+
+	if s.axis != attributeAxis && (s.name == "text" || s.name == "node") {
+		if _, ok := p.accept("("); ok {
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			s.test = s.name + "()"
+		}
+	}
+
+	for {
+		if _, ok := p.accept("["); !ok {
+			return s, nil
+		}
+		pred, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		s.predicates = append(s.predicates, pred)
+	}
+}
+
+type axis int
+
+const (
+	childAxis axis = iota
+	selfAxis
+	parentAxis
+	attributeAxis
+)
+
+type step struct {
+	axis        axis
+	name        string
+	test        string
+	descendants bool
+	predicates  []query
+}
+
+// match returns true if the node passes the node test of the step.
+func (s *step) match(n *node) bool {
+	switch s.test {
+	case "text()":
+		return n.kind == textNode
+	case "node()":
+		return true
+	}
+	if n.kind != elementNode && n.kind != attributeNode {
+		return false
+	}
+	return s.name == "*" || s.name == n.name
+}
+
+// apply returns the nodes selected by the step from the context node.
+func (s *step) apply(ctx *node) []*node {
+	var candidates []*node
+	switch s.axis {
+	case selfAxis:
+		candidates = []*node{ctx}
+	case parentAxis:
+		if ctx.parent != nil {
+			candidates = []*node{ctx.parent}
+		}
+	case attributeAxis:
+		for _, attr := range ctx.attrs {
+			if s.name == "*" || s.name == attr.name {
+				candidates = append(candidates, attr)
+			}
+		}
+	default:
+		for _, child := range ctx.children {
+			if s.match(child) {
+				candidates = append(candidates, child)
+			}
+		}
+	}
+
+	for _, pred := range s.predicates {
+		var selected []*node
+		for i, n := range candidates {
+			if matchPredicate(pred, n, i+1, len(candidates)) {
+				selected = append(selected, n)
+			}
+		}
+		candidates = selected
+	}
+	return candidates
+}
+
+// predicateContext is the position of the node being tested by a predicate.
+type predicateContext struct {
+	position int
+	size     int
+}
+
+// matchPredicate evaluates a predicate, a number predicate is true if it
+// equals the position of the node.
+func matchPredicate(pred query, n *node, position, size int) bool {
+	switch q := pred.(type) {
+	case *numberQuery:
+		return int(q.value) == position
+	case *functionQuery:
+		if q.name == "last" {
+			return position == size
+		}
+	}
+
+	r := evalPredicate(pred, n, predicateContext{position: position, size: size})
+	if r.kind == numberResult {
+		return int(r.number) == position
+	}
+	return r.bool()
+}
+
+func evalPredicate(q query, n *node, pc predicateContext) result {
+	switch q := q.(type) {
+	case *functionQuery:
+		switch q.name {
+		case "last":
+			return result{kind: numberResult, number: float64(pc.size)}
+		case "position":
+			return result{kind: numberResult, number: float64(pc.position)}
+		}
+	case *comparisonQuery:
+		return compareResults(q.op, evalPredicate(q.left, n, pc), evalPredicate(q.right, n, pc))
+	case *logicalQuery:
+		left := evalPredicate(q.left, n, pc).bool()
+		if left == q.or {
+			return boolResult(left)
+		}
+		return boolResult(evalPredicate(q.right, n, pc).bool())
+	}
+	return q.eval(n)
+}
+
+type pathQuery struct {
+	absolute bool
+	steps    []*step
+}
+
+func (q *pathQuery) eval(ctx *node) result {
+	if q.absolute {
+		for ctx.parent != nil {
+			ctx = ctx.parent
+		}
+	}
+
+	nodes := []*node{ctx}
+	for _, s := range q.steps {
+		if s.descendants {
+			var expanded []*node
+			for _, n := range nodes {
+				expanded = append(expanded, n.descendantsOrSelf()...)
+			}
+			nodes = expanded
+		}
+
+		seen := make(map[*node]bool)
+		var next []*node
+		for _, n := range nodes {
+			for _, selected := range s.apply(n) {
+				if !seen[selected] {
+					seen[selected] = true
+					next = append(next, selected)
+				}
+			}
+		}
+		nodes = next
+	}
+	return result{nodes: nodes}
+}
+
+type literalQuery struct {
+	value string
+}
+
+func (q *literalQuery) eval(*node) result {
+	return result{kind: stringResult, value: q.value}
+}
+
+type numberQuery struct {
+	value float64
+}
+
+func (q *numberQuery) eval(*node) result {
+	return result{kind: numberResult, number: q.value}
+}
+
+type functionQuery struct {
+	name string
+	arg  query
+}
+
+func (q *functionQuery) eval(ctx *node) result {
+	var r result
+	if q.arg != nil {
+		r = q.arg.eval(ctx)
+	} else {
+		r = result{nodes: []*node{ctx}}
+	}
+
+	switch q.name {
+	case "name", "local-name":
+		if r.kind != nodeSetResult || len(r.nodes) == 0 {
+			return result{kind: stringResult}
+		}
+		return result{kind: stringResult, value: r.nodes[0].name}
+	case "count":
+		return result{kind: numberResult, number: float64(len(r.nodes))}
+	case "string":
+		s, _ := r.String()
+		return result{kind: stringResult, value: s}
+	case "number":
+		return result{kind: numberResult, number: toNumber(r)}
+	}
+	// last() and position() outside of a predicate.
+	return result{kind: numberResult, number: 1}
+}
+
+type comparisonQuery struct {
+	op          string
+	left, right query
+}
+
+func (q *comparisonQuery) eval(ctx *node) result {
+	return compareResults(q.op, q.left.eval(ctx), q.right.eval(ctx))
+}
+
+type logicalQuery struct {
+	or          bool
+	left, right query
+}
+
+func (q *logicalQuery) eval(ctx *node) result {
+	left := q.left.eval(ctx).bool()
+	if left == q.or {
+		return boolResult(left)
+	}
+	return boolResult(q.right.eval(ctx).bool())
+}
+
+func boolResult(b bool) result {
+	return result{kind: booleanResult, boolean: b}
+}
+
+// compareResults compares the results, a node set is compared by the values
+// of its nodes and the comparison is true if it is true for any node.  The
+// values are compared as numbers if either side is a number or the operator
+// is an ordering.
+func compareResults(op string, left, right result) result {
+	for _, l := range values(left) {
+		for _, r := range values(right) {
+			if compareValues(op, l, r, left.kind == numberResult || right.kind == numberResult) {
+				return boolResult(true)
+			}
+		}
+	}
+	return boolResult(false)
+}
+
+func values(r result) []string {
+	if r.kind != nodeSetResult {
+		s, _ := r.String()
+		return []string{s}
+	}
+	vs := make([]string, 0, len(r.nodes))
+	for _, n := range r.nodes {
+		vs = append(vs, n.value())
+	}
+	return vs
+}
+
+func compareValues(op, l, r string, numeric bool) bool {
+	if numeric || op != "=" && op != "!=" {
+		lf, lerr := strconv.ParseFloat(strings.TrimSpace(l), 64)
+		rf, rerr := strconv.ParseFloat(strings.TrimSpace(r), 64)
+		if lerr != nil || rerr != nil {
+			return op == "!="
+		}
+		switch op {
+		case "=":
+			return lf == rf
+		case "!=":
+			return lf != rf
+		case "<":
+			return lf < rf
+		case "<=":
+			return lf <= rf
+		case ">":
+			return lf > rf
+		default:
+			return lf >= rf
+		}
+	}
+	if op == "=" {
+		return l == r
+	}
+	return l != r
+}
+
+func toNumber(r result) float64 {
+	if r.kind == numberResult {
+		return r.number
+	}
+	s, _ := r.String()
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
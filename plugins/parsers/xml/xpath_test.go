@@ -0,0 +1,71 @@
+package xml
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuery(t *testing.T) {
+	doc, err := parseDocument([]byte(sensors))
+	require.NoError(t, err)
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"/status/device/@name", []string{"appliance01"}},
+		{"//value", []string{"41.5", "1200", "0"}},
+		{"//sensor[2]/@id", []string{"2"}},
+		{"//sensor[last()]/@id", []string{"3"}},
+		{"//sensor[position() > 1]/@id", []string{"2", "3"}},
+		{"//sensor[value > 100]/@type", []string{"fan"}},
+		{"//sensor[@type = 'fan'][1]/@id", []string{"2"}},
+		{"//sensor[@disabled]/@id", []string{"3"}},
+		{"//sensor[ok = 'true' and @type != 'fan']/@id", []string{"1"}},
+		{"//ok[. = 'false']/../@id", []string{"2"}},
+		{"/status/*/psu/text()", []string{"PSU 1"}},
+		{"//sensor[1]/@*", []string{"1", "temperature"}},
+		{"/status/device/node()[1]", []string{"86400"}},
+		{"//missing", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := compileQuery(tt.query)
+			require.NoError(t, err)
+			r := q.eval(doc)
+			require.Equal(t, nodeSetResult, r.kind)
+			var actual []string
+			for _, n := range r.nodes {
+				actual = append(actual, n.value())
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestQueryFunctions(t *testing.T) {
+	doc, err := parseDocument([]byte(sensors))
+	require.NoError(t, err)
+
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"count(//sensor)", "3"},
+		{"name(/status/*)", "device"},
+		{"local-name(//psu)", "psu"},
+		{"string(//sensor[2]/value)", "1200"},
+		{"number(//sensor[1]/value)", "41.5"},
+		{"count(//sensor) > 2", "true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := compileQuery(tt.query)
+			require.NoError(t, err)
+			actual, ok := q.eval(doc).String()
+			require.True(t, ok)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
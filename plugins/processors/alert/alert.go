@@ -0,0 +1,392 @@
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Name of the alert metrics.
+  # measurement = "alert"
+
+  ## How often the absent rules are checked.
+  # check_interval = "10s"
+
+  ## The state of the rules is forgotten for series without values for this
+  ## long, including raised alerts.  Must be longer than the absent_for of
+  ## the absent rules.
+  # series_timeout = "24h"
+
+  ## Rules are evaluated on each series, the series of a metric is its
+  ## measurement and tags.  An alert metric is emitted when the state of a
+  ## rule changes, it has the tags of the series and the alert and source
+  ## tags, which replace series tags with the same keys.
+  [[processors.alert.rule]]
+    ## Name of the rule, used as the alert tag of the alert metrics.
+    name = "cpu_high"
+
+    ## Measurements the rule applies to, supports globs.  All measurements
+    ## if empty.
+    measurement = ["cpu"]
+
+    ## Field the rule is evaluated on.
+    field = "usage_user"
+
+    ## Condition of the rule: "above" or "below" the threshold, or "absent"
+    ## if the series has no value for the field for the absent_for duration.
+    condition = "above"
+    threshold = 90.0
+
+    ## Number of consecutive values meeting the condition before the alert
+    ## is raised, and not meeting the condition before it is cleared.
+    # count = 1
+
+    ## Duration without values before an absent alert is raised.
+    # absent_for = "5m"
+
+    ## Level of the raised alert.
+    # level = "critical"
+`
+
+const (
+	conditionAbove  = "above"
+	conditionBelow  = "below"
+	conditionAbsent = "absent"
+
+	levelOK = "ok"
+)
+
+type Alert struct {
+	Measurement   string            `toml:"measurement"`
+	CheckInterval internal.Duration `toml:"check_interval"`
+	SeriesTimeout internal.Duration `toml:"series_timeout"`
+	Rules         []rule            `toml:"rule"`
+
+	sync.Mutex
+	init   bool
+	series map[seriesKey]*series
+
+	acc    telegraf.Accumulator
+	cancel chan struct{}
+	wg     sync.WaitGroup
+
+	logName string
+}
+
+type rule struct {
+	Name        string            `toml:"name"`
+	Measurement []string          `toml:"measurement"`
+	Field       string            `toml:"field"`
+	Condition   string            `toml:"condition"`
+	Threshold   float64           `toml:"threshold"`
+	Count       int               `toml:"count"`
+	AbsentFor   internal.Duration `toml:"absent_for"`
+	Level       string            `toml:"level"`
+
+	filter filter.Filter
+}
+
+// seriesKey identifies the state of a rule for a series.
+type seriesKey struct {
+	rule int
+	id   uint64
+}
+
+// series is the state of a rule for a series.
+type series struct {
+	name  string
+	tags  map[string]string
+	level string
+
+	// count is the number of consecutive values that would change the
+	// level.
+	count    int
+	lastSeen time.Time
+}
+
+func (a *Alert) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *Alert) Description() string {
+	return "Raise alerts when fields cross thresholds or are absent"
+}
+
+// SetLogName sets the name of the plugin in log messages.
+func (a *Alert) SetLogName(name string) {
+	a.logName = name
+}
+
+// Init checks the intervals, the rules with errors are disabled.
+func (a *Alert) Init() error {
+	if a.CheckInterval.Duration <= 0 {
+		return errors.New("check_interval must be positive")
+	}
+	if a.SeriesTimeout.Duration <= 0 {
+		return errors.New("series_timeout must be positive")
+	}
+
+	a.Lock()
+	defer a.Unlock()
+	if !a.init {
+		a.initOnce()
+	}
+
+	for _, r := range a.Rules {
+		if r.Condition == conditionAbsent && a.SeriesTimeout.Duration <= r.AbsentFor.Duration {
+			return fmt.Errorf("series_timeout must be longer than the absent_for of rule %q", r.Name)
+		}
+	}
+	return nil
+}
+
+func (a *Alert) initOnce() {
+	rules := a.Rules[:0]
+	for _, r := range a.Rules {
+		if err := r.compile(); err != nil {
+			log.Printf("E! [%s] Error in rule %q, rule disabled: %v", a.logName, r.Name, err)
+			continue
+		}
+		rules = append(rules, r)
+	}
+	a.Rules = rules
+	a.series = make(map[seriesKey]*series)
+	a.init = true
+}
+
+func (r *rule) compile() error {
+	switch r.Condition {
+	case conditionAbove, conditionBelow:
+	case conditionAbsent:
+		if r.AbsentFor.Duration <= 0 {
+			return errors.New("absent_for must be set")
+		}
+	default:
+		return fmt.Errorf("unknown condition %q", r.Condition)
+	}
+	if r.Field == "" {
+		return errors.New("field must be set")
+	}
+	if r.Count < 1 {
+		r.Count = 1
+	}
+	if r.Level == "" {
+		r.Level = "critical"
+	}
+
+	var err error
+	r.filter, err = filter.Compile(r.Measurement)
+	return err
+}
+
+// Start checks the absent rules every check interval, the alerts are added to
+// the accumulator.
+func (a *Alert) Start(acc telegraf.Accumulator) error {
+	a.Lock()
+	if !a.init {
+		a.initOnce()
+	}
+	a.Unlock()
+
+	a.acc = acc
+	a.cancel = make(chan struct{})
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		ticker := time.NewTicker(a.CheckInterval.Duration)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-a.cancel:
+				return
+			case now := <-ticker.C:
+				for _, m := range a.checkAbsent(now) {
+					a.acc.AddMetric(m)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+func (a *Alert) Stop() {
+	close(a.cancel)
+	a.wg.Wait()
+}
+
+// Apply evaluates the rules on the metrics, the metrics are passed unmodified
+// followed by the alerts raised or cleared by them.
+func (a *Alert) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	a.Lock()
+	defer a.Unlock()
+	if !a.init {
+		a.initOnce()
+	}
+
+	now := time.Now()
+	var alerts []telegraf.Metric
+	for _, m := range in {
+		for i := range a.Rules {
+			r := &a.Rules[i]
+			if r.filter != nil && !r.filter.Match(m.Name()) {
+				continue
+			}
+			value, ok := m.GetField(r.Field)
+			if !ok {
+				continue
+			}
+
+			key := seriesKey{rule: i, id: m.HashID()}
+			s, ok := a.series[key]
+			if !ok {
+				s = &series{name: m.Name(), tags: m.Tags(), level: levelOK}
+				a.series[key] = s
+			}
+			s.lastSeen = now
+
+			if alert := a.evaluate(r, s, value, m.Time()); alert != nil {
+				alerts = append(alerts, alert)
+			}
+		}
+	}
+	return append(in, alerts...)
+}
+
+// evaluate updates the state of the series with the value, it returns the
+// alert if the level changed.
+func (a *Alert) evaluate(r *rule, s *series, value interface{}, tm time.Time) telegraf.Metric {
+	if r.Condition == conditionAbsent {
+		if s.level == levelOK {
+			return nil
+		}
+		return a.transition(r, s, levelOK, nil, tm)
+	}
+
+	v, ok := toFloat(value)
+	if !ok {
+		return nil
+	}
+
+	met := v > r.Threshold
+	if r.Condition == conditionBelow {
+		met = v < r.Threshold
+	}
+	if met == (s.level != levelOK) {
+		s.count = 0
+		return nil
+	}
+
+	s.count++
+	if s.count < r.Count {
+		return nil
+	}
+	level := levelOK
+	if met {
+		level = r.Level
+	}
+	return a.transition(r, s, level, value, tm)
+}
+
+// checkAbsent raises the alerts of the absent rules for the series without
+// values for longer than the absent_for duration.
+//
+// The state of the other rules is forgotten for series that are ok and have
+// not been seen since the last check, as it is the same as the state of a new
+// series.  The state of all rules is forgotten for series that have not been
+// seen for the series timeout.
+func (a *Alert) checkAbsent(now time.Time) []telegraf.Metric {
+	a.Lock()
+	defer a.Unlock()
+
+	var alerts []telegraf.Metric
+	for key, s := range a.series {
+		if a.SeriesTimeout.Duration > 0 && now.Sub(s.lastSeen) >= a.SeriesTimeout.Duration {
+			delete(a.series, key)
+			continue
+		}
+
+		r := &a.Rules[key.rule]
+		if r.Condition != conditionAbsent {
+			if s.level == levelOK && s.count == 0 && now.Sub(s.lastSeen) >= a.CheckInterval.Duration {
+				delete(a.series, key)
+			}
+			continue
+		}
+		if s.level != levelOK {
+			continue
+		}
+		if now.Sub(s.lastSeen) < r.AbsentFor.Duration {
+			continue
+		}
+		if alert := a.transition(r, s, r.Level, nil, now); alert != nil {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+// transition sets the level of the series and returns the alert metric.
+func (a *Alert) transition(r *rule, s *series, level string, value interface{}, tm time.Time) telegraf.Metric {
+	previous := s.level
+	s.level = level
+	s.count = 0
+
+	// The alert and source tags replace the series tags with the same keys.
+	tags := make(map[string]string, len(s.tags)+2)
+	for k, v := range s.tags {
+		tags[k] = v
+	}
+	tags["alert"] = r.Name
+	tags["source"] = s.name
+
+	fields := map[string]interface{}{
+		"level":          level,
+		"previous_level": previous,
+	}
+	if value != nil {
+		fields["value"] = value
+	}
+	if r.Condition != conditionAbsent {
+		fields["threshold"] = r.Threshold
+	}
+
+	m, err := metric.New(a.Measurement, tags, fields, tm)
+	if err != nil {
+		log.Printf("E! [%s] Error creating alert: %v", a.logName, err)
+		return nil
+	}
+	log.Printf("D! [%s] Alert %q for %s %v: %s -> %s", a.logName, r.Name, s.name, s.tags, previous, level)
+	return m
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func init() {
+	processors.Add("alert", func() telegraf.Processor {
+		return &Alert{
+			Measurement:   "alert",
+			CheckInterval: internal.Duration{Duration: 10 * time.Second},
+			SeriesTimeout: internal.Duration{Duration: 24 * time.Hour},
+			logName:       "processors.alert",
+		}
+	})
+}
@@ -0,0 +1,257 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newAlert(rules ...rule) *Alert {
+	return &Alert{
+		Measurement:   "alert",
+		CheckInterval: internal.Duration{Duration: 10 * time.Second},
+		SeriesTimeout: internal.Duration{Duration: time.Hour},
+		Rules:         rules,
+	}
+}
+
+func cpu(value float64, sec int64) telegraf.Metric {
+	return testutil.MustMetric("cpu",
+		map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_user": value},
+		time.Unix(sec, 0))
+}
+
+func alertMetric(level, previous string, value float64, sec int64) telegraf.Metric {
+	return testutil.MustMetric("alert",
+		map[string]string{"cpu": "cpu0", "alert": "cpu_high", "source": "cpu"},
+		map[string]interface{}{
+			"level":          level,
+			"previous_level": previous,
+			"value":          value,
+			"threshold":      90.0,
+		},
+		time.Unix(sec, 0))
+}
+
+func TestAbove(t *testing.T) {
+	plugin := newAlert(rule{
+		Name:        "cpu_high",
+		Measurement: []string{"cpu"},
+		Field:       "usage_user",
+		Condition:   conditionAbove,
+		Threshold:   90.0,
+		Count:       2,
+	})
+
+	var actual []telegraf.Metric
+	for i, v := range []float64{95, 50, 95, 96, 97, 50, 50} {
+		actual = append(actual, plugin.Apply(cpu(v, int64(i)))...)
+	}
+
+	expected := []telegraf.Metric{
+		cpu(95, 0),
+		cpu(50, 1),
+		cpu(95, 2),
+		cpu(96, 3),
+		alertMetric("critical", "ok", 96, 3),
+		cpu(97, 4),
+		cpu(50, 5),
+		cpu(50, 6),
+		alertMetric("ok", "critical", 50, 6),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestBelow(t *testing.T) {
+	plugin := newAlert(rule{
+		Name:      "disk_low",
+		Field:     "free",
+		Condition: conditionBelow,
+		Threshold: 10,
+		Level:     "warning",
+	})
+
+	disk := func(free int64) telegraf.Metric {
+		return testutil.MustMetric("disk",
+			map[string]string{"path": "/"},
+			map[string]interface{}{"free": free},
+			time.Unix(0, 0))
+	}
+
+	actual := plugin.Apply(disk(20), disk(5))
+	expected := []telegraf.Metric{
+		disk(20),
+		disk(5),
+		testutil.MustMetric("alert",
+			map[string]string{"path": "/", "alert": "disk_low", "source": "disk"},
+			map[string]interface{}{
+				"level":          "warning",
+				"previous_level": "ok",
+				"value":          int64(5),
+				"threshold":      10.0,
+			},
+			time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestSeriesAndFilter(t *testing.T) {
+	plugin := newAlert(rule{
+		Name:        "cpu_high",
+		Measurement: []string{"cp*"},
+		Field:       "usage_user",
+		Condition:   conditionAbove,
+		Threshold:   90.0,
+	})
+
+	other := testutil.MustMetric("cpu",
+		map[string]string{"cpu": "cpu1"},
+		map[string]interface{}{"usage_user": 50.0},
+		time.Unix(0, 0))
+	mem := testutil.MustMetric("mem",
+		map[string]string{},
+		map[string]interface{}{"usage_user": 99.0},
+		time.Unix(0, 0))
+
+	actual := plugin.Apply(cpu(95, 0), other, mem)
+	expected := []telegraf.Metric{
+		cpu(95, 0),
+		other,
+		mem,
+		alertMetric("critical", "ok", 95, 0),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestAbsent(t *testing.T) {
+	plugin := newAlert(rule{
+		Name:      "cpu_absent",
+		Field:     "usage_user",
+		Condition: conditionAbsent,
+		AbsentFor: internal.Duration{Duration: time.Minute},
+	})
+
+	actual := plugin.Apply(cpu(50, 0))
+	require.Len(t, actual, 1)
+
+	require.Empty(t, plugin.checkAbsent(time.Now()))
+
+	now := time.Now().Add(2 * time.Minute)
+	alerts := plugin.checkAbsent(now)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("alert",
+			map[string]string{"cpu": "cpu0", "alert": "cpu_absent", "source": "cpu"},
+			map[string]interface{}{
+				"level":          "critical",
+				"previous_level": "ok",
+			},
+			now),
+	}
+	testutil.RequireMetricsEqual(t, expected, alerts)
+
+	// The alert is raised once.
+	require.Empty(t, plugin.checkAbsent(now.Add(time.Minute)))
+
+	actual = plugin.Apply(cpu(50, 10))
+	expected = []telegraf.Metric{
+		cpu(50, 10),
+		testutil.MustMetric("alert",
+			map[string]string{"cpu": "cpu0", "alert": "cpu_absent", "source": "cpu"},
+			map[string]interface{}{
+				"level":          "ok",
+				"previous_level": "critical",
+			},
+			time.Unix(10, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestForgetOKSeries(t *testing.T) {
+	plugin := newAlert(rule{
+		Name:      "cpu_high",
+		Field:     "usage_user",
+		Condition: conditionAbove,
+		Threshold: 90.0,
+	})
+
+	plugin.Apply(cpu(50, 0))
+	require.Len(t, plugin.series, 1)
+	plugin.checkAbsent(time.Now().Add(time.Minute))
+	require.Len(t, plugin.series, 0)
+}
+
+func TestSeriesTimeout(t *testing.T) {
+	plugin := newAlert(rule{
+		Name:      "cpu_absent",
+		Field:     "usage_user",
+		Condition: conditionAbsent,
+		AbsentFor: internal.Duration{Duration: time.Minute},
+		Level:     "critical",
+	})
+
+	plugin.Apply(cpu(50, 0))
+	now := time.Now()
+	require.Len(t, plugin.checkAbsent(now.Add(2*time.Minute)), 1)
+	require.Len(t, plugin.series, 1)
+	require.Len(t, plugin.checkAbsent(now.Add(2*time.Hour)), 0)
+	require.Len(t, plugin.series, 0)
+}
+
+func TestInitIntervals(t *testing.T) {
+	absent := rule{
+		Name:      "cpu_absent",
+		Field:     "usage_user",
+		Condition: conditionAbsent,
+		AbsentFor: internal.Duration{Duration: time.Hour},
+	}
+
+	plugin := newAlert(absent)
+	plugin.CheckInterval = internal.Duration{}
+	require.Error(t, plugin.Init())
+
+	plugin = newAlert(absent)
+	plugin.SeriesTimeout = internal.Duration{}
+	require.Error(t, plugin.Init())
+
+	plugin = newAlert(absent)
+	require.Error(t, plugin.Init())
+
+	plugin = newAlert(absent)
+	plugin.SeriesTimeout = internal.Duration{Duration: 2 * time.Hour}
+	require.NoError(t, plugin.Init())
+}
+
+func TestInvalidRule(t *testing.T) {
+	plugin := newAlert(
+		rule{Name: "unknown", Field: "usage_user", Condition: "sometimes"},
+		rule{Name: "absent", Field: "usage_user", Condition: conditionAbsent},
+		rule{Name: "nofield", Condition: conditionAbove},
+	)
+
+	actual := plugin.Apply(cpu(95, 0))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{cpu(95, 0)}, actual)
+	require.Empty(t, plugin.Rules)
+}
+
+func TestStartStop(t *testing.T) {
+	plugin := newAlert(rule{
+		Name:      "cpu_absent",
+		Field:     "usage_user",
+		Condition: conditionAbsent,
+		AbsentFor: internal.Duration{Duration: time.Millisecond},
+	})
+	plugin.CheckInterval = internal.Duration{Duration: time.Millisecond}
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Start(&acc))
+	plugin.Apply(cpu(50, 0))
+	acc.Wait(1)
+	plugin.Stop()
+
+	require.Equal(t, "critical", acc.GetTelegrafMetrics()[0].Fields()["level"])
+}
@@ -0,0 +1,157 @@
+package date
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Set the metric time from the value of a field or tag.  The source is
+  ## parsed using the source_format, which can be a Go "reference time" layout
+  ## or one of "unix", "unix_ms", "unix_us" or "unix_ns".
+  # source_field = ""
+  # source_tag = ""
+  # source_format = "2006-01-02T15:04:05Z07:00"
+
+  ## Remove the source field or tag after setting the metric time.
+  # remove_source = false
+
+  ## Shift the metric time by the offset, such as "-1h".
+  # time_offset = "0s"
+
+  ## Add the metric time to a new tag or field, formatted using the
+  ## date_format, which can be a Go "reference time" layout or one of "unix",
+  ## "unix_ms", "unix_us" or "unix_ns".  For example "Jan" for the month,
+  ## "Mon" for the weekday or "15" for the hour of the day.
+  # tag_key = "month"
+  # field_key = ""
+  # date_format = "Jan"
+
+  ## Timezone used to parse the source and format the metric time, such as
+  ## "America/New_York", "Local" or "UTC".
+  # timezone = "UTC"
+`
+
+type Date struct {
+	SourceField  string            `toml:"source_field"`
+	SourceTag    string            `toml:"source_tag"`
+	SourceFormat string            `toml:"source_format"`
+	RemoveSource bool              `toml:"remove_source"`
+	TimeOffset   internal.Duration `toml:"time_offset"`
+
+	TagKey     string `toml:"tag_key"`
+	FieldKey   string `toml:"field_key"`
+	DateFormat string `toml:"date_format"`
+	Timezone   string `toml:"timezone"`
+
+	location *time.Location
+
+	logName string
+}
+
+func (d *Date) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Date) Description() string {
+	return "Set the metric time from a field or tag and add the formatted time as a tag or field."
+}
+
+// SetLogName sets the name of the plugin in log messages.
+func (d *Date) SetLogName(name string) {
+	d.logName = name
+}
+
+func (d *Date) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if d.location == nil {
+		loc, err := time.LoadLocation(d.Timezone)
+		if err != nil {
+			log.Printf("E! [%s] Error loading timezone %q, using UTC: %v", d.logName, d.Timezone, err)
+			loc = time.UTC
+		}
+		d.location = loc
+	}
+
+	for _, metric := range in {
+		if err := d.setTime(metric); err != nil {
+			log.Printf("D! [%s] Error setting metric time: %v", d.logName, err)
+		}
+
+		if d.TimeOffset.Duration != 0 {
+			metric.SetTime(metric.Time().Add(d.TimeOffset.Duration))
+		}
+
+		if d.TagKey != "" {
+			metric.AddTag(d.TagKey, fmt.Sprint(d.format(metric.Time())))
+		}
+		if d.FieldKey != "" {
+			metric.AddField(d.FieldKey, d.format(metric.Time()))
+		}
+	}
+	return in
+}
+
+// setTime sets the metric time from the source field or tag.
+func (d *Date) setTime(metric telegraf.Metric) error {
+	var value interface{}
+	var ok bool
+	switch {
+	case d.SourceField != "":
+		value, ok = metric.GetField(d.SourceField)
+	case d.SourceTag != "":
+		value, ok = metric.GetTag(d.SourceTag)
+	default:
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+
+	ts, err := internal.ParseTimestampWithLocation(value, d.SourceFormat, d.location.String())
+	if err != nil {
+		return fmt.Errorf("parsing %v: %v", value, err)
+	}
+	metric.SetTime(ts)
+
+	if d.RemoveSource {
+		if d.SourceField != "" {
+			metric.RemoveField(d.SourceField)
+		} else {
+			metric.RemoveTag(d.SourceTag)
+		}
+	}
+	return nil
+}
+
+// format returns the time formatted with the date format, as an integer for
+// the unix formats.
+func (d *Date) format(t time.Time) interface{} {
+	switch strings.ToLower(d.DateFormat) {
+	case "unix":
+		return t.Unix()
+	case "unix_ms":
+		return t.UnixNano() / int64(time.Millisecond)
+	case "unix_us":
+		return t.UnixNano() / int64(time.Microsecond)
+	case "unix_ns":
+		return t.UnixNano()
+	}
+	return t.In(d.location).Format(d.DateFormat)
+}
+
+func init() {
+	processors.Add("date", func() telegraf.Processor {
+		return &Date{
+			SourceFormat: time.RFC3339,
+			DateFormat:   "Jan",
+			Timezone:     "UTC",
+			logName:      "processors.date",
+		}
+	})
+}
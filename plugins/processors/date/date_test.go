@@ -0,0 +1,135 @@
+package date
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newDate() *Date {
+	return &Date{
+		SourceFormat: time.RFC3339,
+		DateFormat:   "Jan",
+		Timezone:     "UTC",
+	}
+}
+
+func TestMonthTag(t *testing.T) {
+	d := newDate()
+	d.TagKey = "month"
+
+	now := time.Date(2019, time.March, 10, 12, 0, 0, 0, time.UTC)
+	m := testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1.0}, now)
+	out := d.Apply(m)
+
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"month": "Mar"},
+			map[string]interface{}{"value": 1.0},
+			now),
+	}, out)
+}
+
+func TestHourFieldTimezone(t *testing.T) {
+	d := newDate()
+	d.FieldKey = "hour"
+	d.DateFormat = "15"
+	d.Timezone = "Asia/Tokyo"
+
+	now := time.Date(2019, time.March, 10, 12, 0, 0, 0, time.UTC)
+	m := testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1.0}, now)
+	out := d.Apply(m)
+
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			nil,
+			map[string]interface{}{"value": 1.0, "hour": "21"},
+			now),
+	}, out)
+}
+
+func TestUnixField(t *testing.T) {
+	d := newDate()
+	d.FieldKey = "timestamp"
+	d.DateFormat = "unix_ms"
+
+	now := time.Unix(1552219200, 5e6)
+	m := testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1.0}, now)
+	out := d.Apply(m)
+
+	v, ok := out[0].GetField("timestamp")
+	require.True(t, ok)
+	require.Equal(t, int64(1552219200005), v)
+}
+
+func TestSetTimeFromField(t *testing.T) {
+	d := newDate()
+	d.SourceField = "created"
+	d.SourceFormat = "unix"
+	d.RemoveSource = true
+
+	m := testutil.MustMetric("event", nil,
+		map[string]interface{}{"value": 1.0, "created": int64(1552219200)},
+		time.Unix(0, 0))
+	out := d.Apply(m)
+
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		testutil.MustMetric("event",
+			nil,
+			map[string]interface{}{"value": 1.0},
+			time.Unix(1552219200, 0)),
+	}, out)
+}
+
+func TestSetTimeFromTagLayout(t *testing.T) {
+	d := newDate()
+	d.SourceTag = "date"
+	d.SourceFormat = "2006-01-02 15:04"
+	d.Timezone = "America/New_York"
+
+	m := testutil.MustMetric("event",
+		map[string]string{"date": "2019-03-10 12:30"},
+		map[string]interface{}{"value": 1.0},
+		time.Unix(0, 0))
+	out := d.Apply(m)
+
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	require.True(t, time.Date(2019, time.March, 10, 12, 30, 0, 0, loc).Equal(out[0].Time()))
+	require.True(t, out[0].HasTag("date"))
+}
+
+func TestSetTimeInvalidSourceKeepsTime(t *testing.T) {
+	d := newDate()
+	d.SourceTag = "date"
+
+	now := time.Unix(100, 0)
+	m := testutil.MustMetric("event",
+		map[string]string{"date": "yesterday"},
+		map[string]interface{}{"value": 1.0},
+		now)
+	out := d.Apply(m)
+	require.Equal(t, now, out[0].Time())
+}
+
+func TestTimeOffset(t *testing.T) {
+	d := newDate()
+	d.TimeOffset = internal.Duration{Duration: -time.Hour}
+	d.TagKey = "hour"
+	d.DateFormat = "15"
+
+	now := time.Date(2019, time.March, 10, 12, 0, 0, 0, time.UTC)
+	m := testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1.0}, now)
+	out := d.Apply(m)
+
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"hour": "11"},
+			map[string]interface{}{"value": 1.0},
+			now.Add(-time.Hour)),
+	}, out)
+}
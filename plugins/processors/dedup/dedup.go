@@ -0,0 +1,190 @@
+package dedup
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+var sampleConfig = `
+  ## Maximum time to suppress unchanged values, a value is emitted again once
+  ## this much time has passed since it was last emitted.
+  # dedup_interval = "10m"
+
+  ## Numeric values that differ from the last emitted value by no more than
+  ## the tolerance are considered unchanged.
+  # tolerance = 0.0
+
+  ## With "metric" a metric is dropped if none of its fields changed, and
+  ## passed unmodified otherwise.  With "field" the unchanged fields are
+  ## removed from the metric, the metric is dropped if no fields remain.
+  # mode = "metric"
+`
+
+const (
+	modeMetric = "metric"
+	modeField  = "field"
+)
+
+type Dedup struct {
+	DedupInterval internal.Duration `toml:"dedup_interval"`
+	Tolerance     float64           `toml:"tolerance"`
+	Mode          string            `toml:"mode"`
+
+	// cache holds the last emitted fields of each series, by the series
+	// hash.
+	cache map[uint64]map[string]value
+
+	// The values are compared by the time of the metrics, latest is the
+	// newest metric time seen and is used as the current time for cleanup.
+	latest      time.Time
+	lastCleanup time.Time
+}
+
+// value is a field value and the time it was emitted.
+type value struct {
+	v    interface{}
+	time time.Time
+}
+
+func (d *Dedup) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Dedup) Description() string {
+	return "Filter metrics with repeating field values"
+}
+
+// Init checks the mode.
+func (d *Dedup) Init() error {
+	switch d.Mode {
+	case modeMetric, modeField:
+		return nil
+	default:
+		return fmt.Errorf("invalid mode %q, must be %q or %q", d.Mode, modeMetric, modeField)
+	}
+}
+
+func (d *Dedup) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if d.cache == nil {
+		d.cache = make(map[uint64]map[string]value)
+	}
+	for _, metric := range in {
+		if metric.Time().After(d.latest) {
+			d.latest = metric.Time()
+		}
+	}
+	d.cleanup(d.latest)
+
+	out := in[:0]
+	for _, metric := range in {
+		if d.dedup(metric) {
+			out = append(out, metric)
+		} else {
+			metric.Drop()
+		}
+	}
+	return out
+}
+
+// dedup suppresses the unchanged fields of the metric, it returns false if
+// the metric should be dropped.
+func (d *Dedup) dedup(metric telegraf.Metric) bool {
+	id := metric.HashID()
+	last, ok := d.cache[id]
+	if !ok {
+		last = make(map[string]value)
+		d.cache[id] = last
+	}
+
+	now := metric.Time()
+	var changed, unchanged []string
+	for _, field := range metric.FieldList() {
+		prev, ok := last[field.Key]
+		if ok && now.Sub(prev.time) < d.DedupInterval.Duration && d.equal(prev.v, field.Value) {
+			unchanged = append(unchanged, field.Key)
+			continue
+		}
+		changed = append(changed, field.Key)
+	}
+
+	if len(changed) == 0 {
+		return false
+	}
+
+	switch d.Mode {
+	case modeField:
+		for _, key := range unchanged {
+			metric.RemoveField(key)
+		}
+	default:
+		// The whole metric is emitted so the unchanged fields are emitted
+		// too.
+		changed = append(changed, unchanged...)
+	}
+
+	for _, key := range changed {
+		v, _ := metric.GetField(key)
+		last[key] = value{v: v, time: now}
+	}
+	return true
+}
+
+// equal returns true if the values are equal, numeric values are equal if
+// they differ by no more than the tolerance.
+func (d *Dedup) equal(a, b interface{}) bool {
+	fa, aok := toFloat(a)
+	fb, bok := toFloat(b)
+	if aok && bok {
+		if d.Tolerance <= 0 {
+			return a == b || fa == fb
+		}
+		return math.Abs(fa-fb) <= d.Tolerance
+	}
+	return a == b
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// cleanup removes the series that have not been emitted for longer than the
+// dedup interval, by the time of the newest metric.
+func (d *Dedup) cleanup(now time.Time) {
+	if now.Sub(d.lastCleanup) < d.DedupInterval.Duration {
+		return
+	}
+	d.lastCleanup = now
+
+	for id, fields := range d.cache {
+		for key, v := range fields {
+			if now.Sub(v.time) >= d.DedupInterval.Duration {
+				delete(fields, key)
+			}
+		}
+		if len(fields) == 0 {
+			delete(d.cache, id)
+		}
+	}
+}
+
+func init() {
+	processors.Add("dedup", func() telegraf.Processor {
+		return &Dedup{
+			DedupInterval: internal.Duration{Duration: 10 * time.Minute},
+			Mode:          modeMetric,
+		}
+	})
+}
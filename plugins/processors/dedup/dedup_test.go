@@ -0,0 +1,161 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newDedup() *Dedup {
+	return &Dedup{
+		DedupInterval: internal.Duration{Duration: 10 * time.Minute},
+		Mode:          modeMetric,
+	}
+}
+
+func newMetric(fields map[string]interface{}, ts time.Time) telegraf.Metric {
+	return testutil.MustMetric("snmp",
+		map[string]string{"agent_host": "router"},
+		fields,
+		ts,
+	)
+}
+
+func TestDedupUnchangedDropped(t *testing.T) {
+	d := newDedup()
+	now := time.Now()
+
+	out := d.Apply(newMetric(map[string]interface{}{"value": int64(1)}, now))
+	require.Len(t, out, 1)
+
+	out = d.Apply(newMetric(map[string]interface{}{"value": int64(1)}, now.Add(time.Minute)))
+	require.Len(t, out, 0)
+
+	out = d.Apply(newMetric(map[string]interface{}{"value": int64(2)}, now.Add(2*time.Minute)))
+	require.Len(t, out, 1)
+}
+
+func TestDedupSeriesAreSeparate(t *testing.T) {
+	d := newDedup()
+	now := time.Now()
+
+	a := testutil.MustMetric("snmp",
+		map[string]string{"agent_host": "a"},
+		map[string]interface{}{"value": int64(1)},
+		now)
+	b := testutil.MustMetric("snmp",
+		map[string]string{"agent_host": "b"},
+		map[string]interface{}{"value": int64(1)},
+		now)
+	out := d.Apply(a, b)
+	require.Len(t, out, 2)
+}
+
+func TestDedupStaleValueEmitted(t *testing.T) {
+	d := newDedup()
+	now := time.Now()
+
+	d.Apply(newMetric(map[string]interface{}{"value": int64(1)}, now))
+	out := d.Apply(newMetric(map[string]interface{}{"value": int64(1)}, now.Add(9*time.Minute)))
+	require.Len(t, out, 0)
+
+	out = d.Apply(newMetric(map[string]interface{}{"value": int64(1)}, now.Add(10*time.Minute)))
+	require.Len(t, out, 1)
+}
+
+func TestDedupCleanupByMetricTime(t *testing.T) {
+	d := newDedup()
+	then := time.Unix(0, 0)
+
+	a := testutil.MustMetric("snmp",
+		map[string]string{"agent_host": "a"},
+		map[string]interface{}{"value": int64(1)},
+		then)
+	b := testutil.MustMetric("snmp",
+		map[string]string{"agent_host": "b"},
+		map[string]interface{}{"value": int64(1)},
+		then.Add(11*time.Minute))
+
+	d.Apply(a)
+	require.Len(t, d.cache, 1)
+
+	// The series of a is stale by the time of b.
+	d.Apply(b)
+	require.Len(t, d.cache, 1)
+	require.Contains(t, d.cache, b.HashID())
+}
+
+func TestDedupMetricModeKeepsAllFields(t *testing.T) {
+	d := newDedup()
+	now := time.Now()
+
+	d.Apply(newMetric(map[string]interface{}{"a": int64(1), "b": "up"}, now))
+	out := d.Apply(newMetric(map[string]interface{}{"a": int64(2), "b": "up"}, now.Add(time.Minute)))
+
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		newMetric(map[string]interface{}{"a": int64(2), "b": "up"}, now.Add(time.Minute)),
+	}, out)
+}
+
+func TestDedupFieldModeRemovesUnchangedFields(t *testing.T) {
+	d := newDedup()
+	d.Mode = modeField
+	now := time.Now()
+
+	d.Apply(newMetric(map[string]interface{}{"a": int64(1), "b": "up"}, now))
+	out := d.Apply(newMetric(map[string]interface{}{"a": int64(2), "b": "up"}, now.Add(time.Minute)))
+
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		newMetric(map[string]interface{}{"a": int64(2)}, now.Add(time.Minute)),
+	}, out)
+
+	// The staleness of each field is tracked separately.
+	out = d.Apply(newMetric(map[string]interface{}{"a": int64(2), "b": "up"}, now.Add(10*time.Minute)))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		newMetric(map[string]interface{}{"b": "up"}, now.Add(10*time.Minute)),
+	}, out)
+}
+
+func TestDedupTolerance(t *testing.T) {
+	d := newDedup()
+	d.Tolerance = 0.5
+	now := time.Now()
+
+	d.Apply(newMetric(map[string]interface{}{"value": 10.0}, now))
+	out := d.Apply(newMetric(map[string]interface{}{"value": 10.4}, now.Add(time.Minute)))
+	require.Len(t, out, 0)
+
+	// Compared to the last emitted value, so small changes add up.
+	out = d.Apply(newMetric(map[string]interface{}{"value": 10.6}, now.Add(2*time.Minute)))
+	require.Len(t, out, 1)
+}
+
+func TestDedupDropCallsMetricDrop(t *testing.T) {
+	d := newDedup()
+	now := time.Now()
+
+	d.Apply(newMetric(map[string]interface{}{"value": int64(1)}, now))
+
+	var delivered bool
+	m := newMetric(map[string]interface{}{"value": int64(1)}, now)
+	tm, _ := metric.WithTracking(m, func(telegraf.DeliveryInfo) { delivered = true })
+	out := d.Apply(tm)
+	require.Len(t, out, 0)
+	require.True(t, delivered)
+}
+
+func TestDedupInitMode(t *testing.T) {
+	d := newDedup()
+	require.NoError(t, d.Init())
+
+	d.Mode = modeField
+	require.NoError(t, d.Init())
+
+	d.Mode = "fields"
+	require.Error(t, d.Init())
+}
@@ -0,0 +1,139 @@
+package execd
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/process"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	influxSerializer "github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+const sampleConfig = `
+  ## Program to run as daemon, and its arguments.
+  ## Metrics are written to its stdin in influx line protocol and read back
+  ## from its stdout in the same format.
+  command = ["/usr/bin/mycollector", "--foo=bar"]
+
+  ## Delay before the process is restarted after an unexpected termination.
+  ## The delay doubles each time the process exits again soon after starting.
+  restart_delay = "10s"
+`
+
+type Execd struct {
+	Command      []string          `toml:"command"`
+	RestartDelay internal.Duration `toml:"restart_delay"`
+
+	acc        telegraf.Accumulator
+	parser     parsers.Parser
+	serializer serializers.Serializer
+	process    *process.Process
+
+	logName string
+}
+
+func New() *Execd {
+	return &Execd{
+		RestartDelay: internal.Duration{Duration: 10 * time.Second},
+		parser:       influx.NewParser(influx.NewMetricHandler()),
+		serializer:   influxSerializer.NewSerializer(),
+		logName:      "processors.execd",
+	}
+}
+
+func (e *Execd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Execd) Description() string {
+	return "Run executable as long-running processor plugin"
+}
+
+// SetLogName sets the name of the plugin in log messages.
+func (e *Execd) SetLogName(name string) {
+	e.logName = name
+}
+
+func (e *Execd) Start(acc telegraf.Accumulator) error {
+	if len(e.Command) == 0 {
+		return errors.New("command must be set")
+	}
+
+	var err error
+	e.process, err = process.New(e.Command)
+	if err != nil {
+		return err
+	}
+	e.process.LogName = e.logName
+	e.process.RestartDelay = e.RestartDelay.Duration
+	e.process.ReadStdoutFn = e.readStdout
+
+	e.acc = acc
+	return e.process.Start()
+}
+
+// Apply writes the metrics to the process, metrics read from the process
+// are added to the accumulator asynchronously.
+func (e *Execd) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		b, err := e.serializer.Serialize(m)
+		if err != nil {
+			log.Printf("E! [%s] Could not serialize metric: %v", e.logName, err)
+			m.Drop()
+			continue
+		}
+
+		_, err = e.process.Write(b)
+		if err != nil {
+			log.Printf("E! [%s] Could not write metric to process: %v", e.logName, err)
+			m.Drop()
+			continue
+		}
+
+		// Once written the metric is handed over to the process, the
+		// metrics it returns are new metrics.
+		m.Accept()
+	}
+	return nil
+}
+
+func (e *Execd) Stop() {
+	e.process.Stop()
+}
+
+func (e *Execd) readStdout(r io.Reader) {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			metrics, perr := e.parser.Parse(line)
+			if perr != nil {
+				e.acc.AddError(perr)
+			}
+			for _, m := range metrics {
+				e.acc.AddMetric(m)
+			}
+		}
+
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("E! [%s] Error reading stdout: %v", e.logName, err)
+			}
+			return
+		}
+	}
+}
+
+func init() {
+	processors.Add("execd", func() telegraf.Processor {
+		return New()
+	})
+}
@@ -0,0 +1,38 @@
+// +build !windows
+
+package execd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecd(t *testing.T) {
+	plugin := New()
+	plugin.Command = []string{"cat"}
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, plugin.Start(acc))
+
+	m := testutil.MustMetric("cpu",
+		map[string]string{"host": "localhost"},
+		map[string]interface{}{"value": 42.0},
+		time.Unix(0, 0),
+	)
+
+	require.Empty(t, plugin.Apply(m.Copy()))
+
+	acc.Wait(1)
+	plugin.Stop()
+
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{m}, acc.GetTelegrafMetrics())
+}
+
+func TestExecdNoCommand(t *testing.T) {
+	plugin := New()
+	require.Error(t, plugin.Start(&testutil.Accumulator{}))
+}
@@ -0,0 +1,271 @@
+package lookup
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Lookup files, the records of later files override those of earlier
+  ## files with the same key.
+  files = ["/etc/telegraf/lookup.csv"]
+
+  ## Format of the lookup files, "csv" or "json".  CSV files start with a
+  ## header row naming the columns, JSON files contain an array of objects.
+  # format = "csv"
+
+  ## Tags used to find the record of a metric, the record columns with the
+  ## same names must match the tag values.  The other columns of the record
+  ## are added to the metric as tags.
+  key_tags = ["host"]
+
+  ## What to do with metrics without a record: "pass" them unmodified,
+  ## "drop" them, or add the default_tags with "default".
+  # on_miss = "pass"
+
+  ## Tags added to metrics without a record when on_miss is "default".
+  # [processors.lookup.default_tags]
+  #   datacenter = "unknown"
+
+  ## How often the files are checked for changes, the files are loaded again
+  ## when they are modified.
+  # reload_interval = "10s"
+`
+
+const (
+	onMissPass    = "pass"
+	onMissDrop    = "drop"
+	onMissDefault = "default"
+)
+
+type Lookup struct {
+	Files          []string          `toml:"files"`
+	Format         string            `toml:"format"`
+	KeyTags        []string          `toml:"key_tags"`
+	OnMiss         string            `toml:"on_miss"`
+	DefaultTags    map[string]string `toml:"default_tags"`
+	ReloadInterval internal.Duration `toml:"reload_interval"`
+
+	table     map[string]map[string]string
+	modTimes  map[string]time.Time
+	lastCheck time.Time
+
+	logName string
+}
+
+func (l *Lookup) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *Lookup) Description() string {
+	return "Add tags to metrics from the records of a lookup file"
+}
+
+// SetLogName sets the name of the plugin in log messages.
+func (l *Lookup) SetLogName(name string) {
+	l.logName = name
+}
+
+// Init checks the on_miss setting.
+func (l *Lookup) Init() error {
+	switch l.OnMiss {
+	case "", onMissPass, onMissDrop, onMissDefault:
+		return nil
+	default:
+		return fmt.Errorf("invalid on_miss %q, must be %q, %q or %q",
+			l.OnMiss, onMissPass, onMissDrop, onMissDefault)
+	}
+}
+
+func (l *Lookup) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	l.reloadIfChanged()
+
+	out := in[:0]
+	for _, metric := range in {
+		key, ok := l.key(metric)
+		if tags, found := l.table[key]; ok && found {
+			for k, v := range tags {
+				metric.AddTag(k, v)
+			}
+			out = append(out, metric)
+			continue
+		}
+
+		switch l.OnMiss {
+		case onMissDrop:
+			metric.Drop()
+			continue
+		case onMissDefault:
+			for k, v := range l.DefaultTags {
+				metric.AddTag(k, v)
+			}
+		}
+		out = append(out, metric)
+	}
+	return out
+}
+
+// key returns the lookup key of the metric, it returns false if the metric
+// is missing any of the key tags.
+func (l *Lookup) key(metric telegraf.Metric) (string, bool) {
+	values := make([]string, 0, len(l.KeyTags))
+	for _, tag := range l.KeyTags {
+		value, ok := metric.GetTag(tag)
+		if !ok {
+			return "", false
+		}
+		values = append(values, value)
+	}
+	return strings.Join(values, "\x00"), true
+}
+
+// reloadIfChanged loads the files if any of them has been modified since they
+// were last loaded.  The previous records are kept if loading fails.
+func (l *Lookup) reloadIfChanged() {
+	now := time.Now()
+	if l.table != nil && now.Sub(l.lastCheck) < l.ReloadInterval.Duration {
+		return
+	}
+	l.lastCheck = now
+	if l.table == nil {
+		l.table = make(map[string]map[string]string)
+		l.modTimes = make(map[string]time.Time)
+	}
+
+	modTimes := make(map[string]time.Time, len(l.Files))
+	changed := false
+	for _, path := range l.Files {
+		info, err := os.Stat(path)
+		if err != nil {
+			log.Printf("E! [%s] Error reading lookup file: %v", l.logName, err)
+			return
+		}
+		modTimes[path] = info.ModTime()
+		if last, ok := l.modTimes[path]; !ok || !info.ModTime().Equal(last) {
+			changed = true
+		}
+	}
+	if !changed {
+		return
+	}
+
+	table := make(map[string]map[string]string)
+	for _, path := range l.Files {
+		if err := l.load(path, table); err != nil {
+			log.Printf("E! [%s] Error loading lookup file %q: %v", l.logName, path, err)
+			return
+		}
+	}
+
+	log.Printf("D! [%s] Loaded %d records", l.logName, len(table))
+	l.table = table
+	l.modTimes = modTimes
+}
+
+// load adds the records of the file to the table.
+func (l *Lookup) load(path string, table map[string]map[string]string) error {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var records []map[string]string
+	switch l.Format {
+	case "", "csv":
+		records, err = parseCSV(buf)
+	case "json":
+		records, err = parseJSON(buf)
+	default:
+		err = fmt.Errorf("unknown format %q", l.Format)
+	}
+	if err != nil {
+		return err
+	}
+
+	for i, record := range records {
+		values := make([]string, 0, len(l.KeyTags))
+		for _, tag := range l.KeyTags {
+			value, ok := record[tag]
+			if !ok {
+				return fmt.Errorf("record %d is missing the key %q", i+1, tag)
+			}
+			values = append(values, value)
+			delete(record, tag)
+		}
+		table[strings.Join(values, "\x00")] = record
+	}
+	return nil
+}
+
+// parseCSV returns the rows following the header row, by column name.  Empty
+// values are left out.
+func parseCSV(buf []byte) ([]map[string]string, error) {
+	reader := csv.NewReader(strings.NewReader(string(buf)))
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	header := rows[0]
+	records := make([]map[string]string, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		record := make(map[string]string, len(row))
+		for i, value := range row {
+			if value != "" {
+				record[header[i]] = value
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// parseJSON returns the objects of an array, with the values converted to
+// strings.  Null values are left out.
+func parseJSON(buf []byte) ([]map[string]string, error) {
+	var objects []map[string]interface{}
+	if err := json.Unmarshal(buf, &objects); err != nil {
+		return nil, err
+	}
+
+	records := make([]map[string]string, 0, len(objects))
+	for _, object := range objects {
+		record := make(map[string]string, len(object))
+		for key, value := range object {
+			switch v := value.(type) {
+			case nil:
+			case string:
+				record[key] = v
+			default:
+				record[key] = fmt.Sprint(v)
+			}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func init() {
+	processors.Add("lookup", func() telegraf.Processor {
+		return &Lookup{
+			Format:         "csv",
+			OnMiss:         onMissPass,
+			ReloadInterval: internal.Duration{Duration: 10 * time.Second},
+			logName:        "processors.lookup",
+		}
+	})
+}
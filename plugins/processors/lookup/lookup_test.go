@@ -0,0 +1,166 @@
+package lookup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const csvTable = `host,datacenter,team,rack
+web01,us-east,frontend,r1
+db01,us-west,storage,
+`
+
+const jsonTable = `[
+  {"host": "web01", "port": 80, "team": "frontend"},
+  {"host": "web01", "port": 443, "team": "security"}
+]`
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "lookup")
+	require.NoError(t, err)
+	return dir
+}
+
+func newMetric(tags map[string]string) telegraf.Metric {
+	return testutil.MustMetric("cpu",
+		tags,
+		map[string]interface{}{"value": 1.0},
+		time.Unix(0, 0))
+}
+
+func TestLookupCSV(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	l := &Lookup{
+		Files:   []string{writeFile(t, dir, "table.csv", csvTable)},
+		Format:  "csv",
+		KeyTags: []string{"host"},
+	}
+
+	actual := l.Apply(
+		newMetric(map[string]string{"host": "web01"}),
+		newMetric(map[string]string{"host": "db01"}),
+		newMetric(map[string]string{"host": "unknown"}),
+		newMetric(map[string]string{}),
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		newMetric(map[string]string{"host": "web01", "datacenter": "us-east", "team": "frontend", "rack": "r1"}),
+		newMetric(map[string]string{"host": "db01", "datacenter": "us-west", "team": "storage"}),
+		newMetric(map[string]string{"host": "unknown"}),
+		newMetric(map[string]string{}),
+	}, actual)
+}
+
+func TestLookupJSONMultipleKeys(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	l := &Lookup{
+		Files:   []string{writeFile(t, dir, "table.json", jsonTable)},
+		Format:  "json",
+		KeyTags: []string{"host", "port"},
+	}
+
+	actual := l.Apply(
+		newMetric(map[string]string{"host": "web01", "port": "443"}),
+		newMetric(map[string]string{"host": "web01"}),
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		newMetric(map[string]string{"host": "web01", "port": "443", "team": "security"}),
+		newMetric(map[string]string{"host": "web01"}),
+	}, actual)
+}
+
+func TestLookupOnMiss(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "table.csv", csvTable)
+
+	l := &Lookup{
+		Files:   []string{path},
+		KeyTags: []string{"host"},
+		OnMiss:  "drop",
+	}
+	actual := l.Apply(
+		newMetric(map[string]string{"host": "unknown"}),
+		newMetric(map[string]string{"host": "db01"}),
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		newMetric(map[string]string{"host": "db01", "datacenter": "us-west", "team": "storage"}),
+	}, actual)
+
+	l = &Lookup{
+		Files:       []string{path},
+		KeyTags:     []string{"host"},
+		OnMiss:      "default",
+		DefaultTags: map[string]string{"datacenter": "unknown"},
+	}
+	actual = l.Apply(newMetric(map[string]string{"host": "unknown"}))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		newMetric(map[string]string{"host": "unknown", "datacenter": "unknown"}),
+	}, actual)
+}
+
+func TestLookupInvalidOnMiss(t *testing.T) {
+	l := &Lookup{
+		KeyTags: []string{"host"},
+		OnMiss:  "skip",
+	}
+	require.Error(t, l.Init())
+
+	l.OnMiss = onMissDrop
+	require.NoError(t, l.Init())
+}
+
+func TestLookupReload(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "table.csv", csvTable)
+
+	l := &Lookup{
+		Files:   []string{path},
+		KeyTags: []string{"host"},
+	}
+	actual := l.Apply(newMetric(map[string]string{"host": "web01"}))
+	require.Equal(t, "us-east", actual[0].Tags()["datacenter"])
+
+	writeFile(t, dir, "table.csv", "host,datacenter\nweb01,eu-central\n")
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	actual = l.Apply(newMetric(map[string]string{"host": "web01"}))
+	require.Equal(t, "eu-central", actual[0].Tags()["datacenter"])
+}
+
+func TestLookupInvalidFileKeepsRecords(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "table.csv", csvTable)
+
+	l := &Lookup{
+		Files:   []string{path},
+		KeyTags: []string{"host"},
+	}
+	l.Apply(newMetric(map[string]string{"host": "web01"}))
+
+	writeFile(t, dir, "table.csv", "name,datacenter\nweb01,eu-central\n")
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	actual := l.Apply(newMetric(map[string]string{"host": "web01"}))
+	require.Equal(t, "us-east", actual[0].Tags()["datacenter"])
+}
@@ -0,0 +1,858 @@
+package math
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/influxdata/telegraf"
+)
+
+var (
+	errDivisionByZero = errors.New("division by zero")
+	errOverflow       = errors.New("integer overflow")
+	errInvalidFloat   = errors.New("result is not a finite number")
+)
+
+// expression is a compiled expression evaluated on a metric.  The values are
+// int64, uint64, float64, string or bool.
+type expression interface {
+	eval(metric telegraf.Metric) (interface{}, error)
+}
+
+// compile parses the expression.
+func compile(s string) (expression, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return expr, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+var operators = []string{
+	"&&", "||", "==", "!=", "<=", ">=",
+	"+", "-", "*", "/", "%", "<", ">", "!", "(", ")", ",",
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	i := 0
+next:
+	for i < len(s) {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c >= '0' && c <= '9' || c == '.':
+			start := i
+			for i < len(s) && (isDigit(s[i]) || s[i] == '.') {
+				i++
+			}
+			if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+				i++
+				if i < len(s) && (s[i] == '+' || s[i] == '-') {
+					i++
+				}
+				for i < len(s) && isDigit(s[i]) {
+					i++
+				}
+			}
+			value, err := parseNumber(s[start:i])
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", s[start:i], start)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: s[start:i], value: value, pos: start})
+		case c == '"' || c == '\'':
+			start := i
+			i++
+			var sb strings.Builder
+			for i < len(s) && s[i] != byte(c) {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				sb.WriteByte(s[i])
+				i++
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokenString, text: s[start:i], value: sb.String(), pos: start})
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(s) && (s[i] == '_' || s[i] == '.' || isDigit(s[i]) || unicode.IsLetter(rune(s[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: s[start:i], pos: start})
+		default:
+			for _, op := range operators {
+				if strings.HasPrefix(s[i:], op) {
+					tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i})
+					i += len(op)
+					continue next
+				}
+			}
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, text: "end of expression", pos: len(s)}), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parseNumber returns an int64 for integer literals, or an uint64 if the
+// literal does not fit, and a float64 otherwise.
+func parseNumber(s string) (interface{}, error) {
+	if !strings.ContainsAny(s, ".eE") {
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return v, nil
+		}
+		return strconv.ParseUint(s, 10, 64)
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// parser is a recursive descent parser, each parse method handles one level
+// of operator precedence.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is one of the operators.
+func (p *parser) accept(ops ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOperator {
+		return "", false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		tok := p.peek()
+		return fmt.Errorf("expected %q, found %q at position %d", op, tok.text, tok.pos)
+	}
+	return nil
+}
+
+func (p *parser) parseBinary(operand func() (expression, error), ops ...string) (expression, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) parseOr() (expression, error) {
+	return p.parseBinary(p.parseAnd, "||")
+}
+
+func (p *parser) parseAnd() (expression, error) {
+	return p.parseBinary(p.parseComparison, "&&")
+}
+
+func (p *parser) parseComparison() (expression, error) {
+	return p.parseBinary(p.parseSum, "==", "!=", "<=", ">=", "<", ">")
+}
+
+func (p *parser) parseSum() (expression, error) {
+	return p.parseBinary(p.parseProduct, "+", "-")
+}
+
+func (p *parser) parseProduct() (expression, error) {
+	return p.parseBinary(p.parseUnary, "*", "/", "%")
+}
+
+func (p *parser) parseUnary() (expression, error) {
+	if op, ok := p.accept("-", "!"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{op: op, operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (expression, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber, tokenString:
+		return &literal{value: tok.value}, nil
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return &literal{value: true}, nil
+		case "false":
+			return &literal{value: false}, nil
+		}
+		if _, ok := p.accept("("); ok {
+			return p.parseCall(tok)
+		}
+		return &fieldRef{name: tok.text}, nil
+	case tokenOperator:
+		if tok.text == "(" {
+			expr, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return expr, p.expect(")")
+		}
+	}
+	return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
+
+func (p *parser) parseCall(name token) (expression, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at position %d", name.text, name.pos)
+	}
+
+	var args []expression
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.accept(","); !ok {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(args) != fn.args {
+		return nil, fmt.Errorf("function %q takes %d arguments, found %d", name.text, fn.args, len(args))
+	}
+	return &callExpr{name: name.text, fn: fn.fn, args: args}, nil
+}
+
+type literal struct {
+	value interface{}
+}
+
+func (e *literal) eval(telegraf.Metric) (interface{}, error) {
+	return e.value, nil
+}
+
+type fieldRef struct {
+	name string
+}
+
+func (e *fieldRef) eval(metric telegraf.Metric) (interface{}, error) {
+	value, ok := metric.GetField(e.name)
+	if !ok {
+		return nil, fmt.Errorf("field %q not found", e.name)
+	}
+	return value, nil
+}
+
+type unaryExpr struct {
+	op      string
+	operand expression
+}
+
+func (e *unaryExpr) eval(metric telegraf.Metric) (interface{}, error) {
+	value, err := e.operand.eval(metric)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.op == "!" {
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator ! not defined on %s", typeName(value))
+		}
+		return !b, nil
+	}
+
+	switch v := value.(type) {
+	case int64:
+		if v == math.MinInt64 {
+			return nil, errOverflow
+		}
+		return -v, nil
+	case uint64:
+		if v > 1<<63 {
+			return nil, errOverflow
+		}
+		return -int64(v), nil
+	case float64:
+		return -v, nil
+	}
+	return nil, fmt.Errorf("operator - not defined on %s", typeName(value))
+}
+
+type binaryExpr struct {
+	op          string
+	left, right expression
+}
+
+func (e *binaryExpr) eval(metric telegraf.Metric) (interface{}, error) {
+	left, err := e.left.eval(metric)
+	if err != nil {
+		return nil, err
+	}
+
+	// The logical operators short circuit.
+	if e.op == "&&" || e.op == "||" {
+		l, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s not defined on %s", e.op, typeName(left))
+		}
+		if l == (e.op == "||") {
+			return l, nil
+		}
+		right, err := e.right.eval(metric)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s not defined on %s", e.op, typeName(right))
+		}
+		return r, nil
+	}
+
+	right, err := e.right.eval(metric)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		return compare(e.op, left, right)
+	}
+
+	if l, ok := left.(string); ok && e.op == "+" {
+		if r, ok := right.(string); ok {
+			return l + r, nil
+		}
+	}
+
+	left, right, err = promote(left, right)
+	if err != nil {
+		return nil, fmt.Errorf("operator %s %v", e.op, err)
+	}
+	switch l := left.(type) {
+	case int64:
+		return intOp(e.op, l, right.(int64))
+	case uint64:
+		return uintOp(e.op, l, right.(uint64))
+	default:
+		return floatOp(e.op, l.(float64), right.(float64))
+	}
+}
+
+type callExpr struct {
+	name string
+	fn   func(args []interface{}) (interface{}, error)
+	args []expression
+}
+
+func (e *callExpr) eval(metric telegraf.Metric) (interface{}, error) {
+	args := make([]interface{}, 0, len(e.args))
+	for _, arg := range e.args {
+		value, err := arg.eval(metric)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+
+	// The metric functions need the metric, they are handled here.
+	switch e.name {
+	case "field", "tag", "has_field", "has_tag":
+		name, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("function %s requires a string, found %s", e.name, typeName(args[0]))
+		}
+		switch e.name {
+		case "field":
+			return (&fieldRef{name: name}).eval(metric)
+		case "tag":
+			value, ok := metric.GetTag(name)
+			if !ok {
+				return nil, fmt.Errorf("tag %q not found", name)
+			}
+			return value, nil
+		case "has_field":
+			return metric.HasField(name), nil
+		default:
+			return metric.HasTag(name), nil
+		}
+	}
+
+	value, err := e.fn(args)
+	if err != nil {
+		return nil, fmt.Errorf("function %s: %v", e.name, err)
+	}
+	return value, nil
+}
+
+type function struct {
+	args int
+	fn   func(args []interface{}) (interface{}, error)
+}
+
+var functions = map[string]function{
+	"field":     {args: 1},
+	"tag":       {args: 1},
+	"has_field": {args: 1},
+	"has_tag":   {args: 1},
+	"abs":       {args: 1, fn: abs},
+	"ceil":      {args: 1, fn: floatFunc(math.Ceil)},
+	"floor":     {args: 1, fn: floatFunc(math.Floor)},
+	"round":     {args: 1, fn: floatFunc(math.Round)},
+	"sqrt":      {args: 1, fn: floatFunc(math.Sqrt)},
+	"exp":       {args: 1, fn: floatFunc(math.Exp)},
+	"log":       {args: 1, fn: floatFunc(math.Log)},
+	"log10":     {args: 1, fn: floatFunc(math.Log10)},
+	"pow":       {args: 2, fn: pow},
+	"min":       {args: 2, fn: minMax(true)},
+	"max":       {args: 2, fn: minMax(false)},
+	"float":     {args: 1, fn: toFloat},
+	"int":       {args: 1, fn: toInt},
+	"uint":      {args: 1, fn: toUint},
+	"string":    {args: 1, fn: toString},
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case int64:
+		return "integer"
+	case uint64:
+		return "unsigned"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// promote converts two numbers to a common type.  Integers are converted to
+// float if either number is a float.  Mixed signed and unsigned integers are
+// unsigned if the signed integer is not negative, otherwise both are
+// converted to signed integers, it is an error if the unsigned integer does
+// not fit.
+func promote(left, right interface{}) (interface{}, interface{}, error) {
+	if !isNumber(left) || !isNumber(right) {
+		return nil, nil, fmt.Errorf("not defined on %s and %s", typeName(left), typeName(right))
+	}
+
+	_, lf := left.(float64)
+	_, rf := right.(float64)
+	if lf || rf {
+		return asFloat(left), asFloat(right), nil
+	}
+
+	switch l := left.(type) {
+	case int64:
+		if r, ok := right.(uint64); ok {
+			if l >= 0 {
+				return uint64(l), r, nil
+			}
+			if r <= math.MaxInt64 {
+				return l, int64(r), nil
+			}
+			return nil, nil, errOverflow
+		}
+	case uint64:
+		if r, ok := right.(int64); ok {
+			if r >= 0 {
+				return l, uint64(r), nil
+			}
+			if l <= math.MaxInt64 {
+				return int64(l), r, nil
+			}
+			return nil, nil, errOverflow
+		}
+	}
+	return left, right, nil
+}
+
+func isNumber(value interface{}) bool {
+	switch value.(type) {
+	case int64, uint64, float64:
+		return true
+	}
+	return false
+}
+
+func asFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	}
+	return value.(float64)
+}
+
+func intOp(op string, l, r int64) (interface{}, error) {
+	switch op {
+	case "+":
+		if (r > 0 && l > math.MaxInt64-r) || (r < 0 && l < math.MinInt64-r) {
+			return nil, errOverflow
+		}
+		return l + r, nil
+	case "-":
+		if (r > 0 && l < math.MinInt64+r) || (r < 0 && l > math.MaxInt64+r) {
+			return nil, errOverflow
+		}
+		return l - r, nil
+	case "*":
+		if l == 0 || r == 0 {
+			return int64(0), nil
+		}
+		v := l * r
+		if v/r != l || (l == -1 && r == math.MinInt64) || (r == -1 && l == math.MinInt64) {
+			return nil, errOverflow
+		}
+		return v, nil
+	case "%":
+		if r == 0 {
+			return nil, errDivisionByZero
+		}
+		if r == -1 {
+			return int64(0), nil
+		}
+		return l % r, nil
+	}
+	return floatOp(op, float64(l), float64(r))
+}
+
+func uintOp(op string, l, r uint64) (interface{}, error) {
+	switch op {
+	case "+":
+		if l > math.MaxUint64-r {
+			return nil, errOverflow
+		}
+		return l + r, nil
+	case "-":
+		if l >= r {
+			return l - r, nil
+		}
+		// The difference is negative, it is returned as a signed integer.
+		if r-l > 1<<63 {
+			return nil, errOverflow
+		}
+		return -int64(r - l), nil
+	case "*":
+		if l == 0 || r == 0 {
+			return uint64(0), nil
+		}
+		v := l * r
+		if v/r != l {
+			return nil, errOverflow
+		}
+		return v, nil
+	case "%":
+		if r == 0 {
+			return nil, errDivisionByZero
+		}
+		return l % r, nil
+	}
+	return floatOp(op, float64(l), float64(r))
+}
+
+func floatOp(op string, l, r float64) (interface{}, error) {
+	switch op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return nil, errDivisionByZero
+		}
+		return l / r, nil
+	case "%":
+		if r == 0 {
+			return nil, errDivisionByZero
+		}
+		return math.Mod(l, r), nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", op)
+}
+
+// compare compares numbers by value regardless of their type, strings and
+// booleans can only be compared with values of the same type.
+func compare(op string, left, right interface{}) (interface{}, error) {
+	var cmp int
+	switch l := left.(type) {
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare %s and %s", typeName(left), typeName(right))
+		}
+		cmp = strings.Compare(l, r)
+	case bool:
+		r, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare %s and %s", typeName(left), typeName(right))
+		}
+		if op != "==" && op != "!=" {
+			return nil, fmt.Errorf("operator %s not defined on boolean", op)
+		}
+		if l != r {
+			cmp = 1
+		}
+	default:
+		var err error
+		cmp, err = compareNumbers(left, right)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch op {
+	case "==":
+		return cmp == 0, nil
+	case "!=":
+		return cmp != 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+func compareNumbers(left, right interface{}) (int, error) {
+	if !isNumber(left) || !isNumber(right) {
+		return 0, fmt.Errorf("cannot compare %s and %s", typeName(left), typeName(right))
+	}
+
+	// A negative integer is less than any unsigned integer, even if they
+	// cannot be converted to a common type.
+	if l, ok := left.(int64); ok && l < 0 {
+		if _, ok := right.(uint64); ok {
+			return -1, nil
+		}
+	}
+	if r, ok := right.(int64); ok && r < 0 {
+		if _, ok := left.(uint64); ok {
+			return 1, nil
+		}
+	}
+
+	left, right, err := promote(left, right)
+	if err != nil {
+		return 0, err
+	}
+	switch l := left.(type) {
+	case int64:
+		return sign(l < right.(int64), l > right.(int64)), nil
+	case uint64:
+		return sign(l < right.(uint64), l > right.(uint64)), nil
+	default:
+		f, r := l.(float64), right.(float64)
+		return sign(f < r, f > r), nil
+	}
+}
+
+func sign(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+func floatFunc(fn func(float64) float64) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if !isNumber(args[0]) {
+			return nil, fmt.Errorf("not defined on %s", typeName(args[0]))
+		}
+		return fn(asFloat(args[0])), nil
+	}
+}
+
+func abs(args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case int64:
+		if v == math.MinInt64 {
+			return nil, errOverflow
+		}
+		if v < 0 {
+			return -v, nil
+		}
+		return v, nil
+	case uint64:
+		return v, nil
+	case float64:
+		return math.Abs(v), nil
+	}
+	return nil, fmt.Errorf("not defined on %s", typeName(args[0]))
+}
+
+func pow(args []interface{}) (interface{}, error) {
+	if !isNumber(args[0]) || !isNumber(args[1]) {
+		return nil, fmt.Errorf("not defined on %s and %s", typeName(args[0]), typeName(args[1]))
+	}
+	return math.Pow(asFloat(args[0]), asFloat(args[1])), nil
+}
+
+func minMax(min bool) func(args []interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		cmp, err := compareNumbers(args[0], args[1])
+		if err != nil {
+			return nil, err
+		}
+		if (cmp <= 0) == min {
+			return args[0], nil
+		}
+		return args[1], nil
+	}
+}
+
+func toFloat(args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case int64, uint64, float64:
+		return asFloat(v), nil
+	case bool:
+		if v {
+			return 1.0, nil
+		}
+		return 0.0, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return nil, fmt.Errorf("not defined on %s", typeName(args[0]))
+}
+
+// toInt converts the value to a signed integer, floats are truncated.
+func toInt(args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case int64:
+		return v, nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return nil, errOverflow
+		}
+		return int64(v), nil
+	case float64:
+		if math.IsNaN(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return nil, fmt.Errorf("%v out of range", v)
+		}
+		return int64(v), nil
+	case bool:
+		if v {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return nil, fmt.Errorf("not defined on %s", typeName(args[0]))
+}
+
+// toUint converts the value to an unsigned integer, floats are truncated.
+func toUint(args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case int64:
+		if v < 0 {
+			return nil, fmt.Errorf("%v out of range", v)
+		}
+		return uint64(v), nil
+	case uint64:
+		return v, nil
+	case float64:
+		if math.IsNaN(v) || v <= -1 || v >= math.MaxUint64 {
+			return nil, fmt.Errorf("%v out of range", v)
+		}
+		return uint64(v), nil
+	case bool:
+		if v {
+			return uint64(1), nil
+		}
+		return uint64(0), nil
+	case string:
+		return strconv.ParseUint(v, 10, 64)
+	}
+	return nil, fmt.Errorf("not defined on %s", typeName(args[0]))
+}
+
+func toString(args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	}
+	return fmt.Sprint(args[0]), nil
+}
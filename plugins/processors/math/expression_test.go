@@ -0,0 +1,154 @@
+package math
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEval(t *testing.T) {
+	metric := testutil.MustMetric("mem",
+		map[string]string{
+			"host": "localhost",
+		},
+		map[string]interface{}{
+			"used":    int64(25),
+			"total":   int64(100),
+			"free":    uint64(75),
+			"temp":    20.5,
+			"up":      true,
+			"state":   "ok",
+			"big":     uint64(math.MaxUint64),
+			"min_int": int64(math.MinInt64),
+		},
+		time.Unix(0, 0),
+	)
+
+	tests := []struct {
+		expression string
+		expected   interface{}
+	}{
+		{"used / total * 100", 25.0},
+		{"used * 100 / total", 25.0},
+		{"used + total", int64(125)},
+		{"used - total", int64(-75)},
+		{"total % 7", int64(2)},
+		{"free + 1", uint64(76)},
+		{"free - total", int64(-25)},
+		{"free - 100", int64(-25)},
+		{"free * 2", uint64(150)},
+		{"used + free", uint64(100)},
+		{"free + (0 - 100)", int64(-25)},
+		{"big - free", uint64(math.MaxUint64 - 75)},
+		{"big - 1", uint64(math.MaxUint64 - 1)},
+		{"big > used", true},
+		{"min_int < free", true},
+		{"temp * 9 / 5 + 32", 68.9},
+		{"-used", int64(-25)},
+		{"2 + 3 * 4", int64(14)},
+		{"(2 + 3) * 4", int64(20)},
+		{"1.5e1", 15.0},
+		{"used < total && up", true},
+		{"used > total || !up", false},
+		{"state == 'ok'", true},
+		{"state != \"ok\"", false},
+		{"tag('host') + \"/\" + state", "localhost/ok"},
+		{"field(\"used\")", int64(25)},
+		{"has_field('missing') && missing > 0", false},
+		{"has_tag('host')", true},
+		{"abs(used - total)", int64(75)},
+		{"round(temp)", 21.0},
+		{"floor(temp)", 20.0},
+		{"ceil(temp)", 21.0},
+		{"sqrt(16)", 4.0},
+		{"pow(2, 10)", 1024.0},
+		{"min(used, temp)", 20.5},
+		{"max(used, free)", uint64(75)},
+		{"int(temp)", int64(20)},
+		{"uint(used)", uint64(25)},
+		{"float(up)", 1.0},
+		{"int('42')", int64(42)},
+		{"string(used)", "25"},
+		{"1 == 1.0", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			expr, err := compile(tt.expression)
+			require.NoError(t, err)
+			actual, err := expr.eval(metric)
+			require.NoError(t, err)
+			if f, ok := tt.expected.(float64); ok {
+				require.IsType(t, f, actual)
+				require.InDelta(t, f, actual, 1e-9)
+				return
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestEvalError(t *testing.T) {
+	metric := testutil.MustMetric("mem",
+		map[string]string{},
+		map[string]interface{}{
+			"used":    int64(25),
+			"zero":    int64(0),
+			"max_int": int64(math.MaxInt64),
+			"big":     uint64(math.MaxUint64),
+			"state":   "ok",
+			"up":      true,
+		},
+		time.Unix(0, 0),
+	)
+
+	tests := []string{
+		"missing + 1",
+		"tag('missing')",
+		"used / zero",
+		"used % zero",
+		"max_int + 1",
+		"max_int * 2",
+		"big + 1",
+		"big + (0 - 1)",
+		"-big",
+		"state + 1",
+		"state < 1",
+		"up + 1",
+		"up && used",
+		"!used",
+		"up < true",
+		"int(big)",
+		"uint(-1)",
+	}
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			expr, err := compile(tt)
+			require.NoError(t, err)
+			_, err = expr.eval(metric)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	tests := []string{
+		"",
+		"1 +",
+		"(1 + 2",
+		"1 2",
+		"unknown(1)",
+		"pow(1)",
+		"'unterminated",
+		"1 # 2",
+		"1.2.3",
+	}
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			_, err := compile(tt)
+			require.Error(t, err)
+		})
+	}
+}
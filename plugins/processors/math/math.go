@@ -0,0 +1,153 @@
+package math
+
+import (
+	"log"
+	"math"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Drop metrics for which any of the expressions evaluates to true, the
+  ## expressions are evaluated after the calculations.
+  # drop = ["usage_idle > 100"]
+
+  ## Calculations are applied in order, the result of a calculation can be
+  ## used by the following ones.  The result is stored in the field, an
+  ## existing field is replaced.
+  [[processors.math.calculation]]
+    field = "used_percent"
+    expression = "used / total * 100"
+
+  # [[processors.math.calculation]]
+  #   field = "temp_f"
+  #   expression = "temp_c * 9 / 5 + 32"
+`
+
+type Math struct {
+	Drop         []string      `toml:"drop"`
+	Calculations []calculation `toml:"calculation"`
+
+	calculations []calculation
+	drops        []dropRule
+	init         bool
+
+	logName string
+}
+
+type calculation struct {
+	Field      string `toml:"field"`
+	Expression string `toml:"expression"`
+
+	expr expression
+}
+
+type dropRule struct {
+	source string
+	expr   expression
+}
+
+func (m *Math) SampleConfig() string {
+	return sampleConfig
+}
+
+func (m *Math) Description() string {
+	return "Compute fields from expressions on the fields and tags of metrics"
+}
+
+// SetLogName sets the name of the plugin in log messages.
+func (m *Math) SetLogName(name string) {
+	m.logName = name
+}
+
+// compile parses the expressions, expressions with errors are skipped.
+func (m *Math) compile() {
+	for _, c := range m.Calculations {
+		expr, err := compile(c.Expression)
+		if err != nil {
+			log.Printf("E! [%s] Error parsing expression %q: %v", m.logName, c.Expression, err)
+			continue
+		}
+		if c.Field == "" {
+			log.Printf("E! [%s] No field set for expression %q", m.logName, c.Expression)
+			continue
+		}
+		c.expr = expr
+		m.calculations = append(m.calculations, c)
+	}
+
+	for _, s := range m.Drop {
+		expr, err := compile(s)
+		if err != nil {
+			log.Printf("E! [%s] Error parsing expression %q: %v", m.logName, s, err)
+			continue
+		}
+		m.drops = append(m.drops, dropRule{source: s, expr: expr})
+	}
+	m.init = true
+}
+
+func (m *Math) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if !m.init {
+		m.compile()
+	}
+
+	out := in[:0]
+	for _, metric := range in {
+		for _, c := range m.calculations {
+			value, err := c.expr.eval(metric)
+			if err == nil {
+				err = checkResult(value)
+			}
+			if err != nil {
+				log.Printf("D! [%s] Error evaluating %q: %v", m.logName, c.Expression, err)
+				continue
+			}
+			metric.AddField(c.Field, value)
+		}
+
+		if m.dropped(metric) {
+			metric.Drop()
+			continue
+		}
+		out = append(out, metric)
+	}
+	return out
+}
+
+// dropped returns true if any of the drop expressions is true.  Expressions
+// that cannot be evaluated do not drop the metric.
+func (m *Math) dropped(metric telegraf.Metric) bool {
+	for _, rule := range m.drops {
+		value, err := rule.expr.eval(metric)
+		if err != nil {
+			log.Printf("D! [%s] Error evaluating %q: %v", m.logName, rule.source, err)
+			continue
+		}
+		b, ok := value.(bool)
+		if !ok {
+			log.Printf("D! [%s] Drop expression %q is %s, not boolean", m.logName, rule.source, typeName(value))
+			continue
+		}
+		if b {
+			return true
+		}
+	}
+	return false
+}
+
+// checkResult returns an error for float results that are not valid field
+// values.
+func checkResult(value interface{}) error {
+	if f, ok := value.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		return errInvalidFloat
+	}
+	return nil
+}
+
+func init() {
+	processors.Add("math", func() telegraf.Processor {
+		return &Math{logName: "processors.math"}
+	})
+}
@@ -0,0 +1,122 @@
+package math
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculation(t *testing.T) {
+	plugin := &Math{
+		Calculations: []calculation{
+			{Field: "used_percent", Expression: "used / total * 100"},
+			{Field: "used_bits", Expression: "used * 8"},
+			{Field: "used", Expression: "used_bits / 8"},
+		},
+	}
+
+	actual := plugin.Apply(
+		testutil.MustMetric("mem",
+			map[string]string{},
+			map[string]interface{}{"used": int64(50), "total": int64(200)},
+			time.Unix(0, 0),
+		),
+	)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("mem",
+			map[string]string{},
+			map[string]interface{}{
+				"used":         50.0,
+				"total":        int64(200),
+				"used_percent": 25.0,
+				"used_bits":    int64(400),
+			},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestCalculationError(t *testing.T) {
+	plugin := &Math{
+		Calculations: []calculation{
+			{Field: "invalid", Expression: "used +"},
+			{Field: "ratio", Expression: "used / total"},
+			{Field: "", Expression: "used"},
+		},
+	}
+
+	input := testutil.MustMetric("mem",
+		map[string]string{},
+		map[string]interface{}{"used": int64(50), "total": int64(0)},
+		time.Unix(0, 0),
+	)
+	actual := plugin.Apply(input.Copy())
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{input}, actual)
+}
+
+func TestDrop(t *testing.T) {
+	plugin := &Math{
+		Drop: []string{"usage_idle > 100", "invalid +", "state"},
+		Calculations: []calculation{
+			{Field: "usage_busy", Expression: "100 - usage_idle"},
+		},
+	}
+
+	actual := plugin.Apply(
+		testutil.MustMetric("cpu",
+			map[string]string{"cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 90.0, "state": "ok"},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("cpu",
+			map[string]string{"cpu": "cpu1"},
+			map[string]interface{}{"usage_idle": 120.0, "state": "ok"},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("cpu",
+			map[string]string{"cpu": "cpu2"},
+			map[string]interface{}{"state": "ok"},
+			time.Unix(0, 0),
+		),
+	)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 90.0, "usage_busy": 10.0, "state": "ok"},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric("cpu",
+			map[string]string{"cpu": "cpu2"},
+			map[string]interface{}{"state": "ok"},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestDropTracking(t *testing.T) {
+	var delivered []telegraf.DeliveryInfo
+	notify := func(di telegraf.DeliveryInfo) {
+		delivered = append(delivered, di)
+	}
+
+	plugin := &Math{
+		Drop: []string{"value < 0"},
+	}
+
+	m := testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{"value": -1},
+		time.Unix(0, 0),
+	)
+	tm, _ := metric.WithTracking(m, notify)
+
+	actual := plugin.Apply(tm)
+	require.Len(t, actual, 0)
+	require.Len(t, delivered, 1)
+}
@@ -0,0 +1,43 @@
+package starlark
+
+import (
+	"time"
+
+	"github.com/influxdata/telegraf/metric"
+	"go.starlark.net/starlark"
+)
+
+// newMetric implements the Metric(name) builtin, creating a new metric
+// without tags or fields and the current time.
+func newMetric(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name starlark.String
+	if err := starlark.UnpackPositionalArgs("Metric", args, kwargs, 1, &name); err != nil {
+		return nil, err
+	}
+
+	m, err := metric.New(string(name), nil, nil, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Metric{metric: m}, nil
+}
+
+// deepcopy implements the deepcopy(metric) builtin, returning a copy of the
+// metric that can be modified and emitted independently.
+func deepcopy(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var sm *Metric
+	if err := starlark.UnpackPositionalArgs("deepcopy", args, kwargs, 1, &sm); err != nil {
+		return nil, err
+	}
+
+	// The copy is created as a new metric, rather than with Copy, so that
+	// a copy that is never emitted does not hold up delivery tracking.
+	m := sm.metric
+	dup, err := metric.New(m.Name(), m.Tags(), m.Fields(), m.Time(), m.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Metric{metric: dup}, nil
+}
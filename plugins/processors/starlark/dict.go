@@ -0,0 +1,242 @@
+package starlark
+
+import (
+	"errors"
+	"fmt"
+
+	"go.starlark.net/starlark"
+)
+
+// mapping is implemented by the dict-like views of the tags and fields of a
+// metric, it allows both to share the implementation of the dict methods.
+type mapping interface {
+	starlark.IterableMapping
+	starlark.HasSetKey
+	Len() int
+	Delete(k starlark.Value) (starlark.Value, bool, error)
+	Clear() error
+}
+
+var dictMethods = map[string]builtinMethod{
+	"clear":      dictClear,
+	"get":        dictGet,
+	"items":      dictItems,
+	"keys":       dictKeys,
+	"pop":        dictPop,
+	"popitem":    dictPopitem,
+	"setdefault": dictSetdefault,
+	"update":     dictUpdate,
+	"values":     dictValues,
+}
+
+type builtinMethod func(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error)
+
+func builtinAttr(recv starlark.Value, name string) (starlark.Value, error) {
+	method := dictMethods[name]
+	if method == nil {
+		return nil, nil
+	}
+
+	return starlark.NewBuiltin(name, func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		return method(b, args, kwargs)
+	}).BindReceiver(recv), nil
+}
+
+func builtinAttrNames() []string {
+	names := make([]string, 0, len(dictMethods))
+	for name := range dictMethods {
+		names = append(names, name)
+	}
+	return names
+}
+
+// dictString formats the mapping like a Starlark dict.
+func dictString(m mapping) string {
+	dict := starlark.NewDict(m.Len())
+	for _, item := range m.Items() {
+		dict.SetKey(item[0], item[1])
+	}
+	return dict.String()
+}
+
+func dictClear(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return starlark.None, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	return starlark.None, b.Receiver().(mapping).Clear()
+}
+
+func dictGet(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key, dflt starlark.Value
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &key, &dflt); err != nil {
+		return nil, err
+	}
+	if v, ok, err := b.Receiver().(mapping).Get(key); err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	} else if ok {
+		return v, nil
+	} else if dflt != nil {
+		return dflt, nil
+	}
+	return starlark.None, nil
+}
+
+func dictItems(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return starlark.None, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	items := b.Receiver().(mapping).Items()
+	res := make([]starlark.Value, len(items))
+	for i, item := range items {
+		res[i] = item
+	}
+	return starlark.NewList(res), nil
+}
+
+func dictKeys(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return starlark.None, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	items := b.Receiver().(mapping).Items()
+	res := make([]starlark.Value, len(items))
+	for i, item := range items {
+		res[i] = item[0]
+	}
+	return starlark.NewList(res), nil
+}
+
+func dictValues(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return starlark.None, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	items := b.Receiver().(mapping).Items()
+	res := make([]starlark.Value, len(items))
+	for i, item := range items {
+		res[i] = item[1]
+	}
+	return starlark.NewList(res), nil
+}
+
+func dictPop(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var k, d starlark.Value
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &k, &d); err != nil {
+		return starlark.None, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	if v, found, err := b.Receiver().(mapping).Delete(k); err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	} else if found {
+		return v, nil
+	} else if d != nil {
+		return d, nil
+	}
+	return nil, fmt.Errorf("%s: missing key", b.Name())
+}
+
+func dictPopitem(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 0); err != nil {
+		return starlark.None, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	recv := b.Receiver().(mapping)
+	items := recv.Items()
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%s: empty dict", b.Name())
+	}
+	item := items[0]
+	if _, _, err := recv.Delete(item[0]); err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	return item, nil
+}
+
+func dictSetdefault(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var key, dflt starlark.Value = nil, starlark.None
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &key, &dflt); err != nil {
+		return nil, err
+	}
+	recv := b.Receiver().(mapping)
+	if v, ok, err := recv.Get(key); err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	} else if ok {
+		return v, nil
+	}
+	if err := recv.SetKey(key, dflt); err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	return dflt, nil
+}
+
+func dictUpdate(b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("update: got %d arguments, want at most 1", len(args))
+	}
+	recv := b.Receiver().(mapping)
+
+	if len(args) == 1 {
+		switch updates := args[0].(type) {
+		case starlark.IterableMapping:
+			for _, item := range updates.Items() {
+				if err := recv.SetKey(item[0], item[1]); err != nil {
+					return nil, fmt.Errorf("%s: %v", b.Name(), err)
+				}
+			}
+		case starlark.Iterable:
+			iter := updates.Iterate()
+			defer iter.Done()
+			var pair starlark.Value
+			for i := 0; iter.Next(&pair); i++ {
+				seq, ok := pair.(starlark.Indexable)
+				if !ok || seq.Len() != 2 {
+					return nil, fmt.Errorf("%s: element #%d is not a pair", b.Name(), i)
+				}
+				if err := recv.SetKey(seq.Index(0), seq.Index(1)); err != nil {
+					return nil, fmt.Errorf("%s: %v", b.Name(), err)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("%s: got %s, want iterable", b.Name(), updates.Type())
+		}
+	}
+
+	for _, pair := range kwargs {
+		if err := recv.SetKey(pair[0], pair[1]); err != nil {
+			return nil, fmt.Errorf("%s: %v", b.Name(), err)
+		}
+	}
+	return starlark.None, nil
+}
+
+// keyIterator iterates over a snapshot of the keys, so the mapping can be
+// modified while iterating.
+type keyIterator struct {
+	keys []starlark.Value
+}
+
+func newKeyIterator(items []starlark.Tuple) *keyIterator {
+	keys := make([]starlark.Value, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, item[0])
+	}
+	return &keyIterator{keys: keys}
+}
+
+func (i *keyIterator) Next(p *starlark.Value) bool {
+	if len(i.keys) == 0 {
+		return false
+	}
+	*p = i.keys[0]
+	i.keys = i.keys[1:]
+	return true
+}
+
+func (i *keyIterator) Done() {
+}
+
+var errFrozen = errors.New("cannot modify frozen metric")
+
+func keyString(k starlark.Value) (string, error) {
+	key, ok := k.(starlark.String)
+	if !ok {
+		return "", fmt.Errorf("key must be of type 'str', not '%s'", k.Type())
+	}
+	return key.GoString(), nil
+}
@@ -0,0 +1,178 @@
+package starlark
+
+import (
+	"errors"
+	"fmt"
+
+	"go.starlark.net/starlark"
+)
+
+// FieldDict is a dict-like view of the fields of a metric.
+type FieldDict struct {
+	m *Metric
+}
+
+func (d FieldDict) String() string {
+	return dictString(d)
+}
+
+func (d FieldDict) Type() string {
+	return "Fields"
+}
+
+func (d FieldDict) Freeze() {
+	d.m.Freeze()
+}
+
+func (d FieldDict) Truth() starlark.Bool {
+	return len(d.m.metric.FieldList()) != 0
+}
+
+func (d FieldDict) Hash() (uint32, error) {
+	return 0, errors.New("not hashable")
+}
+
+// AttrNames implements the starlark.HasAttrs interface.
+func (d FieldDict) AttrNames() []string {
+	return builtinAttrNames()
+}
+
+// Attr implements the starlark.HasAttrs interface.
+func (d FieldDict) Attr(name string) (starlark.Value, error) {
+	return builtinAttr(d, name)
+}
+
+// Get implements the starlark.Mapping interface.
+func (d FieldDict) Get(k starlark.Value) (v starlark.Value, found bool, err error) {
+	key, err := keyString(k)
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, ok := d.m.metric.GetField(key)
+	if ok {
+		sv, err := asStarlarkValue(value)
+		return sv, true, err
+	}
+	return starlark.None, false, nil
+}
+
+// SetKey implements the starlark.HasSetKey interface.
+func (d FieldDict) SetKey(k, v starlark.Value) error {
+	if d.m.frozen {
+		return errFrozen
+	}
+
+	key, err := keyString(k)
+	if err != nil {
+		return err
+	}
+
+	value, err := asGoValue(v)
+	if err != nil {
+		return err
+	}
+
+	d.m.metric.AddField(key, value)
+	return nil
+}
+
+// Items implements the starlark.IterableMapping interface.
+func (d FieldDict) Items() []starlark.Tuple {
+	items := make([]starlark.Tuple, 0, len(d.m.metric.FieldList()))
+	for _, field := range d.m.metric.FieldList() {
+		sv, err := asStarlarkValue(field.Value)
+		if err != nil {
+			continue
+		}
+		pair := starlark.Tuple{starlark.String(field.Key), sv}
+		items = append(items, pair)
+	}
+	return items
+}
+
+// Iterate implements the starlark.Iterable interface.
+func (d FieldDict) Iterate() starlark.Iterator {
+	return newKeyIterator(d.Items())
+}
+
+// Len implements the starlark.Sequence interface.
+func (d FieldDict) Len() int {
+	return len(d.m.metric.FieldList())
+}
+
+// Delete removes a field, returning its value if it was set.
+func (d FieldDict) Delete(k starlark.Value) (v starlark.Value, found bool, err error) {
+	if d.m.frozen {
+		return nil, false, errFrozen
+	}
+
+	key, err := keyString(k)
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, ok := d.m.metric.GetField(key)
+	if ok {
+		d.m.metric.RemoveField(key)
+		sv, err := asStarlarkValue(value)
+		return sv, true, err
+	}
+	return starlark.None, false, nil
+}
+
+// Clear removes all fields.
+func (d FieldDict) Clear() error {
+	if d.m.frozen {
+		return errFrozen
+	}
+
+	keys := make([]string, 0, len(d.m.metric.FieldList()))
+	for _, field := range d.m.metric.FieldList() {
+		keys = append(keys, field.Key)
+	}
+	for _, key := range keys {
+		d.m.metric.RemoveField(key)
+	}
+	return nil
+}
+
+// asStarlarkValue converts a field value to a Starlark value.
+func asStarlarkValue(value interface{}) (starlark.Value, error) {
+	switch v := value.(type) {
+	case float64:
+		return starlark.Float(v), nil
+	case int64:
+		return starlark.MakeInt64(v), nil
+	case uint64:
+		return starlark.MakeUint64(v), nil
+	case string:
+		return starlark.String(v), nil
+	case bool:
+		return starlark.Bool(v), nil
+	}
+
+	return starlark.None, fmt.Errorf("invalid type %T", value)
+}
+
+// asGoValue converts a Starlark value to a field value.
+func asGoValue(value starlark.Value) (interface{}, error) {
+	switch v := value.(type) {
+	case starlark.Float:
+		return float64(v), nil
+	case starlark.Int:
+		if n, ok := v.Int64(); ok {
+			return n, nil
+		}
+		if n, ok := v.Uint64(); ok {
+			return n, nil
+		}
+		return nil, errors.New("field value out of range")
+	case starlark.String:
+		return v.GoString(), nil
+	case starlark.Bool:
+		return bool(v), nil
+	}
+
+	return nil, fmt.Errorf("field value must be a float, int, str or bool, not '%s'", value.Type())
+}
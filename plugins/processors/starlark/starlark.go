@@ -0,0 +1,226 @@
+package starlark
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"go.starlark.net/starlark"
+)
+
+const (
+	description  = "Process metrics using a Starlark script"
+	sampleConfig = `
+  ## The Starlark source can be set as a string in this configuration file, or
+  ## by referencing a file containing the script.  Only one source or script
+  ## should be set at once.
+  ##
+  ## Source of the Starlark script.
+  source = '''
+def apply(metric):
+	return metric
+'''
+
+  ## File containing a Starlark script.
+  # script = "/usr/local/bin/myscript.star"
+`
+)
+
+type Starlark struct {
+	Source string `toml:"source"`
+	Script string `toml:"script"`
+
+	initialized bool
+	err         error
+	thread      *starlark.Thread
+	applyFunc   *starlark.Function
+	args        starlark.Tuple
+	results     []telegraf.Metric
+
+	logName string
+}
+
+func (s *Starlark) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Starlark) Description() string {
+	return description
+}
+
+// SetLogName sets the name of the plugin in log messages.
+func (s *Starlark) SetLogName(name string) {
+	s.logName = name
+}
+
+func (s *Starlark) Apply(metrics ...telegraf.Metric) []telegraf.Metric {
+	// The script is compiled once, if it fails the metrics are passed
+	// through unchanged.
+	if !s.initialized {
+		s.initialized = true
+		s.err = s.compile()
+		if s.err != nil {
+			log.Printf("E! [%s] initialization error, "+
+				"metrics are passed unchanged: %v", s.logName, s.err)
+		}
+	}
+	if s.err != nil {
+		return metrics
+	}
+
+	s.results = s.results[:0]
+	for _, m := range metrics {
+		err := s.apply(m)
+		if err != nil {
+			log.Printf("E! [%s] %v", s.logName, err)
+			m.Drop()
+		}
+	}
+
+	// The results are owned by the caller, so they must not be reused.
+	results := make([]telegraf.Metric, len(s.results))
+	copy(results, s.results)
+	return results
+}
+
+// apply calls the apply function of the script with a single metric and
+// appends the returned metrics to the results.
+func (s *Starlark) apply(m telegraf.Metric) error {
+	sm := &Metric{metric: m}
+	s.args[0] = sm
+
+	rv, err := starlark.Call(s.thread, s.applyFunc, s.args, nil)
+	if err != nil {
+		if err, ok := err.(*starlark.EvalError); ok {
+			for _, line := range splitLines(err.Backtrace()) {
+				log.Printf("E! [%s] %s", s.logName, line)
+			}
+		}
+		return err
+	}
+
+	var emitted []*Metric
+	switch rv := rv.(type) {
+	case *starlark.List:
+		iter := rv.Iterate()
+		defer iter.Done()
+
+		var v starlark.Value
+		for iter.Next(&v) {
+			switch v := v.(type) {
+			case *Metric:
+				emitted = append(emitted, v)
+			default:
+				return fmt.Errorf("invalid type returned in list: %s", v.Type())
+			}
+		}
+	case *Metric:
+		emitted = append(emitted, rv)
+	case starlark.NoneType:
+	default:
+		return fmt.Errorf("invalid type returned: %s", rv.Type())
+	}
+
+	seen := make(map[*Metric]bool, len(emitted))
+	for _, v := range emitted {
+		if seen[v] {
+			return errors.New("metric returned more than once")
+		}
+		if v.frozen {
+			return errors.New("frozen metric returned, metrics can only be emitted once")
+		}
+		seen[v] = true
+	}
+
+	keepOriginal := false
+	for _, v := range emitted {
+		if v.metric == m {
+			keepOriginal = true
+		}
+		// Further changes to the metric, for instance through the shared
+		// state in a later call, must not alter an emitted metric.
+		v.Freeze()
+		s.results = append(s.results, v.metric)
+	}
+
+	if !keepOriginal {
+		m.Drop()
+	}
+	return nil
+}
+
+func (s *Starlark) compile() error {
+	if s.Source != "" && s.Script != "" {
+		return errors.New("both source and script cannot be set")
+	}
+
+	src := s.Source
+	filename := "processors.starlark"
+	if s.Script != "" {
+		b, err := ioutil.ReadFile(s.Script)
+		if err != nil {
+			return err
+		}
+		src = string(b)
+		filename = s.Script
+	}
+	if src == "" {
+		return errors.New("one of source or script must be set")
+	}
+
+	s.thread = &starlark.Thread{
+		Print: func(_ *starlark.Thread, msg string) {
+			log.Printf("I! [%s] %s", s.logName, msg)
+		},
+	}
+
+	builtins := starlark.StringDict{}
+	builtins["Metric"] = starlark.NewBuiltin("Metric", newMetric)
+	builtins["deepcopy"] = starlark.NewBuiltin("deepcopy", deepcopy)
+
+	// The state dictionary is not frozen with the globals of the script,
+	// allowing it to keep data between calls to apply.
+	builtins["state"] = starlark.NewDict(0)
+
+	globals, err := starlark.ExecFile(s.thread, filename, src, builtins)
+	if err != nil {
+		if err, ok := err.(*starlark.EvalError); ok {
+			for _, line := range splitLines(err.Backtrace()) {
+				log.Printf("E! [%s] %s", s.logName, line)
+			}
+		}
+		return err
+	}
+
+	apply, ok := globals["apply"]
+	if !ok {
+		return errors.New("apply is not defined")
+	}
+
+	s.applyFunc, ok = apply.(*starlark.Function)
+	if !ok {
+		return errors.New("apply is not a function")
+	}
+
+	if s.applyFunc.NumParams() != 1 {
+		return errors.New("apply function must take one parameter")
+	}
+
+	// Reuse the argument tuple across calls.
+	s.args = make(starlark.Tuple, 1)
+	return nil
+}
+
+func splitLines(s string) []string {
+	return strings.Split(strings.TrimSpace(s), "\n")
+}
+
+func init() {
+	processors.Add("starlark", func() telegraf.Processor {
+		return &Starlark{logName: "processors.starlark"}
+	})
+}
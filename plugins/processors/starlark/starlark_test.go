@@ -0,0 +1,473 @@
+package starlark
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStarlark(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		input    []telegraf.Metric
+		expected []telegraf.Metric
+	}{
+		{
+			name: "passthrough",
+			source: `
+def apply(metric):
+	return metric
+`,
+			input: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{},
+					map[string]interface{}{"value": 42},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{},
+					map[string]interface{}{"value": 42},
+					time.Unix(0, 0),
+				),
+			},
+		},
+		{
+			name: "return none drops metric",
+			source: `
+def apply(metric):
+	return None
+`,
+			input: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{},
+					map[string]interface{}{"value": 42},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []telegraf.Metric{},
+		},
+		{
+			name: "modify name tags fields and time",
+			source: `
+def apply(metric):
+	metric.name = "system"
+	metric.tags["host"] = "example.org"
+	metric.tags.pop("cpu")
+	metric.fields["value"] = metric.fields["value"] * 2
+	metric.fields["ok"] = True
+	metric.time = metric.time + 1000
+	return metric
+`,
+			input: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{"cpu": "cpu0"},
+					map[string]interface{}{"value": 42},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []telegraf.Metric{
+				testutil.MustMetric("system",
+					map[string]string{"host": "example.org"},
+					map[string]interface{}{"value": 84, "ok": true},
+					time.Unix(0, 1000),
+				),
+			},
+		},
+		{
+			name: "field types",
+			source: `
+def apply(metric):
+	metric.fields["float"] = metric.fields["int"] / 2
+	metric.fields["string"] = str(metric.fields["uint"])
+	return metric
+`,
+			input: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{},
+					map[string]interface{}{
+						"int":  int64(3),
+						"uint": uint64(42),
+					},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{},
+					map[string]interface{}{
+						"int":    int64(3),
+						"uint":   uint64(42),
+						"float":  1.5,
+						"string": "42",
+					},
+					time.Unix(0, 0),
+				),
+			},
+		},
+		{
+			name: "iterate and delete while iterating",
+			source: `
+def apply(metric):
+	for k in metric.tags:
+		if k.startswith("tmp_"):
+			metric.tags.pop(k)
+	for k, v in metric.fields.items():
+		metric.fields[k + "_x"] = v
+	return metric
+`,
+			input: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{
+						"host":  "example.org",
+						"tmp_a": "a",
+						"tmp_b": "b",
+					},
+					map[string]interface{}{"value": 42},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{"host": "example.org"},
+					map[string]interface{}{"value": 42, "value_x": 42},
+					time.Unix(0, 0),
+				),
+			},
+		},
+		{
+			name: "return multiple metrics",
+			source: `
+def apply(metric):
+	result = [metric]
+	for k, v in metric.fields.items():
+		m = Metric("split")
+		m.tags.update(metric.tags)
+		m.tags["field"] = k
+		m.fields["value"] = v
+		m.time = metric.time
+		result.append(m)
+	return result
+`,
+			input: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{"host": "example.org"},
+					map[string]interface{}{"a": 1, "b": 2},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{"host": "example.org"},
+					map[string]interface{}{"a": 1, "b": 2},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric("split",
+					map[string]string{"host": "example.org", "field": "a"},
+					map[string]interface{}{"value": 1},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric("split",
+					map[string]string{"host": "example.org", "field": "b"},
+					map[string]interface{}{"value": 2},
+					time.Unix(0, 0),
+				),
+			},
+		},
+		{
+			name: "deepcopy",
+			source: `
+def apply(metric):
+	copy = deepcopy(metric)
+	copy.name = "copy"
+	return [metric, copy]
+`,
+			input: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{},
+					map[string]interface{}{"value": 42},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{},
+					map[string]interface{}{"value": 42},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric("copy",
+					map[string]string{},
+					map[string]interface{}{"value": 42},
+					time.Unix(0, 0),
+				),
+			},
+		},
+		{
+			name: "state is kept between calls",
+			source: `
+def apply(metric):
+	state["count"] = state.get("count", 0) + 1
+	metric.fields["count"] = state["count"]
+	return metric
+`,
+			input: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{},
+					map[string]interface{}{"value": 42},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric("cpu",
+					map[string]string{},
+					map[string]interface{}{"value": 42},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{},
+					map[string]interface{}{"value": 42, "count": 1},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric("cpu",
+					map[string]string{},
+					map[string]interface{}{"value": 42, "count": 2},
+					time.Unix(0, 0),
+				),
+			},
+		},
+		{
+			name: "runtime error drops metric",
+			source: `
+def apply(metric):
+	if metric.name == "bad":
+		return 1 / 0
+	return metric
+`,
+			input: []telegraf.Metric{
+				testutil.MustMetric("bad",
+					map[string]string{},
+					map[string]interface{}{"value": 42},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric("good",
+					map[string]string{},
+					map[string]interface{}{"value": 42},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []telegraf.Metric{
+				testutil.MustMetric("good",
+					map[string]string{},
+					map[string]interface{}{"value": 42},
+					time.Unix(0, 0),
+				),
+			},
+		},
+		{
+			name: "invalid return type drops metric",
+			source: `
+def apply(metric):
+	return "cpu"
+`,
+			input: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{},
+					map[string]interface{}{"value": 42},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []telegraf.Metric{},
+		},
+		{
+			name: "metric returned twice drops metric",
+			source: `
+def apply(metric):
+	return [metric, metric]
+`,
+			input: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{},
+					map[string]interface{}{"value": 42},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []telegraf.Metric{},
+		},
+		{
+			name: "emitted metric cannot be modified later",
+			source: `
+def apply(metric):
+	last = state.get("last")
+	if last != None:
+		last.fields["late"] = True
+	state["last"] = metric
+	return metric
+`,
+			input: []telegraf.Metric{
+				testutil.MustMetric("first",
+					map[string]string{},
+					map[string]interface{}{"value": 42},
+					time.Unix(0, 0),
+				),
+				testutil.MustMetric("second",
+					map[string]string{},
+					map[string]interface{}{"value": 42},
+					time.Unix(0, 0),
+				),
+			},
+			expected: []telegraf.Metric{
+				testutil.MustMetric("first",
+					map[string]string{},
+					map[string]interface{}{"value": 42},
+					time.Unix(0, 0),
+				),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &Starlark{
+				Source: tt.source,
+			}
+
+			// The metrics created from the fields of a metric are in
+			// the random order of the fields.
+			actual := plugin.Apply(tt.input...)
+			testutil.RequireMetricsEqual(t, tt.expected, actual, testutil.SortMetrics())
+		})
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin *Starlark
+	}{
+		{
+			name:   "no source",
+			plugin: &Starlark{},
+		},
+		{
+			name: "source and script",
+			plugin: &Starlark{
+				Source: "def apply(metric):\n\treturn metric\n",
+				Script: "testdata/script.star",
+			},
+		},
+		{
+			name: "syntax error",
+			plugin: &Starlark{
+				Source: "def apply(metric)\n",
+			},
+		},
+		{
+			name: "apply not defined",
+			plugin: &Starlark{
+				Source: "def process(metric):\n\treturn metric\n",
+			},
+		},
+		{
+			name: "apply wrong arguments",
+			plugin: &Starlark{
+				Source: "def apply():\n\treturn None\n",
+			},
+		},
+		{
+			name: "missing script",
+			plugin: &Starlark{
+				Script: "testdata/does-not-exist.star",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.plugin.compile())
+		})
+	}
+}
+
+func TestCompileErrorPassesMetrics(t *testing.T) {
+	plugin := &Starlark{}
+
+	input := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{"value": 42},
+			time.Unix(0, 0),
+		),
+	}
+	actual := plugin.Apply(input...)
+	testutil.RequireMetricsEqual(t, input, actual)
+}
+
+func TestCompileErrorNotRetried(t *testing.T) {
+	dir, err := ioutil.TempDir("", "starlark")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	plugin := &Starlark{
+		Script: filepath.Join(dir, "script.star"),
+	}
+
+	input := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{"value": 42},
+			time.Unix(0, 0),
+		),
+	}
+	actual := plugin.Apply(input...)
+	testutil.RequireMetricsEqual(t, input, actual)
+
+	// The script is not loaded again once it failed.
+	err = ioutil.WriteFile(plugin.Script, []byte("def apply(metric):\n\treturn None\n"), 0644)
+	require.NoError(t, err)
+
+	actual = plugin.Apply(input...)
+	testutil.RequireMetricsEqual(t, input, actual)
+}
+
+func TestScript(t *testing.T) {
+	f, err := ioutil.TempFile("", "script.star")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(`
+def apply(metric):
+	metric.tags["script"] = "yes"
+	return metric
+`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	plugin := &Starlark{
+		Script: f.Name(),
+	}
+
+	actual := plugin.Apply(
+		testutil.MustMetric("cpu",
+			map[string]string{},
+			map[string]interface{}{"value": 42},
+			time.Unix(0, 0),
+		),
+	)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"script": "yes"},
+			map[string]interface{}{"value": 42},
+			time.Unix(0, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
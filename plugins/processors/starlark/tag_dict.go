@@ -0,0 +1,135 @@
+package starlark
+
+import (
+	"errors"
+	"fmt"
+
+	"go.starlark.net/starlark"
+)
+
+// TagDict is a dict-like view of the tags of a metric.
+type TagDict struct {
+	m *Metric
+}
+
+func (d TagDict) String() string {
+	return dictString(d)
+}
+
+func (d TagDict) Type() string {
+	return "Tags"
+}
+
+func (d TagDict) Freeze() {
+	d.m.Freeze()
+}
+
+func (d TagDict) Truth() starlark.Bool {
+	return len(d.m.metric.TagList()) != 0
+}
+
+func (d TagDict) Hash() (uint32, error) {
+	return 0, errors.New("not hashable")
+}
+
+// AttrNames implements the starlark.HasAttrs interface.
+func (d TagDict) AttrNames() []string {
+	return builtinAttrNames()
+}
+
+// Attr implements the starlark.HasAttrs interface.
+func (d TagDict) Attr(name string) (starlark.Value, error) {
+	return builtinAttr(d, name)
+}
+
+// Get implements the starlark.Mapping interface.
+func (d TagDict) Get(k starlark.Value) (v starlark.Value, found bool, err error) {
+	key, err := keyString(k)
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, ok := d.m.metric.GetTag(key)
+	if ok {
+		return starlark.String(value), true, nil
+	}
+	return starlark.None, false, nil
+}
+
+// SetKey implements the starlark.HasSetKey interface.
+func (d TagDict) SetKey(k, v starlark.Value) error {
+	if d.m.frozen {
+		return errFrozen
+	}
+
+	key, err := keyString(k)
+	if err != nil {
+		return err
+	}
+
+	value, ok := v.(starlark.String)
+	if !ok {
+		return fmt.Errorf("tag value must be of type 'str', not '%s'", v.Type())
+	}
+
+	d.m.metric.AddTag(key, value.GoString())
+	return nil
+}
+
+// Items implements the starlark.IterableMapping interface.
+func (d TagDict) Items() []starlark.Tuple {
+	items := make([]starlark.Tuple, 0, len(d.m.metric.TagList()))
+	for _, tag := range d.m.metric.TagList() {
+		pair := starlark.Tuple{
+			starlark.String(tag.Key),
+			starlark.String(tag.Value),
+		}
+		items = append(items, pair)
+	}
+	return items
+}
+
+// Iterate implements the starlark.Iterable interface.
+func (d TagDict) Iterate() starlark.Iterator {
+	return newKeyIterator(d.Items())
+}
+
+// Len implements the starlark.Sequence interface.
+func (d TagDict) Len() int {
+	return len(d.m.metric.TagList())
+}
+
+// Delete removes a tag, returning its value if it was set.
+func (d TagDict) Delete(k starlark.Value) (v starlark.Value, found bool, err error) {
+	if d.m.frozen {
+		return nil, false, errFrozen
+	}
+
+	key, err := keyString(k)
+	if err != nil {
+		return nil, false, err
+	}
+
+	value, ok := d.m.metric.GetTag(key)
+	if ok {
+		d.m.metric.RemoveTag(key)
+		return starlark.String(value), true, nil
+	}
+	return starlark.None, false, nil
+}
+
+// Clear removes all tags.
+func (d TagDict) Clear() error {
+	if d.m.frozen {
+		return errFrozen
+	}
+
+	keys := make([]string, 0, len(d.m.metric.TagList()))
+	for _, tag := range d.m.metric.TagList() {
+		keys = append(keys, tag.Key)
+	}
+	for _, key := range keys {
+		d.m.metric.RemoveTag(key)
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package tag_limit
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const sampleConfig = `
+  ## Maximum number of tags of a metric, 0 for no limit.  The tags listed in
+  ## keep are preserved first, followed by the other tags in order of their
+  ## keys.
+  # limit = 0
+  # keep = ["host"]
+
+  ## Maximum number of distinct values of a tag key seen within the window, 0
+  ## for no limit.  Values already seen within the window are accepted.
+  # max_values = 0
+
+  ## Tag keys the max_values limit applies to, supports globs.  The limit
+  ## applies to all tag keys if empty.
+  # tag_keys = []
+
+  ## Length of the sliding window, a value is forgotten if it has not been
+  ## seen for this long.
+  # window = "1h"
+
+  ## What to do with metrics exceeding a limit: "collapse" removes the excess
+  ## tags and replaces the excess tag values with the other_value, "drop"
+  ## drops the metric.
+  # action = "collapse"
+  # other_value = "other"
+`
+
+const (
+	actionCollapse = "collapse"
+	actionDrop     = "drop"
+
+	defaultWindow = time.Hour
+)
+
+type TagLimit struct {
+	Limit      int               `toml:"limit"`
+	Keep       []string          `toml:"keep"`
+	MaxValues  int               `toml:"max_values"`
+	TagKeys    []string          `toml:"tag_keys"`
+	Window     internal.Duration `toml:"window"`
+	Action     string            `toml:"action"`
+	OtherValue string            `toml:"other_value"`
+
+	TagsRemoved     selfstat.Stat
+	ValuesCollapsed selfstat.Stat
+	MetricsDropped  selfstat.Stat
+
+	init      bool
+	keep      map[string]bool
+	tagFilter filter.Filter
+	now       func() time.Time
+
+	// values holds the time each tag value was last seen, by tag key.
+	values      map[string]map[string]time.Time
+	exceeded    map[string]bool
+	lastCleanup time.Time
+
+	alias   string
+	logName string
+}
+
+func (t *TagLimit) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *TagLimit) Description() string {
+	return "Limit the number of tags and the number of distinct tag values"
+}
+
+// SetLogName sets the name of the plugin in log messages.
+func (t *TagLimit) SetLogName(name string) {
+	t.logName = name
+}
+
+// SetAlias sets the alias of the plugin, the internal metrics are tagged
+// with it.
+func (t *TagLimit) SetAlias(alias string) {
+	t.alias = alias
+}
+
+// Init checks the action.
+func (t *TagLimit) Init() error {
+	switch t.Action {
+	case actionCollapse, actionDrop:
+	default:
+		return fmt.Errorf("invalid action %q, must be %q or %q", t.Action, actionCollapse, actionDrop)
+	}
+
+	t.initOnce()
+	return nil
+}
+
+func (t *TagLimit) initOnce() {
+	t.keep = make(map[string]bool, len(t.Keep))
+	for _, key := range t.Keep {
+		t.keep[key] = true
+	}
+
+	var err error
+	t.tagFilter, err = filter.Compile(t.TagKeys)
+	if err != nil {
+		log.Printf("E! [%s] Error compiling tag_keys, limiting all tags: %v", t.logName, err)
+	}
+
+	if t.MaxValues > 0 && t.Window.Duration <= 0 {
+		log.Printf("W! [%s] Invalid window %s, using %s", t.logName, t.Window.Duration, defaultWindow)
+		t.Window.Duration = defaultWindow
+	}
+
+	if t.now == nil {
+		t.now = time.Now
+	}
+	t.values = make(map[string]map[string]time.Time)
+	t.exceeded = make(map[string]bool)
+
+	// The stats of each instance are tagged with its alias.
+	tags := map[string]string{}
+	if t.alias != "" {
+		tags["alias"] = t.alias
+	}
+	t.TagsRemoved = selfstat.Register("tag_limit", "tags_removed", tags)
+	t.ValuesCollapsed = selfstat.Register("tag_limit", "values_collapsed", tags)
+	t.MetricsDropped = selfstat.Register("tag_limit", "metrics_dropped", tags)
+	t.init = true
+}
+
+func (t *TagLimit) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if !t.init {
+		t.initOnce()
+	}
+
+	now := t.now()
+	t.cleanup(now)
+
+	out := in[:0]
+	for _, metric := range in {
+		accepted, ok := t.limitValues(metric)
+		if !ok || !t.limitTags(metric) {
+			t.MetricsDropped.Incr(1)
+			metric.Drop()
+			continue
+		}
+
+		// The values are only recorded once the metric has passed all
+		// limits, the values of a dropped metric are not seen.
+		for _, tag := range accepted {
+			seen, ok := t.values[tag.Key]
+			if !ok {
+				seen = make(map[string]time.Time)
+				t.values[tag.Key] = seen
+			}
+			seen[tag.Value] = now
+		}
+		out = append(out, metric)
+	}
+	return out
+}
+
+// limitValues enforces the max_values limit, it returns the tags with
+// accepted values, and false if the metric should be dropped.
+func (t *TagLimit) limitValues(metric telegraf.Metric) ([]*telegraf.Tag, bool) {
+	if t.MaxValues <= 0 {
+		return nil, true
+	}
+
+	var accepted []*telegraf.Tag
+	var collapse []string
+	for _, tag := range metric.TagList() {
+		if t.tagFilter != nil && !t.tagFilter.Match(tag.Key) {
+			continue
+		}
+
+		seen := t.values[tag.Key]
+		if _, ok := seen[tag.Value]; ok || len(seen) < t.MaxValues {
+			accepted = append(accepted, &telegraf.Tag{Key: tag.Key, Value: tag.Value})
+			continue
+		}
+
+		if !t.exceeded[tag.Key] {
+			log.Printf("W! [%s] Tag %q exceeded %d distinct values", t.logName, tag.Key, t.MaxValues)
+			t.exceeded[tag.Key] = true
+		}
+		if t.Action == actionDrop {
+			return nil, false
+		}
+		collapse = append(collapse, tag.Key)
+	}
+
+	for _, key := range collapse {
+		metric.AddTag(key, t.OtherValue)
+	}
+	t.ValuesCollapsed.Incr(int64(len(collapse)))
+	return accepted, true
+}
+
+// limitTags enforces the limit of tags per metric, it returns false if the
+// metric should be dropped.
+func (t *TagLimit) limitTags(metric telegraf.Metric) bool {
+	if t.Limit <= 0 || len(metric.TagList()) <= t.Limit {
+		return true
+	}
+	if t.Action == actionDrop {
+		return false
+	}
+
+	keys := make([]string, 0, len(metric.TagList()))
+	for _, tag := range metric.TagList() {
+		keys = append(keys, tag.Key)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return t.keep[keys[i]] && !t.keep[keys[j]]
+	})
+
+	for _, key := range keys[t.Limit:] {
+		metric.RemoveTag(key)
+	}
+	t.TagsRemoved.Incr(int64(len(keys) - t.Limit))
+	return true
+}
+
+// cleanup forgets the tag values that have not been seen within the window.
+// It runs at a tenth of the window so values expire close to the window
+// length.
+func (t *TagLimit) cleanup(now time.Time) {
+	if now.Sub(t.lastCleanup) < t.Window.Duration/10 {
+		return
+	}
+	t.lastCleanup = now
+
+	for key, seen := range t.values {
+		for value, last := range seen {
+			if now.Sub(last) >= t.Window.Duration {
+				delete(seen, value)
+			}
+		}
+		if len(seen) < t.MaxValues {
+			delete(t.exceeded, key)
+		}
+		if len(seen) == 0 {
+			delete(t.values, key)
+		}
+	}
+}
+
+func init() {
+	processors.Add("tag_limit", func() telegraf.Processor {
+		return &TagLimit{
+			Window:     internal.Duration{Duration: defaultWindow},
+			Action:     actionCollapse,
+			OtherValue: "other",
+			logName:    "processors.tag_limit",
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package tag_limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(tags map[string]string) telegraf.Metric {
+	return testutil.MustMetric("http",
+		tags,
+		map[string]interface{}{"value": 1.0},
+		time.Unix(0, 0))
+}
+
+func TestLimit(t *testing.T) {
+	plugin := &TagLimit{
+		Limit:  2,
+		Keep:   []string{"method", "host"},
+		Action: actionCollapse,
+	}
+	plugin.initOnce()
+	plugin.TagsRemoved.Set(0)
+
+	actual := plugin.Apply(
+		newMetric(map[string]string{"a": "1", "b": "2", "host": "web01", "method": "GET"}),
+		newMetric(map[string]string{"a": "1", "host": "web01", "method": "GET"}),
+		newMetric(map[string]string{"a": "1", "b": "2"}),
+	)
+	expected := []telegraf.Metric{
+		newMetric(map[string]string{"host": "web01", "method": "GET"}),
+		newMetric(map[string]string{"host": "web01", "method": "GET"}),
+		newMetric(map[string]string{"a": "1", "b": "2"}),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+	require.Equal(t, int64(3), plugin.TagsRemoved.Get())
+}
+
+func TestLimitDrop(t *testing.T) {
+	plugin := &TagLimit{
+		Limit:  1,
+		Action: actionDrop,
+	}
+
+	actual := plugin.Apply(
+		newMetric(map[string]string{"a": "1", "b": "2"}),
+		newMetric(map[string]string{"a": "1"}),
+	)
+	expected := []telegraf.Metric{
+		newMetric(map[string]string{"a": "1"}),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestMaxValuesCollapse(t *testing.T) {
+	plugin := &TagLimit{
+		MaxValues:  2,
+		TagKeys:    []string{"path*"},
+		Window:     internal.Duration{Duration: time.Hour},
+		Action:     actionCollapse,
+		OtherValue: "other",
+	}
+	plugin.initOnce()
+	plugin.ValuesCollapsed.Set(0)
+
+	actual := plugin.Apply(
+		newMetric(map[string]string{"path": "/a", "host": "web01"}),
+		newMetric(map[string]string{"path": "/b", "host": "web02"}),
+		newMetric(map[string]string{"path": "/c", "host": "web03"}),
+		newMetric(map[string]string{"path": "/a", "host": "web04"}),
+	)
+	expected := []telegraf.Metric{
+		newMetric(map[string]string{"path": "/a", "host": "web01"}),
+		newMetric(map[string]string{"path": "/b", "host": "web02"}),
+		newMetric(map[string]string{"path": "other", "host": "web03"}),
+		newMetric(map[string]string{"path": "/a", "host": "web04"}),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+	require.Equal(t, int64(1), plugin.ValuesCollapsed.Get())
+}
+
+func TestMaxValuesDrop(t *testing.T) {
+	plugin := &TagLimit{
+		MaxValues: 1,
+		Window:    internal.Duration{Duration: time.Hour},
+		Action:    actionDrop,
+	}
+	plugin.initOnce()
+	plugin.MetricsDropped.Set(0)
+
+	var delivered []telegraf.DeliveryInfo
+	notify := func(di telegraf.DeliveryInfo) {
+		delivered = append(delivered, di)
+	}
+	tm, _ := metric.WithTracking(newMetric(map[string]string{"path": "/b"}), notify)
+
+	actual := plugin.Apply(
+		newMetric(map[string]string{"path": "/a"}),
+		tm,
+	)
+	expected := []telegraf.Metric{
+		newMetric(map[string]string{"path": "/a"}),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+	require.Len(t, delivered, 1)
+	require.Equal(t, int64(1), plugin.MetricsDropped.Get())
+}
+
+func TestMaxValuesWindow(t *testing.T) {
+	now := time.Unix(0, 0)
+	plugin := &TagLimit{
+		MaxValues:  1,
+		Window:     internal.Duration{Duration: time.Minute},
+		Action:     actionCollapse,
+		OtherValue: "other",
+		now:        func() time.Time { return now },
+	}
+
+	actual := plugin.Apply(newMetric(map[string]string{"path": "/a"}))
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{newMetric(map[string]string{"path": "/a"})}, actual)
+
+	now = now.Add(30 * time.Second)
+	actual = plugin.Apply(newMetric(map[string]string{"path": "/b"}))
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{newMetric(map[string]string{"path": "other"})}, actual)
+
+	// The first value has expired, the new value is accepted.
+	now = now.Add(time.Minute)
+	actual = plugin.Apply(newMetric(map[string]string{"path": "/b"}))
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{newMetric(map[string]string{"path": "/b"})}, actual)
+}
+
+func TestMaxValuesDropNotRecorded(t *testing.T) {
+	plugin := &TagLimit{
+		MaxValues: 1,
+		Window:    internal.Duration{Duration: time.Hour},
+		Action:    actionDrop,
+	}
+
+	// The value of "path" is not recorded, the metric is dropped for "host".
+	actual := plugin.Apply(
+		newMetric(map[string]string{"host": "a"}),
+		newMetric(map[string]string{"host": "b", "path": "/b"}),
+		newMetric(map[string]string{"path": "/c"}),
+	)
+	expected := []telegraf.Metric{
+		newMetric(map[string]string{"host": "a"}),
+		newMetric(map[string]string{"path": "/c"}),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestDefaultWindow(t *testing.T) {
+	plugin := &TagLimit{
+		MaxValues: 1,
+		Action:    actionDrop,
+	}
+	plugin.initOnce()
+	require.Equal(t, defaultWindow, plugin.Window.Duration)
+
+	actual := plugin.Apply(newMetric(map[string]string{"path": "/a"}))
+	require.Len(t, actual, 1)
+	actual = plugin.Apply(newMetric(map[string]string{"path": "/b"}))
+	require.Len(t, actual, 0)
+}
+
+func TestStatsAlias(t *testing.T) {
+	plugin := &TagLimit{
+		MaxValues: 1,
+		Window:    internal.Duration{Duration: time.Hour},
+		Action:    actionDrop,
+	}
+	plugin.SetAlias("paths")
+	require.NoError(t, plugin.Init())
+	plugin.Apply(
+		newMetric(map[string]string{"path": "/a"}),
+		newMetric(map[string]string{"path": "/b"}),
+	)
+
+	for _, m := range selfstat.Metrics() {
+		if m.Name() != "internal_tag_limit" {
+			continue
+		}
+		if alias, ok := m.GetTag("alias"); ok && alias == "paths" {
+			field, ok := m.GetField("metrics_dropped")
+			require.True(t, ok)
+			require.Equal(t, int64(1), field)
+			return
+		}
+	}
+	t.Fatal("no stats with the alias tag")
+}
+
+func TestInitAction(t *testing.T) {
+	plugin := &TagLimit{Action: "truncate"}
+	require.Error(t, plugin.Init())
+
+	plugin = &TagLimit{Action: actionDrop}
+	require.NoError(t, plugin.Init())
+}
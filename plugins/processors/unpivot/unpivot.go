@@ -0,0 +1,67 @@
+package unpivot
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const description = "Rotate multi field metric into several single field metrics"
+const sampleConfig = `
+  ## Tag to use for the name.
+  tag_key = "name"
+  ## Field to use for the name of the value.
+  value_key = "value"
+`
+
+type Unpivot struct {
+	TagKey   string `toml:"tag_key"`
+	ValueKey string `toml:"value_key"`
+}
+
+func (p *Unpivot) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *Unpivot) Description() string {
+	return description
+}
+
+func (p *Unpivot) Apply(metrics ...telegraf.Metric) []telegraf.Metric {
+	results := make([]telegraf.Metric, 0, len(metrics))
+	for _, m := range metrics {
+		fields := make([]telegraf.Field, 0, len(m.FieldList()))
+		for _, field := range m.FieldList() {
+			fields = append(fields, *field)
+		}
+		if len(fields) == 0 {
+			results = append(results, m)
+			continue
+		}
+
+		// Each field gets a copy of the metric, the original metric is used
+		// for the last field so that tracking metrics are delivered once
+		// all of the copies are.
+		for i, field := range fields {
+			var nm telegraf.Metric
+			if i == len(fields)-1 {
+				nm = m
+			} else {
+				nm = m.Copy()
+			}
+
+			for _, f := range fields {
+				nm.RemoveField(f.Key)
+			}
+			nm.AddTag(p.TagKey, field.Key)
+			nm.AddField(p.ValueKey, field.Value)
+			results = append(results, nm)
+		}
+	}
+	return results
+}
+
+func init() {
+	processors.Add("unpivot", func() telegraf.Processor {
+		return &Unpivot{}
+	})
+}
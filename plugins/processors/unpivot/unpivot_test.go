@@ -0,0 +1,101 @@
+package unpivot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnpivot(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		unpivot  *Unpivot
+		metrics  []telegraf.Metric
+		expected []telegraf.Metric
+	}{
+		{
+			name: "simple",
+			unpivot: &Unpivot{
+				TagKey:   "name",
+				ValueKey: "value",
+			},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{},
+					map[string]interface{}{"idle_time": int64(42)},
+					now),
+			},
+			expected: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{"name": "idle_time"},
+					map[string]interface{}{"value": int64(42)},
+					now),
+			},
+		},
+		{
+			name: "multi fields",
+			unpivot: &Unpivot{
+				TagKey:   "name",
+				ValueKey: "value",
+			},
+			metrics: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{"host": "a"},
+					map[string]interface{}{
+						"idle_time": int64(42),
+						"idle_user": "up",
+					},
+					now),
+			},
+			expected: []telegraf.Metric{
+				testutil.MustMetric("cpu",
+					map[string]string{"host": "a", "name": "idle_time"},
+					map[string]interface{}{"value": int64(42)},
+					now),
+				testutil.MustMetric("cpu",
+					map[string]string{"host": "a", "name": "idle_user"},
+					map[string]interface{}{"value": "up"},
+					now),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := tt.unpivot.Apply(tt.metrics...)
+			testutil.RequireMetricsEqual(t, tt.expected, actual, testutil.SortMetrics())
+		})
+	}
+}
+
+func TestUnpivotTracking(t *testing.T) {
+	u := &Unpivot{
+		TagKey:   "name",
+		ValueKey: "value",
+	}
+
+	var delivered bool
+	m := testutil.MustMetric("cpu",
+		map[string]string{},
+		map[string]interface{}{
+			"a": int64(1),
+			"b": int64(2),
+			"c": int64(3),
+		},
+		time.Now())
+	tm, _ := metric.WithTracking(m, func(info telegraf.DeliveryInfo) {
+		delivered = info.Delivered()
+	})
+
+	actual := u.Apply(tm)
+	require.Len(t, actual, 3)
+	for _, m := range actual {
+		require.False(t, delivered)
+		m.Accept()
+	}
+	require.True(t, delivered)
+}
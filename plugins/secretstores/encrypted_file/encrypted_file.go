@@ -0,0 +1,149 @@
+package encrypted_file
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const sampleConfig = `
+  ## Unique identifier used to reference the store, secrets are referenced
+  ## as "@{id:key}" in string values of the plugin configuration.
+  id = "vault_file"
+
+  ## File containing a JSON object of keys to secrets, encrypted with:
+  ##   openssl enc -aes-256-cbc -pbkdf2 -md sha256 -iter 10000 \
+  ##     -in secrets.json -out secrets.enc
+  path = "/etc/telegraf/secrets.enc"
+
+  ## Password used to encrypt the file, it is recommended to set this using
+  ## an environment variable.
+  password = "$TELEGRAF_SECRETS_PASSWORD"
+
+  ## Number of PBKDF2 iterations used to derive the key from the password.
+  # iterations = 10000
+`
+
+const (
+	saltHeader = "Salted__"
+	saltSize   = 8
+	keySize    = 32
+)
+
+type EncryptedFile struct {
+	Path       string `toml:"path"`
+	Password   string `toml:"password"`
+	Iterations int    `toml:"iterations"`
+}
+
+func (e *EncryptedFile) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *EncryptedFile) Description() string {
+	return "Read secrets from a password encrypted file"
+}
+
+// Get decrypts the file and returns the secret with the key.  The file is
+// read on each call so that changes are picked up when the configuration is
+// reloaded.
+func (e *EncryptedFile) Get(key string) (string, error) {
+	secrets, err := e.load()
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := secrets[key]
+	if !ok {
+		return "", fmt.Errorf("secret %q not found in %s", key, e.Path)
+	}
+	return value, nil
+}
+
+func (e *EncryptedFile) load() (map[string]string, error) {
+	if e.Path == "" {
+		return nil, errors.New("path must be set")
+	}
+	if e.Password == "" {
+		return nil, errors.New("password must be set")
+	}
+
+	data, err := ioutil.ReadFile(e.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := decrypt(data, []byte(e.Password), e.Iterations)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt %s: %v", e.Path, err)
+	}
+
+	secrets := make(map[string]string)
+	err = json.Unmarshal(plaintext, &secrets)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", e.Path, err)
+	}
+	return secrets, nil
+}
+
+// decrypt decrypts data in the format written by "openssl enc -pbkdf2" using
+// AES-256-CBC, with the key and IV derived using PBKDF2-HMAC-SHA256.
+func decrypt(data, password []byte, iterations int) ([]byte, error) {
+	if len(data) < len(saltHeader)+saltSize ||
+		!bytes.Equal(data[:len(saltHeader)], []byte(saltHeader)) {
+		return nil, errors.New("missing salt header")
+	}
+	salt := data[len(saltHeader) : len(saltHeader)+saltSize]
+	ciphertext := data[len(saltHeader)+saltSize:]
+
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, errors.New("invalid ciphertext length")
+	}
+
+	key, iv := deriveKey(password, salt, iterations)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	// Remove the PKCS#7 padding, invalid padding usually means the password
+	// is wrong.
+	pad := int(plaintext[len(plaintext)-1])
+	if pad == 0 || pad > aes.BlockSize {
+		return nil, errors.New("bad password or corrupt file")
+	}
+	for _, b := range plaintext[len(plaintext)-pad:] {
+		if int(b) != pad {
+			return nil, errors.New("bad password or corrupt file")
+		}
+	}
+	return plaintext[:len(plaintext)-pad], nil
+}
+
+func deriveKey(password, salt []byte, iterations int) ([]byte, []byte) {
+	if iterations <= 0 {
+		iterations = 10000
+	}
+	dk := pbkdf2.Key(password, salt, iterations, keySize+aes.BlockSize, sha256.New)
+	return dk[:keySize], dk[keySize:]
+}
+
+func init() {
+	secretstores.Add("encrypted_file", func() telegraf.SecretStore {
+		return &EncryptedFile{
+			Iterations: 10000,
+		}
+	})
+}
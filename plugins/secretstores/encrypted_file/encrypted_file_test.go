@@ -0,0 +1,94 @@
+package encrypted_file
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// encrypt writes data in the same format as "openssl enc -pbkdf2".
+func encrypt(t *testing.T, plaintext, password []byte, iterations int) []byte {
+	salt := []byte("12345678")
+	key, iv := deriveKey(password, salt, iterations)
+
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+
+	pad := aes.BlockSize - len(plaintext)%aes.BlockSize
+	for i := 0; i < pad; i++ {
+		plaintext = append(plaintext, byte(pad))
+	}
+
+	ciphertext := make([]byte, len(plaintext))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, plaintext)
+
+	data := append([]byte(saltHeader), salt...)
+	return append(data, ciphertext...)
+}
+
+func writeSecrets(t *testing.T, data []byte) (string, func()) {
+	dir, err := ioutil.TempDir("", "secrets")
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "secrets.enc")
+	err = ioutil.WriteFile(path, data, 0600)
+	require.NoError(t, err)
+
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestGet(t *testing.T) {
+	data := encrypt(t, []byte(`{"password": "hunter2", "token": "abc"}`), []byte("secret"), 1000)
+	path, cleanup := writeSecrets(t, data)
+	defer cleanup()
+
+	e := &EncryptedFile{
+		Path:       path,
+		Password:   "secret",
+		Iterations: 1000,
+	}
+
+	value, err := e.Get("password")
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", value)
+
+	value, err = e.Get("token")
+	require.NoError(t, err)
+	require.Equal(t, "abc", value)
+
+	_, err = e.Get("missing")
+	require.Error(t, err)
+}
+
+func TestGetWrongPassword(t *testing.T) {
+	data := encrypt(t, []byte(`{"password": "hunter2"}`), []byte("secret"), 1000)
+	path, cleanup := writeSecrets(t, data)
+	defer cleanup()
+
+	e := &EncryptedFile{
+		Path:       path,
+		Password:   "wrong",
+		Iterations: 1000,
+	}
+
+	_, err := e.Get("password")
+	require.Error(t, err)
+}
+
+func TestGetNotEncrypted(t *testing.T) {
+	path, cleanup := writeSecrets(t, []byte(`{"password": "hunter2"}`))
+	defer cleanup()
+
+	e := &EncryptedFile{
+		Path:     path,
+		Password: "secret",
+	}
+
+	_, err := e.Get("password")
+	require.Error(t, err)
+}
@@ -0,0 +1,57 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+const sampleConfig = `
+  ## Unique identifier used to reference the store, secrets are referenced
+  ## as "@{id:key}" in string values of the plugin configuration.
+  id = "files"
+
+  ## Directory containing the secrets, each secret is read from the file with
+  ## the same name as its key.  A trailing newline is removed from the value.
+  directory = "/run/secrets"
+`
+
+type File struct {
+	Directory string `toml:"directory"`
+}
+
+func (f *File) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *File) Description() string {
+	return "Read secrets from files in a directory"
+}
+
+func (f *File) Get(key string) (string, error) {
+	if f.Directory == "" {
+		return "", errors.New("directory must be set")
+	}
+
+	if key == "" || key == "." || key == ".." ||
+		strings.ContainsAny(key, `/\`) {
+		return "", fmt.Errorf("invalid secret key %q", key)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(f.Directory, key))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+func init() {
+	secretstores.Add("file", func() telegraf.SecretStore {
+		return &File{}
+	})
+}
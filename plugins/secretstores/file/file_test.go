@@ -0,0 +1,37 @@
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "password"), []byte("hunter2\n"), 0600)
+	require.NoError(t, err)
+
+	f := &File{Directory: dir}
+
+	value, err := f.Get("password")
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", value)
+
+	_, err = f.Get("missing")
+	require.Error(t, err)
+}
+
+func TestGetInvalidKey(t *testing.T) {
+	f := &File{Directory: "/run/secrets"}
+
+	for _, key := range []string{"", ".", "..", "../passwd", "a/b", `a\b`} {
+		_, err := f.Get(key)
+		require.Error(t, err, key)
+	}
+}
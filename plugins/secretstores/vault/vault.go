@@ -0,0 +1,207 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/tls"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+const sampleConfig = `
+  ## Unique identifier used to reference the store, secrets are referenced
+  ## as "@{id:path#field}" in string values of the plugin configuration.
+  id = "vault"
+
+  ## Address of the Vault server.
+  url = "https://127.0.0.1:8200"
+
+  ## Token used to authenticate, it is recommended to set this using an
+  ## environment variable.
+  token = "$VAULT_TOKEN"
+
+  ## Mount path of the key/value secrets engine.
+  # mount = "secret"
+
+  ## Version of the key/value secrets engine, 1 or 2.
+  # kv_version = 2
+
+  ## Timeout for HTTP requests.
+  # timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+type Vault struct {
+	URL       string            `toml:"url"`
+	Token     string            `toml:"token"`
+	Mount     string            `toml:"mount"`
+	KVVersion int               `toml:"kv_version"`
+	Timeout   internal.Duration `toml:"timeout"`
+	tls.ClientConfig
+
+	client *http.Client
+}
+
+func (v *Vault) SampleConfig() string {
+	return sampleConfig
+}
+
+func (v *Vault) Description() string {
+	return "Read secrets from a HashiCorp Vault key/value secrets engine"
+}
+
+// Get returns a field of a secret, the key has the format "path#field".
+func (v *Vault) Get(key string) (string, error) {
+	i := strings.LastIndex(key, "#")
+	if i <= 0 || i == len(key)-1 {
+		return "", fmt.Errorf("invalid secret key %q, expected \"path#field\"", key)
+	}
+	path, field := key[:i], key[i+1:]
+
+	if v.client == nil {
+		client, err := v.createClient()
+		if err != nil {
+			return "", err
+		}
+		v.client = client
+	}
+
+	data, err := v.read(path)
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("field %q not found in secret %q", field, path)
+	}
+
+	switch value := value.(type) {
+	case string:
+		return value, nil
+	case nil:
+		return "", nil
+	default:
+		b, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+}
+
+func (v *Vault) createClient() (*http.Client, error) {
+	if v.URL == "" {
+		return nil, errors.New("url must be set")
+	}
+
+	tlsCfg, err := v.ClientConfig.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: v.Timeout.Duration,
+	}, nil
+}
+
+// read returns the key/value pairs of the secret at the path.
+func (v *Vault) read(path string) (map[string]interface{}, error) {
+	mount := strings.Trim(v.Mount, "/")
+	path = strings.Trim(path, "/")
+
+	var endpoint string
+	switch v.KVVersion {
+	case 1:
+		endpoint = "/v1/" + mount + "/" + path
+	case 2:
+		endpoint = "/v1/" + mount + "/data/" + path
+	default:
+		return nil, fmt.Errorf("unsupported kv_version %d", v.KVVersion)
+	}
+
+	u, err := url.Parse(strings.TrimRight(v.URL, "/") + endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if v.Token != "" {
+		req.Header.Set("X-Vault-Token", v.Token)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil, fmt.Errorf("reading secret %q: received status code %d (%s)",
+			path, resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	var body struct {
+		Data json.RawMessage `json:"data"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&body)
+	if err != nil {
+		return nil, fmt.Errorf("reading secret %q: %v", path, err)
+	}
+
+	// Version 2 of the engine wraps the secret with its metadata.
+	raw := body.Data
+	if v.KVVersion == 2 {
+		var wrapped struct {
+			Data json.RawMessage `json:"data"`
+		}
+		err = json.Unmarshal(raw, &wrapped)
+		if err != nil {
+			return nil, fmt.Errorf("reading secret %q: %v", path, err)
+		}
+		raw = wrapped.Data
+	}
+
+	var data map[string]interface{}
+	err = json.Unmarshal(raw, &data)
+	if err != nil {
+		return nil, fmt.Errorf("reading secret %q: %v", path, err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("secret %q not found", path)
+	}
+	return data, nil
+}
+
+func init() {
+	secretstores.Add("vault", func() telegraf.SecretStore {
+		return &Vault{
+			Mount:     "secret",
+			KVVersion: 2,
+			Timeout:   internal.Duration{Duration: 5 * time.Second},
+		}
+	})
+}
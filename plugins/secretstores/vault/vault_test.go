@@ -0,0 +1,93 @@
+package vault
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/stretchr/testify/require"
+)
+
+const token = "s.token"
+
+func newVault(url string, version int) *Vault {
+	return &Vault{
+		URL:       url,
+		Token:     token,
+		Mount:     "secret",
+		KVVersion: version,
+		Timeout:   internal.Duration{Duration: 5 * time.Second},
+	}
+}
+
+func stubServer(t *testing.T, path string, body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.Method != "GET" || r.URL.Path != path {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintln(w, `{"errors":[]}`)
+			return
+		}
+		fmt.Fprintln(w, body)
+	}))
+}
+
+func TestGetKV2(t *testing.T) {
+	ts := stubServer(t, "/v1/secret/data/db/mysql",
+		`{"data":{"data":{"password":"hunter2","port":3306},"metadata":{"version":1}}}`)
+	defer ts.Close()
+
+	v := newVault(ts.URL, 2)
+
+	value, err := v.Get("db/mysql#password")
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", value)
+
+	value, err = v.Get("db/mysql#port")
+	require.NoError(t, err)
+	require.Equal(t, "3306", value)
+
+	_, err = v.Get("db/mysql#missing")
+	require.Error(t, err)
+
+	_, err = v.Get("db/other#password")
+	require.Error(t, err)
+}
+
+func TestGetKV1(t *testing.T) {
+	ts := stubServer(t, "/v1/secret/db/mysql",
+		`{"data":{"password":"hunter2"},"lease_duration":2764800}`)
+	defer ts.Close()
+
+	v := newVault(ts.URL, 1)
+
+	value, err := v.Get("db/mysql#password")
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", value)
+}
+
+func TestGetForbidden(t *testing.T) {
+	ts := stubServer(t, "/v1/secret/data/db/mysql", `{}`)
+	defer ts.Close()
+
+	v := newVault(ts.URL, 2)
+	v.Token = "s.wrong"
+
+	_, err := v.Get("db/mysql#password")
+	require.Error(t, err)
+}
+
+func TestGetInvalidKey(t *testing.T) {
+	v := newVault("http://127.0.0.1:8200", 2)
+
+	for _, key := range []string{"", "db/mysql", "#password", "db/mysql#"} {
+		_, err := v.Get(key)
+		require.Error(t, err, key)
+	}
+}
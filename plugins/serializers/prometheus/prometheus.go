@@ -0,0 +1,277 @@
+package prometheus
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/promconv"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+const helpString = "Telegraf collected metric"
+
+// Serializer renders metrics in the Prometheus text exposition format.
+//
+// The metrics of a batch are grouped by metric family, the names are derived
+// from the measurement and field keys as done by the prometheus_client
+// output.
+type Serializer struct {
+	// ExportTimestamp adds the time of the metrics to the samples.
+	ExportTimestamp bool
+
+	// StringAsLabel adds the string fields as labels, they are ignored
+	// otherwise.
+	StringAsLabel bool
+}
+
+func NewSerializer(exportTimestamp, stringAsLabel bool) (*Serializer, error) {
+	return &Serializer{
+		ExportTimestamp: exportTimestamp,
+		StringAsLabel:   stringAsLabel,
+	}, nil
+}
+
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return s.SerializeBatch([]telegraf.Metric{metric})
+}
+
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	families := make(map[string]*family)
+	for _, m := range metrics {
+		s.add(families, m)
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		if _, err := expfmt.MetricFamilyToText(&buf, families[name].toProto(name, s.ExportTimestamp)); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// family holds the samples of a metric family by label set.
+type family struct {
+	typ     telegraf.ValueType
+	samples map[string]*sample
+}
+
+type sample struct {
+	labels    []*dto.LabelPair
+	timestamp int64
+
+	value     float64
+	count     uint64
+	sum       float64
+	quantiles map[float64]float64
+	buckets   map[float64]uint64
+}
+
+// add adds the fields of the metric to their families.  Samples of a label set
+// seen before are replaced by the later metric of the batch, the fields of
+// summaries and histograms are merged.
+func (s *Serializer) add(families map[string]*family, m telegraf.Metric) {
+	labels := s.labels(m)
+	id := labelsID(labels)
+	timestamp := m.Time().UnixNano() / int64(1e6)
+
+	switch m.Type() {
+	case telegraf.Summary, telegraf.Histogram:
+		name := promconv.Sanitize(m.Name())
+		fam := getFamily(families, name, m.Type())
+		if fam == nil {
+			return
+		}
+		smp, ok := fam.samples[id]
+		if !ok {
+			smp = &sample{
+				labels:    labels,
+				quantiles: make(map[float64]float64),
+				buckets:   make(map[float64]uint64),
+			}
+			fam.samples[id] = smp
+		}
+		smp.timestamp = timestamp
+
+		for _, field := range m.FieldList() {
+			value, ok := promconv.ToFloat(field.Value)
+			if !ok {
+				continue
+			}
+			switch field.Key {
+			case "count":
+				smp.count = uint64(value)
+			case "sum":
+				smp.sum = value
+			default:
+				bound, err := strconv.ParseFloat(field.Key, 64)
+				if err != nil {
+					continue
+				}
+				if m.Type() == telegraf.Summary {
+					smp.quantiles[bound] = value
+				} else {
+					smp.buckets[bound] = uint64(value)
+				}
+			}
+		}
+	default:
+		for _, field := range m.FieldList() {
+			value, ok := promconv.ToFloat(field.Value)
+			if !ok {
+				continue
+			}
+			fam := getFamily(families, promconv.MetricName(m, field.Key), m.Type())
+			if fam == nil {
+				continue
+			}
+			fam.samples[id] = &sample{
+				labels:    labels,
+				timestamp: timestamp,
+				value:     value,
+			}
+		}
+	}
+}
+
+// getFamily returns the family of the name, creating it if needed.  It
+// returns nil for the name of an invalid metric or if the family has a
+// different kind of type: a counter, gauge or untyped value cannot be added to
+// a summary or histogram.
+func getFamily(families map[string]*family, name string, typ telegraf.ValueType) *family {
+	if !promconv.IsValidMetricName(name) {
+		return nil
+	}
+	fam, ok := families[name]
+	if !ok {
+		fam = &family{typ: typ, samples: make(map[string]*sample)}
+		families[name] = fam
+		return fam
+	}
+	if isDistribution(fam.typ) != isDistribution(typ) || (isDistribution(typ) && fam.typ != typ) {
+		return nil
+	}
+	return fam
+}
+
+func isDistribution(typ telegraf.ValueType) bool {
+	return typ == telegraf.Summary || typ == telegraf.Histogram
+}
+
+// labels returns the labels of the metric sorted by name.
+func (s *Serializer) labels(m telegraf.Metric) []*dto.LabelPair {
+	values := promconv.Labels(m, s.StringAsLabel)
+	labels := make([]*dto.LabelPair, 0, len(values))
+	for name, value := range values {
+		labels = append(labels, &dto.LabelPair{
+			Name:  proto.String(name),
+			Value: proto.String(value),
+		})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].GetName() < labels[j].GetName()
+	})
+	return labels
+}
+
+func labelsID(labels []*dto.LabelPair) string {
+	var sb strings.Builder
+	for _, l := range labels {
+		sb.WriteString(l.GetName())
+		sb.WriteByte('=')
+		sb.WriteString(l.GetValue())
+		sb.WriteByte(0xff)
+	}
+	return sb.String()
+}
+
+func (f *family) toProto(name string, exportTimestamp bool) *dto.MetricFamily {
+	mf := &dto.MetricFamily{
+		Name: proto.String(name),
+		Help: proto.String(helpString),
+		Type: metricType(f.typ).Enum(),
+	}
+
+	ids := make([]string, 0, len(f.samples))
+	for id := range f.samples {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		smp := f.samples[id]
+		m := &dto.Metric{Label: smp.labels}
+		if exportTimestamp {
+			m.TimestampMs = proto.Int64(smp.timestamp)
+		}
+
+		switch f.typ {
+		case telegraf.Counter:
+			m.Counter = &dto.Counter{Value: proto.Float64(smp.value)}
+		case telegraf.Gauge:
+			m.Gauge = &dto.Gauge{Value: proto.Float64(smp.value)}
+		case telegraf.Summary:
+			m.Summary = &dto.Summary{
+				SampleCount: proto.Uint64(smp.count),
+				SampleSum:   proto.Float64(smp.sum),
+			}
+			quantiles := make([]float64, 0, len(smp.quantiles))
+			for q := range smp.quantiles {
+				quantiles = append(quantiles, q)
+			}
+			sort.Float64s(quantiles)
+			for _, q := range quantiles {
+				m.Summary.Quantile = append(m.Summary.Quantile, &dto.Quantile{
+					Quantile: proto.Float64(q),
+					Value:    proto.Float64(smp.quantiles[q]),
+				})
+			}
+		case telegraf.Histogram:
+			m.Histogram = &dto.Histogram{
+				SampleCount: proto.Uint64(smp.count),
+				SampleSum:   proto.Float64(smp.sum),
+			}
+			bounds := make([]float64, 0, len(smp.buckets))
+			for b := range smp.buckets {
+				bounds = append(bounds, b)
+			}
+			sort.Float64s(bounds)
+			for _, b := range bounds {
+				m.Histogram.Bucket = append(m.Histogram.Bucket, &dto.Bucket{
+					UpperBound:      proto.Float64(b),
+					CumulativeCount: proto.Uint64(smp.buckets[b]),
+				})
+			}
+		default:
+			m.Untyped = &dto.Untyped{Value: proto.Float64(smp.value)}
+		}
+		mf.Metric = append(mf.Metric, m)
+	}
+	return mf
+}
+
+func metricType(typ telegraf.ValueType) dto.MetricType {
+	switch typ {
+	case telegraf.Counter:
+		return dto.MetricType_COUNTER
+	case telegraf.Gauge:
+		return dto.MetricType_GAUGE
+	case telegraf.Summary:
+		return dto.MetricType_SUMMARY
+	case telegraf.Histogram:
+		return dto.MetricType_HISTOGRAM
+	default:
+		return dto.MetricType_UNTYPED
+	}
+}
@@ -0,0 +1,191 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSerializeScalar(t *testing.T) {
+	s, err := NewSerializer(false, false)
+	require.NoError(t, err)
+
+	buf, err := s.SerializeBatch([]telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "example.org", "cpu": "cpu0"},
+			map[string]interface{}{
+				"time_idle": 42.0,
+				"time_user": int64(3),
+				"ok":        true,
+				"state":     "running",
+			},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"http.requests",
+			map[string]string{"code": "200"},
+			map[string]interface{}{"counter": uint64(1027)},
+			time.Unix(0, 0),
+			telegraf.Counter,
+		),
+		testutil.MustMetric(
+			"temperature",
+			map[string]string{"sensor": "a \"quoted\" name"},
+			map[string]interface{}{"value": 21.5},
+			time.Unix(0, 0),
+			telegraf.Gauge,
+		),
+	})
+	require.NoError(t, err)
+	require.Equal(t, `# HELP cpu_time_idle Telegraf collected metric
+# TYPE cpu_time_idle untyped
+cpu_time_idle{cpu="cpu0",host="example.org"} 42
+# HELP cpu_time_user Telegraf collected metric
+# TYPE cpu_time_user untyped
+cpu_time_user{cpu="cpu0",host="example.org"} 3
+# HELP http_requests Telegraf collected metric
+# TYPE http_requests counter
+http_requests{code="200"} 1027
+# HELP temperature Telegraf collected metric
+# TYPE temperature gauge
+temperature{sensor="a \"quoted\" name"} 21.5
+`, string(buf))
+}
+
+func TestSerializeSummary(t *testing.T) {
+	s, err := NewSerializer(false, false)
+	require.NoError(t, err)
+
+	buf, err := s.Serialize(testutil.MustMetric(
+		"rpc_duration_seconds",
+		map[string]string{},
+		map[string]interface{}{
+			"0.9":   9001.0,
+			"0.5":   4773.0,
+			"count": 2693.0,
+			"sum":   1.7560473e+07,
+		},
+		time.Unix(0, 0),
+		telegraf.Summary,
+	))
+	require.NoError(t, err)
+	require.Equal(t, `# HELP rpc_duration_seconds Telegraf collected metric
+# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{quantile="0.5"} 4773
+rpc_duration_seconds{quantile="0.9"} 9001
+rpc_duration_seconds_sum 1.7560473e+07
+rpc_duration_seconds_count 2693
+`, string(buf))
+}
+
+func TestSerializeHistogram(t *testing.T) {
+	s, err := NewSerializer(true, false)
+	require.NoError(t, err)
+
+	buf, err := s.SerializeBatch([]telegraf.Metric{
+		testutil.MustMetric(
+			"request_seconds",
+			map[string]string{"path": "/"},
+			map[string]interface{}{
+				"0.5":  3.0,
+				"0.1":  1.0,
+				"+Inf": 4.0,
+				"sum":  2.5,
+			},
+			time.Unix(1, 0),
+			telegraf.Histogram,
+		),
+		testutil.MustMetric(
+			"request_seconds",
+			map[string]string{"path": "/"},
+			map[string]interface{}{"count": 4.0},
+			time.Unix(2, 0),
+			telegraf.Histogram,
+		),
+	})
+	require.NoError(t, err)
+	require.Equal(t, `# HELP request_seconds Telegraf collected metric
+# TYPE request_seconds histogram
+request_seconds_bucket{path="/",le="0.1"} 1 2000
+request_seconds_bucket{path="/",le="0.5"} 3 2000
+request_seconds_bucket{path="/",le="+Inf"} 4 2000
+request_seconds_sum{path="/"} 2.5 2000
+request_seconds_count{path="/"} 4 2000
+`, string(buf))
+}
+
+func TestSerializeStringAsLabel(t *testing.T) {
+	s, err := NewSerializer(false, true)
+	require.NoError(t, err)
+
+	buf, err := s.Serialize(testutil.MustMetric(
+		"service",
+		map[string]string{"host-name": "example.org", "__reserved": "x"},
+		map[string]interface{}{
+			"state":  "running",
+			"uptime": int64(60),
+		},
+		time.Unix(0, 0),
+	))
+	require.NoError(t, err)
+	require.Equal(t, `# HELP service_uptime Telegraf collected metric
+# TYPE service_uptime untyped
+service_uptime{host_name="example.org",state="running"} 60
+`, string(buf))
+}
+
+func TestSerializeConflictingTypes(t *testing.T) {
+	s, err := NewSerializer(false, false)
+	require.NoError(t, err)
+
+	buf, err := s.SerializeBatch([]telegraf.Metric{
+		testutil.MustMetric(
+			"latency",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"value": 1.0},
+			time.Unix(0, 0),
+		),
+		testutil.MustMetric(
+			"latency",
+			map[string]string{"host": "b"},
+			map[string]interface{}{"0.5": 1.0, "count": 1.0, "sum": 1.0},
+			time.Unix(0, 0),
+			telegraf.Summary,
+		),
+		testutil.MustMetric(
+			"latency",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"value": 2.0},
+			time.Unix(1, 0),
+		),
+		testutil.MustMetric(
+			"1xx",
+			map[string]string{},
+			map[string]interface{}{"value": 2.0},
+			time.Unix(1, 0),
+		),
+	})
+	require.NoError(t, err)
+	require.Equal(t, `# HELP latency Telegraf collected metric
+# TYPE latency untyped
+latency{host="a"} 2
+`, string(buf))
+}
+
+func TestSerializeNoFields(t *testing.T) {
+	s, err := NewSerializer(false, false)
+	require.NoError(t, err)
+
+	buf, err := s.Serialize(testutil.MustMetric(
+		"service",
+		map[string]string{},
+		map[string]interface{}{"state": "running"},
+		time.Unix(0, 0),
+	))
+	require.NoError(t, err)
+	require.Empty(t, buf)
+}
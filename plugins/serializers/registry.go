@@ -10,6 +10,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/plugins/serializers/json"
 	"github.com/influxdata/telegraf/plugins/serializers/nowmetric"
+	"github.com/influxdata/telegraf/plugins/serializers/prometheus"
 	"github.com/influxdata/telegraf/plugins/serializers/splunkmetric"
 	"github.com/influxdata/telegraf/plugins/serializers/wavefront"
 )
@@ -76,6 +77,12 @@ type Config struct {
 	// Use Strict rules to sanitize metric and tag names from invalid characters for Wavefront
 	// When enabled forward slash (/) and comma (,) will be accepted
 	WavefrontUseStrict bool
+
+	// Include the metric timestamp on each sample; prometheus format only
+	PrometheusExportTimestamp bool
+
+	// Add string fields as labels; prometheus format only
+	PrometheusStringAsLabel bool
 }
 
 // NewSerializer a Serializer interface based on the given config.
@@ -97,12 +104,18 @@ func NewSerializer(config *Config) (Serializer, error) {
 		serializer, err = NewCarbon2Serializer()
 	case "wavefront":
 		serializer, err = NewWavefrontSerializer(config.Prefix, config.WavefrontUseStrict, config.WavefrontSourceOverride)
+	case "prometheus":
+		serializer, err = NewPrometheusSerializer(config.PrometheusExportTimestamp, config.PrometheusStringAsLabel)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
 	return serializer, err
 }
 
+func NewPrometheusSerializer(exportTimestamp, stringAsLabel bool) (Serializer, error) {
+	return prometheus.NewSerializer(exportTimestamp, stringAsLabel)
+}
+
 func NewWavefrontSerializer(prefix string, useStrict bool, sourceOverride []string) (Serializer, error) {
 	return wavefront.NewSerializer(prefix, useStrict, sourceOverride)
 }
@@ -10,3 +10,26 @@ type Processor interface {
 	// Apply the filter to the given metric.
 	Apply(in ...Metric) []Metric
 }
+
+// StreamingProcessor is a processor that can emit metrics at any time,
+// rather than only as the result of Apply.
+type StreamingProcessor interface {
+	Processor
+
+	// Start is called before any metrics are applied.  Metrics emitted by
+	// the processor must be added to the accumulator, they continue through
+	// the processors that follow.
+	Start(acc Accumulator) error
+
+	// Stop is called after the last metric has been applied, it should
+	// emit any pending metrics before returning.
+	Stop()
+}
+
+// Initializer is a processor that checks its configuration before it is
+// used.
+type Initializer interface {
+	// Init is called once after the configuration is loaded, an error is
+	// reported as an error in the configuration.
+	Init() error
+}
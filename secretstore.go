@@ -0,0 +1,13 @@
+package telegraf
+
+// SecretStore provides secrets that are referenced in the configuration.
+type SecretStore interface {
+	// SampleConfig returns the default configuration of the SecretStore
+	SampleConfig() string
+
+	// Description returns a one-sentence description on the SecretStore
+	Description() string
+
+	// Get returns the secret with the given key.
+	Get(key string) (string, error)
+}